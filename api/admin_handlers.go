@@ -0,0 +1,313 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/hypertf/nahcloud/service/chaos"
+	"github.com/hypertf/nahcloud/service/ratelimit"
+)
+
+// ReseedChaosRequest represents a request to change the chaos seed
+type ReseedChaosRequest struct {
+	Seed int64 `json:"seed"`
+}
+
+// GetChaosConfig handles GET /v1/_admin/chaos
+func (h *Handler) GetChaosConfig(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.Config())
+}
+
+// UpdateChaosConfig handles PUT /v1/_admin/chaos
+func (h *Handler) UpdateChaosConfig(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var config chaos.Config
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	if err := h.chaosService.UpdateConfig(config); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.Config())
+}
+
+// AddChaosRule handles POST /v1/_admin/chaos/rules
+func (h *Handler) AddChaosRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var rule chaos.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	if err := h.chaosService.AddRule(rule); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, rule)
+}
+
+// DeleteChaosRule handles DELETE /v1/_admin/chaos/rules/{name}
+func (h *Handler) DeleteChaosRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	if err := h.chaosService.RemoveRule(name); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReseedChaos handles POST /v1/_admin/chaos/seed
+func (h *Handler) ReseedChaos(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req ReseedChaosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	h.chaosService.Reseed(req.Seed)
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.Config())
+}
+
+// GetChaosJournal handles GET /v1/_admin/chaos/journal
+func (h *Handler) GetChaosJournal(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := chaos.JournalFilter{
+		Key:  query.Get("key"),
+		Rule: query.Get("rule"),
+	}
+	if since := query.Get("since"); since != "" {
+		seq, err := strconv.ParseInt(since, 10, 64)
+		if err != nil || seq < 0 {
+			h.writeError(w, domain.InvalidInputError("invalid since", map[string]interface{}{"since": since}))
+			return
+		}
+		filter.Since = seq
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			h.writeError(w, domain.InvalidInputError("invalid limit", map[string]interface{}{"limit": limit}))
+			return
+		}
+		filter.Limit = n
+	}
+
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(h.chaosService.Journal(filter), ""))
+}
+
+// ClearChaosJournal handles DELETE /v1/_admin/chaos/journal
+func (h *Handler) ClearChaosJournal(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.chaosService.ClearJournal()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRateLimits handles GET /v1/_admin/ratelimit
+func (h *Handler) GetRateLimits(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.rateLimiter.Status())
+}
+
+// UpdateRateLimits handles PUT /v1/_admin/ratelimit
+func (h *Handler) UpdateRateLimits(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var config ratelimit.Config
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	if err := h.rateLimiter.UpdateConfig(config); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.rateLimiter.Status())
+}
+
+// ResetRateLimits handles DELETE /v1/_admin/ratelimit/buckets
+func (h *Handler) ResetRateLimits(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.rateLimiter.Reset()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateAPIKey handles POST /v1/_admin/keys
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, key)
+}
+
+// ListAPIKeys handles GET /v1/_admin/keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	page, err := parsePageOptions(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	opts := domain.APIKeyListOptions{
+		IncludeRevoked: r.URL.Query().Get("include_revoked") == "true",
+		PageOptions:    page,
+	}
+
+	keys, next, err := h.service.ListAPIKeys(opts)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(keys, next))
+}
+
+// GetAPIKey handles GET /v1/_admin/keys/{id}
+func (h *Handler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	key, err := h.service.GetAPIKey(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, key)
+}
+
+// RevokeAPIKey handles DELETE /v1/_admin/keys/{id}
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := h.service.RevokeAPIKey(id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTFStateLocks handles GET /v1/_admin/tfstate/locks
+func (h *Handler) ListTFStateLocks(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	locks, err := h.service.ListTFStateLocks()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(locks, ""))
+}
+
+// ForceUnlockTFState handles DELETE /v1/_admin/tfstate/locks/{id}
+func (h *Handler) ForceUnlockTFState(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	released, err := h.service.ForceUnlockTFState(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, released)
+}
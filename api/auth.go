@@ -0,0 +1,182 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// authenticate checks bearer token authentication and, for API keys, that the
+// key's scopes cover the request. The server token grants full access.
+func (h *Handler) authenticate(r *http.Request) error {
+	key, err := h.identify(r)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		if h.token != "" {
+			setSubject(r, "token")
+		}
+		return nil
+	}
+	setSubject(r, "api_key:"+key.ID)
+
+	if strings.HasPrefix(r.URL.Path, "/v1/_admin/") {
+		return domain.ForbiddenError("api keys cannot use admin endpoints", nil)
+	}
+
+	resource := domain.ResourceForPath(r.URL.Path)
+	access := domain.ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		access = domain.ScopeRead
+	}
+	if !key.Allows(resource, access) {
+		return domain.ForbiddenError("api key is missing a required scope", map[string]interface{}{
+			"scope": resource + ":" + access,
+		})
+	}
+
+	// Project-restricted keys only reach projects, instances and the operations on them
+	if key.ProjectID != "" && resource != "projects" && resource != "instances" && resource != "operations" {
+		return forbiddenOutsideProject(key)
+	}
+	return nil
+}
+
+// credentials is the outcome of checking a request's bearer token
+type credentials struct {
+	key         *domain.APIKey // nil for the server token and unauthenticated requests
+	accessToken bool           // the key was presented through an OAuth access token
+	err         error
+}
+
+// identify returns the API key a request is authenticated with, directly or through
+// an OAuth access token, or nil for the server token and for unauthenticated
+// requests when no server token is set
+func (h *Handler) identify(r *http.Request) (*domain.APIKey, error) {
+	creds := h.credentials(r)
+	if creds.err != nil {
+		return nil, creds.err
+	}
+	if creds.accessToken && h.chaosService.ExpireTokenEarly(r) {
+		return nil, domain.UnauthorizedError("access token has expired")
+	}
+	return creds.key, nil
+}
+
+// credentials returns the request's resolved credentials. They are resolved on
+// first use and kept with the request, so the token is only hashed and looked up
+// once however many checks the request goes through.
+func (h *Handler) credentials(r *http.Request) *credentials {
+	info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	if ok && info.credentials != nil {
+		return info.credentials
+	}
+
+	creds := h.resolveCredentials(r)
+	if ok {
+		info.credentials = creds
+	}
+	return creds
+}
+
+// rateLimitSubject names who a request is authenticated as for rate limiting.
+// Anonymous requests and invalid credentials all share the "" subject.
+func (h *Handler) rateLimitSubject(r *http.Request) string {
+	creds := h.credentials(r)
+	switch {
+	case creds.err != nil:
+		return ""
+	case creds.key != nil:
+		return "api_key:" + creds.key.ID
+	case h.token != "":
+		return "token"
+	}
+	return ""
+}
+
+// resolveCredentials checks a request's bearer token against the server token,
+// OAuth access tokens and API keys
+func (h *Handler) resolveCredentials(r *http.Request) *credentials {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		if h.token == "" {
+			return &credentials{} // No authentication required
+		}
+		return &credentials{err: domain.UnauthorizedError("missing authorization header")}
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		if h.token == "" {
+			return &credentials{}
+		}
+		return &credentials{err: domain.UnauthorizedError("invalid authorization header format")}
+	}
+
+	if h.token != "" && parts[1] == h.token {
+		return &credentials{}
+	}
+
+	key, err := h.service.AuthenticateAccessToken(parts[1])
+	if err == nil {
+		return &credentials{key: key, accessToken: true}
+	}
+	if !domain.IsNotFound(err) {
+		return &credentials{err: err}
+	}
+
+	key, err = h.service.AuthenticateAPIKey(parts[1])
+	if err != nil {
+		if !domain.IsNotFound(err) {
+			return &credentials{err: err}
+		}
+		// Without a server token, unknown credentials are ignored as before
+		if h.token == "" {
+			return &credentials{}
+		}
+		return &credentials{err: domain.UnauthorizedError("invalid token")}
+	}
+	return &credentials{key: key}
+}
+
+// restrictedProject returns the project a request's API key is restricted to, or ""
+func (h *Handler) restrictedProject(r *http.Request) (string, error) {
+	key, err := h.identify(r)
+	if err != nil || key == nil {
+		return "", err
+	}
+	return key.ProjectID, nil
+}
+
+// authorizeProject checks that a project-restricted API key may act on a project
+func (h *Handler) authorizeProject(r *http.Request, projectID string) error {
+	key, err := h.identify(r)
+	if err != nil || key == nil || key.ProjectID == "" {
+		return err
+	}
+	if projectID != key.ProjectID {
+		return forbiddenOutsideProject(key)
+	}
+	return nil
+}
+
+// authorizeInstance checks that a project-restricted API key may act on an instance
+func (h *Handler) authorizeInstance(r *http.Request, instanceID string) error {
+	projectID, err := h.restrictedProject(r)
+	if err != nil || projectID == "" {
+		return err
+	}
+	instance, err := h.service.GetInstance(instanceID)
+	if err != nil {
+		return err
+	}
+	return h.authorizeProject(r, instance.ProjectID)
+}
+
+func forbiddenOutsideProject(key *domain.APIKey) error {
+	return domain.ForbiddenError("api key is restricted to a project", map[string]interface{}{
+		"project_id": key.ProjectID,
+	})
+}
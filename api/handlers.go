@@ -2,52 +2,48 @@ package api
 
 import (
 	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/mux"
 	"github.com/hypertf/nahcloud/domain"
 	"github.com/hypertf/nahcloud/service"
 	"github.com/hypertf/nahcloud/service/chaos"
+	"github.com/hypertf/nahcloud/service/metrics"
+	"github.com/hypertf/nahcloud/service/ratelimit"
 )
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
 	service      *service.Service
 	chaosService *chaos.ChaosService
+	rateLimiter  *ratelimit.Limiter
 	token        string
+	logger       *slog.Logger
+
+	metrics        *metrics.Registry
+	requestMetrics *requestMetrics
+
+	readinessChecks []readinessCheck
+	draining        atomic.Bool
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(svc *service.Service, chaosService *chaos.ChaosService, token string) *Handler {
+func NewHandler(svc *service.Service, chaosService *chaos.ChaosService, rateLimiter *ratelimit.Limiter, token string) *Handler {
+	registry := metrics.NewRegistry()
 	return &Handler{
-		service:      svc,
-		chaosService: chaosService,
-		token:        token,
-	}
-}
-
-// authenticate checks bearer token authentication
-func (h *Handler) authenticate(r *http.Request) error {
-	if h.token == "" {
-		return nil // No authentication required
-	}
-
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return domain.UnauthorizedError("missing authorization header")
-	}
-
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		return domain.UnauthorizedError("invalid authorization header format")
+		service:        svc,
+		chaosService:   chaosService,
+		rateLimiter:    rateLimiter,
+		token:          token,
+		logger:         slog.Default(),
+		metrics:        registry,
+		requestMetrics: newRequestMetrics(registry, chaosService),
 	}
-
-	if parts[1] != h.token {
-		return domain.UnauthorizedError("invalid token")
-	}
-
-	return nil
 }
 
 // writeError writes a domain error as JSON response
@@ -68,10 +64,14 @@ func (h *Handler) writeError(w http.ResponseWriter, err error) {
 			statusCode = http.StatusBadRequest
 		case domain.ErrorCodeUnauthorized:
 			statusCode = http.StatusUnauthorized
+		case domain.ErrorCodeForbidden:
+			statusCode = http.StatusForbidden
 		case domain.ErrorCodeTooManyRequests:
 			statusCode = http.StatusTooManyRequests
 		case domain.ErrorCodeServiceUnavailable:
 			statusCode = http.StatusServiceUnavailable
+		case domain.ErrorCodeConflict:
+			statusCode = http.StatusConflict
 		default:
 			statusCode = http.StatusInternalServerError
 		}
@@ -82,7 +82,7 @@ func (h *Handler) writeError(w http.ResponseWriter, err error) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(nahErr)
+	json.NewEncoder(w).Encode(nahErr.WithRequestID(w.Header().Get(domain.RequestIDHeader)))
 }
 
 // writeJSON writes a JSON response
@@ -99,6 +99,36 @@ func (h *Handler) writeText(w http.ResponseWriter, statusCode int, text string)
 	w.Write([]byte(text))
 }
 
+// wantsAsync reports whether the caller opted in to receiving an operation instead of the final resource
+// Clients opt in with ?async=true or a "Prefer: respond-async" header.
+func wantsAsync(r *http.Request) bool {
+	if r.URL.Query().Get("async") == "true" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Prefer"), "respond-async")
+}
+
+// parsePageOptions reads the page_size and page_token query parameters
+// A missing page_size falls back to domain.DefaultPageSize.
+func parsePageOptions(r *http.Request) (domain.PageOptions, error) {
+	page := domain.PageOptions{
+		PageSize:  domain.DefaultPageSize,
+		PageToken: r.URL.Query().Get("page_token"),
+	}
+	if raw := r.URL.Query().Get("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 || size > domain.MaxPageSize {
+			return page, domain.InvalidInputError("invalid page_size", map[string]interface{}{
+				"min":    1,
+				"max":    domain.MaxPageSize,
+				"actual": raw,
+			})
+		}
+		page.PageSize = size
+	}
+	return page, nil
+}
+
 // Project handlers
 
 // CreateProject handles POST /v1/projects
@@ -108,7 +138,8 @@ func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "POST"); err != nil {
+	// Project-restricted keys can't create projects
+	if err := h.authorizeProject(r, ""); err != nil {
 		h.writeError(w, err)
 		return
 	}
@@ -135,14 +166,14 @@ func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeProject(r, id); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	project, err := h.service.GetProject(id)
 	if err != nil {
 		h.writeError(w, err)
@@ -159,22 +190,47 @@ func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+	page, err := parsePageOptions(r)
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
 	opts := domain.ProjectListOptions{
-		Name: r.URL.Query().Get("name"),
+		Name:        r.URL.Query().Get("name"),
+		PageOptions: page,
+	}
+
+	// Project-restricted keys only see their own project
+	restricted, err := h.restrictedProject(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if restricted != "" {
+		projects := []*domain.Project{}
+		project, err := h.service.GetProject(restricted)
+		if err != nil && !domain.IsNotFound(err) {
+			h.writeError(w, err)
+			return
+		}
+		if project != nil && (opts.Name == "" || opts.Name == project.Name) {
+			projects = append(projects, project)
+		}
+		h.writeJSON(w, http.StatusOK, domain.NewListResponse(projects, ""))
+		return
 	}
 
-	projects, err := h.service.ListProjects(opts)
+	projects, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.Project, string, error) {
+		opts.PageOptions = page
+		return h.service.ListProjects(opts)
+	})
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, projects)
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(projects, next))
 }
 
 // UpdateProject handles PATCH /v1/projects/{id}
@@ -184,14 +240,14 @@ func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "PATCH"); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeProject(r, id); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	var req domain.UpdateProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
@@ -214,14 +270,14 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "DELETE"); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeProject(r, id); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	err := h.service.DeleteProject(id)
 	if err != nil {
 		h.writeError(w, err)
@@ -240,14 +296,24 @@ func (h *Handler) CreateInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	var req domain.CreateInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	if err := h.authorizeProject(r, req.ProjectID); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	var req domain.CreateInstanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if wantsAsync(r) {
+		op, err := h.service.CreateInstanceAsync(req)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusAccepted, op)
 		return
 	}
 
@@ -267,14 +333,14 @@ func (h *Handler) GetInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	instance, err := h.service.GetInstance(id)
 	if err != nil {
 		h.writeError(w, err)
@@ -291,25 +357,44 @@ func (h *Handler) ListInstances(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	page, err := parsePageOptions(r)
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
 	opts := domain.InstanceListOptions{
-		ProjectID: r.URL.Query().Get("project_id"),
-		Name:      r.URL.Query().Get("name"),
-		Region:    r.URL.Query().Get("region"),
-		Status:    r.URL.Query().Get("status"),
+		ProjectID:   r.URL.Query().Get("project_id"),
+		Name:        r.URL.Query().Get("name"),
+		Region:      r.URL.Query().Get("region"),
+		Status:      r.URL.Query().Get("status"),
+		PageOptions: page,
+	}
+
+	// Project-restricted keys only see instances in their own project
+	restricted, err := h.restrictedProject(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if restricted != "" {
+		if opts.ProjectID != "" && opts.ProjectID != restricted {
+			h.writeError(w, h.authorizeProject(r, opts.ProjectID))
+			return
+		}
+		opts.ProjectID = restricted
 	}
 
-	instances, err := h.service.ListInstances(opts)
+	instances, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.Instance, string, error) {
+		opts.PageOptions = page
+		return h.service.ListInstances(opts)
+	})
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, instances)
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(instances, next))
 }
 
 // UpdateInstance handles PATCH /v1/instances/{id}
@@ -319,20 +404,30 @@ func (h *Handler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	var req domain.UpdateInstanceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
 		return
 	}
 
+	if wantsAsync(r) {
+		op, err := h.service.UpdateInstanceAsync(id, req)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusAccepted, op)
+		return
+	}
+
 	instance, err := h.service.UpdateInstance(id, req)
 	if err != nil {
 		h.writeError(w, err)
@@ -349,13 +444,23 @@ func (h *Handler) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
+	if wantsAsync(r) {
+		op, err := h.service.DeleteInstanceAsync(id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusAccepted, op)
+		return
+	}
 
 	err := h.service.DeleteInstance(id)
 	if err != nil {
@@ -366,16 +471,106 @@ func (h *Handler) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Metadata handlers
+// InstanceAction handles POST /v1/instances/{id}/actions/{action}
+func (h *Handler) InstanceAction(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
 
-// CreateMetadata handles POST /v1/metadata
-func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	action := vars["action"]
+
+	// The body is optional; only resize needs one
+	var req domain.InstanceActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+		return
+	}
+
+	op, err := h.service.InstanceAction(id, action, req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusAccepted, op)
+}
+
+// Operation handlers
+
+// GetOperation handles GET /v1/operations/{id}
+func (h *Handler) GetOperation(w http.ResponseWriter, r *http.Request) {
 	if err := h.authenticate(r); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	op, err := h.service.GetOperation(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if err := h.authorizeProject(r, op.ProjectID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, op)
+}
+
+// ListOperations handles GET /v1/operations
+func (h *Handler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// Project-restricted keys can only fetch the operations on their project by ID
+	if err := h.authorizeProject(r, ""); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	page, err := parsePageOptions(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	opts := domain.OperationListOptions{
+		TargetType:  r.URL.Query().Get("target_type"),
+		TargetID:    r.URL.Query().Get("target_id"),
+		Status:      r.URL.Query().Get("status"),
+		PageOptions: page,
+	}
+
+	operations, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.Operation, string, error) {
+		opts.PageOptions = page
+		return h.service.ListOperations(opts)
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(operations, next))
+}
+
+// Metadata handlers
+
+// CreateMetadata handles POST /v1/metadata
+func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
 		h.writeError(w, err)
 		return
 	}
@@ -402,11 +597,6 @@ func (h *Handler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
-		h.writeError(w, err)
-		return
-	}
-
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -426,22 +616,27 @@ func (h *Handler) ListMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+	page, err := parsePageOptions(r)
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
 	opts := domain.MetadataListOptions{
-		Prefix: r.URL.Query().Get("prefix"),
+		Prefix:      r.URL.Query().Get("prefix"),
+		PageOptions: page,
 	}
 
-	metadata, err := h.service.ListMetadata(opts)
+	metadata, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.Metadata, string, error) {
+		opts.PageOptions = page
+		return h.service.ListMetadata(opts)
+	})
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, metadata)
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(metadata, next))
 }
 
 // UpdateMetadata handles PATCH /v1/metadata/{id}
@@ -451,11 +646,6 @@ func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
-		h.writeError(w, err)
-		return
-	}
-
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -481,11 +671,6 @@ func (h *Handler) DeleteMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
-		h.writeError(w, err)
-		return
-	}
-
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -511,6 +696,15 @@ func (h *Handler) CreateBucket(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
 		return
 	}
+	if wantsAsync(r) {
+		op, err := h.service.CreateBucketAsync(req)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusAccepted, op)
+		return
+	}
 	bucket, err := h.service.CreateBucket(req)
 	if err != nil {
 		h.writeError(w, err)
@@ -541,13 +735,21 @@ func (h *Handler) ListBuckets(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, err)
 		return
 	}
-	opts := domain.BucketListOptions{ Name: r.URL.Query().Get("name") }
-	buckets, err := h.service.ListBuckets(opts)
+	page, err := parsePageOptions(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	opts := domain.BucketListOptions{Name: r.URL.Query().Get("name"), PageOptions: page}
+	buckets, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.Bucket, string, error) {
+		opts.PageOptions = page
+		return h.service.ListBuckets(opts)
+	})
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
-	h.writeJSON(w, http.StatusOK, buckets)
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(buckets, next))
 }
 
 // UpdateBucket handles PATCH /v1/buckets/{id}
@@ -563,6 +765,15 @@ func (h *Handler) UpdateBucket(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
 		return
 	}
+	if wantsAsync(r) {
+		op, err := h.service.UpdateBucketAsync(id, req)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusAccepted, op)
+		return
+	}
 	bucket, err := h.service.UpdateBucket(id, req)
 	if err != nil {
 		h.writeError(w, err)
@@ -579,6 +790,15 @@ func (h *Handler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 	}
 	vars := mux.Vars(r)
 	id := vars["id"]
+	if wantsAsync(r) {
+		op, err := h.service.DeleteBucketAsync(id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusAccepted, op)
+		return
+	}
 	if err := h.service.DeleteBucket(id); err != nil {
 		h.writeError(w, err)
 		return
@@ -641,16 +861,25 @@ func (h *Handler) ListObjects(w http.ResponseWriter, r *http.Request) {
 	}
 	vars := mux.Vars(r)
 	bucketID := vars["bucket_id"]
-	opts := domain.ObjectListOptions{
-		BucketID: bucketID,
-		Prefix:   r.URL.Query().Get("prefix"),
+	page, err := parsePageOptions(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
 	}
-	objects, err := h.service.ListObjects(opts)
+	opts := domain.ObjectListOptions{
+		BucketID:    bucketID,
+		Prefix:      r.URL.Query().Get("prefix"),
+		PageOptions: page,
+	}
+	objects, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.Object, string, error) {
+		opts.PageOptions = page
+		return h.service.ListObjects(opts)
+	})
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
-	h.writeJSON(w, http.StatusOK, objects)
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(objects, next))
 }
 
 // UpdateObject handles PATCH /v1/bucket/{bucket_id}/objects/{id}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds how long the readiness checks may take together
+const readinessTimeout = 2 * time.Second
+
+// HealthStatus is the response of the health and readiness endpoints
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// readinessCheck is a named dependency check run by /readyz
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// AddReadinessCheck adds a dependency check to /readyz. The server is only ready
+// while every check returns nil. Call it before the server starts.
+func (h *Handler) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	h.readinessChecks = append(h.readinessChecks, readinessCheck{name: name, check: check})
+}
+
+// Drain marks the server as shutting down, so /readyz reports it as not ready
+// while outstanding requests complete
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Healthz handles GET /healthz
+// It reports that the process is up and serving, without checking dependencies.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Readyz handles GET /readyz
+// It runs every readiness check and responds with 503 if any fail, if the server is
+// draining, or if chaos makes readiness flap.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	ready := true
+	checks := make(map[string]string, len(h.readinessChecks)+2)
+	for _, c := range h.readinessChecks {
+		if err := c.check(ctx); err != nil {
+			checks[c.name] = err.Error()
+			ready = false
+		} else {
+			checks[c.name] = "ok"
+		}
+	}
+
+	if h.draining.Load() {
+		checks["draining"] = "server is shutting down"
+		ready = false
+	} else {
+		checks["draining"] = "ok"
+	}
+
+	if h.chaosService.FailReadiness(r) {
+		checks["chaos"] = "readiness flap injected"
+		ready = false
+	}
+
+	if !ready {
+		h.writeJSON(w, http.StatusServiceUnavailable, HealthStatus{Status: "not_ready", Checks: checks})
+		return
+	}
+	h.writeJSON(w, http.StatusOK, HealthStatus{Status: "ready", Checks: checks})
+}
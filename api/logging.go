@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/hypertf/nahcloud/service/chaos"
+)
+
+// validRequestID matches client-supplied request IDs that are safe to echo back
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestInfoKey struct{}
+
+// requestInfo carries what handlers learn about a request back to the access log,
+// and the request's credentials once they have been resolved
+type requestInfo struct {
+	subject     string
+	credentials *credentials
+}
+
+// SetLogger sets the logger used for access logs
+func (h *Handler) SetLogger(logger *slog.Logger) {
+	h.logger = logger
+}
+
+// loggingMiddleware assigns every request an ID, echoed in the X-Request-Id header,
+// and writes an access log entry once it completes
+func (h *Handler) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// Keep a caller's request ID so logs can be correlated across services
+		id := r.Header.Get(domain.RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(domain.RequestIDHeader, id)
+
+		info := &requestInfo{subject: "anonymous"}
+		r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+		r, faults := chaos.TrackFaults(r)
+		rec := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			// Chaos aborts connections by panicking; log the request before passing it on
+			aborted := recover()
+
+			route := routeTemplate(r)
+			injected := faults()
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int64("bytes", rec.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("request_id", id),
+				slog.String("subject", info.subject),
+				slog.Bool("chaos", len(injected) > 0),
+			}
+			if len(injected) > 0 {
+				attrs = append(attrs, slog.Any("faults", injected))
+			}
+			if aborted != nil {
+				attrs = append(attrs, slog.Bool("aborted", true))
+			}
+			h.logger.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+
+			if aborted != nil {
+				panic(aborted)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// routeTemplate returns the path template of the route a request matched, or its path
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// setSubject records who a request is authenticated as, for the access log
+func setSubject(r *http.Request, subject string) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.subject = subject
+	}
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the status code and size of a response. It passes
+// hijacking and flushing through, which chaos network faults rely on.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
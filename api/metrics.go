@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hypertf/nahcloud/service/chaos"
+	"github.com/hypertf/nahcloud/service/metrics"
+)
+
+// requestMetrics instruments the requests the router serves
+type requestMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+// newRequestMetrics registers request and chaos fault metrics
+func newRequestMetrics(registry *metrics.Registry, chaosService *chaos.ChaosService) *requestMetrics {
+	requests := &requestMetrics{
+		requests: registry.NewCounterVec("nahcloud_http_requests_total",
+			"HTTP requests served, by method, route and status.", "method", "route", "status"),
+		duration: registry.NewHistogramVec("nahcloud_http_request_duration_seconds",
+			"HTTP request latency, by method, route and status.", metrics.DefaultBuckets, "method", "route", "status"),
+	}
+
+	registry.NewCounterFunc("nahcloud_chaos_faults_total", "Chaos faults injected, by fault type.", []string{"fault"}, func() []metrics.Sample {
+		var samples []metrics.Sample
+		for fault, n := range chaosService.FaultCounts() {
+			samples = append(samples, metrics.Sample{LabelValues: []string{fault}, Value: float64(n)})
+		}
+		return samples
+	})
+
+	return requests
+}
+
+// SetMetrics sets the registry served at /metrics and registers the request and
+// chaos fault metrics on it. Call it before setting up the router.
+func (h *Handler) SetMetrics(registry *metrics.Registry) {
+	h.metrics = registry
+	h.requestMetrics = newRequestMetrics(registry, h.chaosService)
+}
+
+// metricsMiddleware counts requests and records their latency by route and status
+func (h *Handler) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			// Connections aborted by chaos are counted before the panic is passed on
+			aborted := recover()
+
+			status := strconv.Itoa(rec.status)
+			if aborted != nil {
+				status = "aborted"
+			}
+			route := routeTemplate(r)
+			h.requestMetrics.requests.Inc(r.Method, route, status)
+			h.requestMetrics.duration.Observe(time.Since(start).Seconds(), r.Method, route, status)
+
+			if aborted != nil {
+				panic(aborted)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// OAuthError is an OAuth2 token endpoint error response (RFC 6749 section 5.2)
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IssueToken handles POST /v1/oauth/token
+// Only the client_credentials grant is supported. Clients authenticate with an API
+// key's ID and secret, either through HTTP Basic authentication or as the
+// client_id and client_secret form parameters.
+func (h *Handler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	// Token responses must never be cached
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "client_credentials":
+	case "":
+		h.writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	default:
+		h.writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only the client_credentials grant is supported")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		// Basic credentials are form-encoded before they are combined
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		h.writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client credentials are required")
+		return
+	}
+
+	token, err := h.service.IssueAccessToken(clientID, clientSecret)
+	if err != nil {
+		if nahErr, ok := err.(*domain.NahError); ok && nahErr.Code == domain.ErrorCodeUnauthorized {
+			h.writeOAuthError(w, http.StatusUnauthorized, "invalid_client", nahErr.Message)
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, token)
+}
+
+// writeOAuthError writes an OAuth2 error response
+func (h *Handler) writeOAuthError(w http.ResponseWriter, statusCode int, code, description string) {
+	if statusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="nahcloud"`)
+	}
+	h.writeJSON(w, statusCode, OAuthError{Error: code, ErrorDescription: description})
+}
@@ -38,6 +38,13 @@ func SetupRouter(handler *Handler, version string) *mux.Router {
 		json.NewEncoder(w).Encode(info)
 	}).Methods("GET")
 
+	// Liveness and readiness probes
+	router.HandleFunc("/healthz", handler.Healthz).Methods("GET")
+	router.HandleFunc("/readyz", handler.Readyz).Methods("GET")
+
+	// Prometheus metrics endpoint
+	router.Handle("/metrics", handler.metrics.Handler()).Methods("GET")
+
 	// Web console routes
 	webHandler := web.NewHandler(handler.service)
 	webRouter := router.PathPrefix("/web").Subrouter()
@@ -64,6 +71,7 @@ func SetupRouter(handler *Handler, version string) *mux.Router {
 	webRouter.HandleFunc("/instances/{id}/edit", webHandler.EditInstanceForm).Methods("GET")
 	webRouter.HandleFunc("/instances/{id}", webHandler.UpdateInstance).Methods("PUT")
 	webRouter.HandleFunc("/instances/{id}", webHandler.DeleteInstance).Methods("DELETE")
+	webRouter.HandleFunc("/instances/{id}/actions/{action}", webHandler.InstanceAction).Methods("POST")
 	
 	// Metadata routes
 	webRouter.HandleFunc("/metadata", webHandler.ListMetadata).Methods("GET")
@@ -82,9 +90,40 @@ func SetupRouter(handler *Handler, version string) *mux.Router {
     webRouter.HandleFunc("/storage/buckets/{name}/objects", webHandler.CreateObject).Methods("POST")
     webRouter.HandleFunc("/storage/buckets/{name}/objects/{objid}", webHandler.ViewObject).Methods("GET")
 
+	// Terraform state routes
+	webRouter.HandleFunc("/tfstate", webHandler.ListTFStates).Methods("GET")
+	webRouter.HandleFunc("/tfstate/{id}", webHandler.TFStateHistory).Methods("GET")
+	webRouter.HandleFunc("/tfstate/{id}/diff", webHandler.TFStateDiff).Methods("GET")
+	webRouter.HandleFunc("/tfstate/{id}/lock", webHandler.ForceUnlockTFState).Methods("DELETE")
+
+	// Admin routes are registered ahead of the API prefix so chaos never applies to them
+	admin := router.PathPrefix("/v1/_admin").Subrouter()
+	admin.HandleFunc("/chaos", handler.GetChaosConfig).Methods("GET")
+	admin.HandleFunc("/chaos", handler.UpdateChaosConfig).Methods("PUT")
+	admin.HandleFunc("/chaos/rules", handler.AddChaosRule).Methods("POST")
+	admin.HandleFunc("/chaos/rules/{name}", handler.DeleteChaosRule).Methods("DELETE")
+	admin.HandleFunc("/chaos/seed", handler.ReseedChaos).Methods("POST")
+	admin.HandleFunc("/chaos/journal", handler.GetChaosJournal).Methods("GET")
+	admin.HandleFunc("/chaos/journal", handler.ClearChaosJournal).Methods("DELETE")
+	admin.HandleFunc("/ratelimit", handler.GetRateLimits).Methods("GET")
+	admin.HandleFunc("/ratelimit", handler.UpdateRateLimits).Methods("PUT")
+	admin.HandleFunc("/ratelimit/buckets", handler.ResetRateLimits).Methods("DELETE")
+	admin.HandleFunc("/keys", handler.CreateAPIKey).Methods("POST")
+	admin.HandleFunc("/keys", handler.ListAPIKeys).Methods("GET")
+	admin.HandleFunc("/keys/{id}", handler.GetAPIKey).Methods("GET")
+	admin.HandleFunc("/keys/{id}", handler.RevokeAPIKey).Methods("DELETE")
+	admin.HandleFunc("/tfstate/locks", handler.ListTFStateLocks).Methods("GET")
+	admin.HandleFunc("/tfstate/locks/{id}", handler.ForceUnlockTFState).Methods("DELETE")
+
 	// API prefix
 	api := router.PathPrefix("/v1").Subrouter()
 
+	// Chaos rules apply to every API route
+	api.Use(handler.chaosService.Middleware)
+
+	// OAuth2 token endpoint
+	api.HandleFunc("/oauth/token", handler.IssueToken).Methods("POST")
+
 	// Project routes
 	api.HandleFunc("/projects", handler.CreateProject).Methods("POST")
 	api.HandleFunc("/projects", handler.ListProjects).Methods("GET")
@@ -98,6 +137,11 @@ func SetupRouter(handler *Handler, version string) *mux.Router {
 	api.HandleFunc("/instances/{id}", handler.GetInstance).Methods("GET")
 	api.HandleFunc("/instances/{id}", handler.UpdateInstance).Methods("PATCH")
 	api.HandleFunc("/instances/{id}", handler.DeleteInstance).Methods("DELETE")
+	api.HandleFunc("/instances/{id}/actions/{action}", handler.InstanceAction).Methods("POST")
+
+	// Operation routes
+	api.HandleFunc("/operations", handler.ListOperations).Methods("GET")
+	api.HandleFunc("/operations/{id}", handler.GetOperation).Methods("GET")
 
 	// Metadata routes
 	api.HandleFunc("/metadata", handler.CreateMetadata).Methods("POST")
@@ -122,17 +166,28 @@ func SetupRouter(handler *Handler, version string) *mux.Router {
 	api.HandleFunc("/bucket/{bucket_id}/objects/{id}", handler.DeleteObject).Methods("DELETE")
 
 	// Terraform state routes
+	api.HandleFunc("/tfstate", handler.TFStateList).Methods("GET") // optional: prefix
 	api.HandleFunc("/tfstate/{id}", handler.TFStateGet).Methods("GET")
 	api.HandleFunc("/tfstate/{id}", handler.TFStatePost).Methods("POST")
 	api.HandleFunc("/tfstate/{id}", handler.TFStateDelete).Methods("DELETE")
 	api.HandleFunc("/tfstate/{id}", handler.TFStateLock).Methods("LOCK")
 	api.HandleFunc("/tfstate/{id}", handler.TFStateUnlock).Methods("UNLOCK")
+	api.HandleFunc("/tfstate/{id}/lock", handler.TFStateLockGet).Methods("GET")
+	api.HandleFunc("/tfstate/{id}/versions", handler.TFStateVersions).Methods("GET")
+	api.HandleFunc("/tfstate/{id}/versions/{version}", handler.TFStateVersionGet).Methods("GET")
+	api.HandleFunc("/tfstate/{id}/versions/{version}/rollback", handler.TFStateRollback).Methods("POST")
+
+	// Assign request IDs and log every request, including those throttled or faulted
+	router.Use(handler.loggingMiddleware)
+
+	// Count requests and record their latency per route and status
+	router.Use(handler.metricsMiddleware)
 
 	// Add CORS middleware for development
 	router.Use(corsMiddleware)
 
-	// Add logging middleware
-	router.Use(loggingMiddleware)
+	// Enforce token bucket quotas on API requests, ahead of any chaos
+	router.Use(handler.rateLimiter.Middleware(handler.rateLimitSubject))
 
 	return router
 }
@@ -142,7 +197,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, X-Nah-No-Chaos, X-Nah-Latency")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, Prefer, X-Nah-No-Chaos, X-Nah-Latency, X-Nah-Chaos-Key, X-Request-Id")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Nah-Chaos-Key, X-Request-Id, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -152,12 +208,3 @@ func corsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-// loggingMiddleware adds basic request logging
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Add proper structured logging here
-		// For now, we'll let the main server handle logging
-		next.ServeHTTP(w, r)
-	})
-}
\ No newline at end of file
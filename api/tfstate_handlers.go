@@ -4,11 +4,36 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/hypertf/nahcloud/domain"
+	"github.com/hypertf/nahcloud/service/chaos"
 )
 
+// TFStateList handles GET /v1/tfstate
+func (h *Handler) TFStateList(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	page, err := parsePageOptions(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	opts := domain.TFStateListOptions{Prefix: r.URL.Query().Get("prefix"), PageOptions: page}
+	states, next, err := chaos.ApplyPaginationChaos(h.chaosService, r, opts.PageOptions, func(page domain.PageOptions) ([]*domain.TFState, string, error) {
+		opts.PageOptions = page
+		return h.service.ListTFStates(opts)
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(states, next))
+}
+
 // TFStateGet handles GET /v1/tfstate/{state_id}
 func (h *Handler) TFStateGet(w http.ResponseWriter, r *http.Request) {
 	if err := h.authenticate(r); err != nil {
@@ -42,14 +67,8 @@ func (h *Handler) TFStatePost(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Enforce lock if present
-	if rawLock, lockInfo, err := h.service.GetTFStateLock(id); err == nil && lockInfo != nil {
-		provided := r.URL.Query().Get("ID")
-		if provided == "" || provided != lockInfo.ID {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusLocked) // 423
-			w.Write([]byte(rawLock))
-			return
-		}
+	if !h.checkTFStateLock(w, r, id) {
+		return
 	}
 
 	body, err := io.ReadAll(r.Body)
@@ -57,13 +76,31 @@ func (h *Handler) TFStatePost(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, domain.InternalError("failed to read request body"))
 		return
 	}
-	if err := h.service.SetTFState(id, string(body)); err != nil {
+	if _, err := h.service.SetTFState(id, string(body), r.URL.Query().Get("ID")); err != nil {
 		h.writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// checkTFStateLock reports whether a request may write a state, which it may
+// unless the state is locked and the request doesn't carry the lock's ID. If it
+// may not, the lock is written in a 423 response.
+func (h *Handler) checkTFStateLock(w http.ResponseWriter, r *http.Request, id string) bool {
+	rawLock, lockInfo, err := h.service.GetTFStateLock(id)
+	if err != nil || lockInfo == nil {
+		return true
+	}
+	provided := r.URL.Query().Get("ID")
+	if provided == "" || provided != lockInfo.ID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusLocked) // 423
+		w.Write([]byte(rawLock))
+		return false
+	}
+	return true
+}
+
 // TFStateDelete handles DELETE /v1/tfstate/{state_id}
 func (h *Handler) TFStateDelete(w http.ResponseWriter, r *http.Request) {
 	if err := h.authenticate(r); err != nil {
@@ -74,14 +111,8 @@ func (h *Handler) TFStateDelete(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Enforce lock if present
-	if rawLock, lockInfo, err := h.service.GetTFStateLock(id); err == nil && lockInfo != nil {
-		provided := r.URL.Query().Get("ID")
-		if provided == "" || provided != lockInfo.ID {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusLocked) // 423
-			w.Write([]byte(rawLock))
-			return
-		}
+	if !h.checkTFStateLock(w, r, id) {
+		return
 	}
 
 	if err := h.service.DeleteTFState(id); err != nil {
@@ -145,8 +176,12 @@ func (h *Handler) TFStateUnlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if lockInfo == nil || provided == lockInfo.ID {
-		// No parsed info or matching ID: unlock
-		if _, _, err := h.service.UnlockTFState(id); err != nil {
+		// No parsed info or matching ID: unlock, unless the lock has changed hands since
+		heldBy := ""
+		if lockInfo != nil {
+			heldBy = lockInfo.ID
+		}
+		if err := h.service.UnlockTFState(id, heldBy); err != nil && !domain.IsNotFound(err) {
 			h.writeError(w, err)
 			return
 		}
@@ -158,3 +193,95 @@ func (h *Handler) TFStateUnlock(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusConflict) // 409
 	w.Write([]byte(rawLock))
 }
+
+// TFStateLockGet handles GET /v1/tfstate/{state_id}/lock
+func (h *Handler) TFStateLockGet(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	status, err := h.service.GetTFStateLockStatus(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, status.Lock)
+}
+
+// TFStateVersions handles GET /v1/tfstate/{state_id}/versions
+func (h *Handler) TFStateVersions(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	versions, err := h.service.ListTFStateVersions(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, domain.NewListResponse(versions, ""))
+}
+
+// TFStateVersionGet handles GET /v1/tfstate/{state_id}/versions/{version}, which
+// returns the state as it was at that version
+func (h *Handler) TFStateVersionGet(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	version, err := tfStateVersionParam(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	_, state, err := h.service.GetTFStateVersion(id, version)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(state))
+}
+
+// TFStateRollback handles POST /v1/tfstate/{state_id}/versions/{version}/rollback
+func (h *Handler) TFStateRollback(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	version, err := tfStateVersionParam(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// Rolling back is a write, so it honours the lock like POST
+	if !h.checkTFStateLock(w, r, id) {
+		return
+	}
+
+	restored, err := h.service.RollbackTFState(id, version, r.URL.Query().Get("ID"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, restored)
+}
+
+// tfStateVersionParam parses the version number in a state version URL
+func tfStateVersionParam(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["version"]
+	version, err := strconv.Atoi(raw)
+	if err != nil || version < 1 {
+		return 0, domain.InvalidInputError("version must be a positive integer", map[string]interface{}{"version": raw})
+	}
+	return version, nil
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -9,7 +12,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/hypertf/nahcloud/service"
 	"github.com/hypertf/nahcloud/service/chaos"
+	"github.com/hypertf/nahcloud/service/ratelimit"
 )
 
 // Version is set at build time
@@ -17,10 +22,17 @@ var Version = "dev"
 
 // Config holds all server configuration
 type Config struct {
-	Addr      string      `mapstructure:"addr"`
-	Token     string      `mapstructure:"token"`
-	SQLiteDSN string      `mapstructure:"sqlite_dsn"`
-	Chaos     ChaosConfig `mapstructure:"chaos"`
+	Addr          string          `mapstructure:"addr"`
+	Token         string          `mapstructure:"token"`
+	Storage       string          `mapstructure:"storage"` // sqlite or memory
+	SQLiteDSN     string          `mapstructure:"sqlite_dsn"`
+	ShutdownDelay time.Duration   `mapstructure:"shutdown_delay"` // how long /readyz reports draining before shutdown
+	Chaos         ChaosConfig     `mapstructure:"chaos"`
+	RateLimit     RateLimitConfig `mapstructure:"rate_limit"`
+	Lifecycle     LifecycleConfig `mapstructure:"lifecycle"`
+	TFState       TFStateConfig   `mapstructure:"tfstate"`
+	OAuth         OAuthConfig     `mapstructure:"oauth"`
+	Log           LogConfig       `mapstructure:"log"`
 }
 
 // ChaosConfig holds chaos engineering configuration
@@ -31,6 +43,12 @@ type ChaosConfig struct {
 	ErrorRate    ErrorRateConfig   `mapstructure:"error_rate"`
 	ErrorTypes   []int             `mapstructure:"error_types"`
 	ErrorWeights []int             `mapstructure:"error_weights"`
+	Pagination   PaginationConfig  `mapstructure:"pagination"`
+	Network      NetworkConfig     `mapstructure:"network"`
+	Retry        RetryConfig       `mapstructure:"retry"`
+	Auth         AuthChaosConfig   `mapstructure:"auth"`
+	Health       HealthChaosConfig `mapstructure:"health"`
+	Rules        []ChaosRuleConfig `mapstructure:"rules"`
 }
 
 // LatencyConfig holds latency injection settings
@@ -39,6 +57,9 @@ type LatencyConfig struct {
 	ProjectsMS  string `mapstructure:"projects_ms"`
 	InstancesMS string `mapstructure:"instances_ms"`
 	MetadataMS  string `mapstructure:"metadata_ms"`
+	BucketsMS   string `mapstructure:"buckets_ms"`
+	ObjectsMS   string `mapstructure:"objects_ms"`
+	TFStateMS   string `mapstructure:"tfstate_ms"`
 }
 
 // ErrorRateConfig holds error injection rates
@@ -47,15 +68,186 @@ type ErrorRateConfig struct {
 	ProjectsGet float64 `mapstructure:"projects_get"`
 	Instances   float64 `mapstructure:"instances"`
 	Metadata    float64 `mapstructure:"metadata"`
+	Buckets     float64 `mapstructure:"buckets"`
+	Objects     float64 `mapstructure:"objects"`
+	TFState     float64 `mapstructure:"tfstate"`
+	TFStateLock float64 `mapstructure:"tfstate_lock"`
+}
+
+// PaginationConfig holds pagination fault injection rates
+type PaginationConfig struct {
+	TruncateRate     float64 `mapstructure:"truncate_rate"`
+	ExpiredTokenRate float64 `mapstructure:"expired_token_rate"`
+	DuplicateRate    float64 `mapstructure:"duplicate_rate"`
+	SkipRate         float64 `mapstructure:"skip_rate"`
+}
+
+// NetworkConfig holds transport-level fault injection rates
+type NetworkConfig struct {
+	ResetRate    float64 `mapstructure:"reset_rate"`
+	SlowBodyRate float64 `mapstructure:"slow_body_rate"`
+	TruncateRate float64 `mapstructure:"truncate_rate"`
+	TimeoutRate  float64 `mapstructure:"timeout_rate"`
+}
+
+// RetryConfig holds the retry hints sent with injected 429 and 503 responses
+type RetryConfig struct {
+	AfterSeconds   int    `mapstructure:"after_seconds"`
+	AfterFormat    string `mapstructure:"after_format"`
+	RateLimitLimit int    `mapstructure:"rate_limit_limit"`
+}
+
+// AuthChaosConfig holds authentication fault rates
+type AuthChaosConfig struct {
+	TokenExpiryRate float64 `mapstructure:"token_expiry_rate"`
+}
+
+// HealthChaosConfig holds health check fault rates
+type HealthChaosConfig struct {
+	ReadinessFlapRate float64 `mapstructure:"readiness_flap_rate"`
+}
+
+// ChaosRuleConfig holds a chaos rule read from the config file
+type ChaosRuleConfig struct {
+	Name        string            `mapstructure:"name"`
+	Match       ChaosMatchConfig  `mapstructure:"match"`
+	Action      ChaosActionConfig `mapstructure:"action"`
+	Probability float64           `mapstructure:"probability"`
+}
+
+// ChaosMatchConfig holds the request conditions of a chaos rule
+type ChaosMatchConfig struct {
+	Methods  []string          `mapstructure:"methods"`
+	Path     string            `mapstructure:"path"`
+	Resource string            `mapstructure:"resource"`
+	Headers  map[string]string `mapstructure:"headers"`
+	NthCall  int               `mapstructure:"nth_call"`
+}
+
+// ChaosActionConfig holds the fault a chaos rule injects
+type ChaosActionConfig struct {
+	Type         string                   `mapstructure:"type"`
+	Latency      *ChaosLatencyRangeConfig `mapstructure:"latency"`
+	Status       int                      `mapstructure:"status"`
+	Body         string                   `mapstructure:"body"`
+	PartialBytes int                      `mapstructure:"partial_bytes"`
+	ChunkBytes   int                      `mapstructure:"chunk_bytes"`
+	ChunkDelayMS int                      `mapstructure:"chunk_delay_ms"`
+	TimeoutMS    int                      `mapstructure:"timeout_ms"`
+
+	RetryAfterSeconds int    `mapstructure:"retry_after_seconds"`
+	RetryAfterFormat  string `mapstructure:"retry_after_format"`
+}
+
+// ChaosLatencyRangeConfig holds a rule's delay range in milliseconds, in the same
+// shape as the admin API's rules
+type ChaosLatencyRangeConfig struct {
+	Min int `mapstructure:"min"`
+	Max int `mapstructure:"max"`
+}
+
+// ToRule converts a configured rule to the chaos service's Rule type
+func (c ChaosRuleConfig) ToRule() chaos.Rule {
+	var latency *chaos.LatencyRange
+	if c.Action.Latency != nil {
+		latency = &chaos.LatencyRange{Min: c.Action.Latency.Min, Max: c.Action.Latency.Max}
+	}
+
+	return chaos.Rule{
+		Name: c.Name,
+		Match: chaos.Match{
+			Methods:  c.Match.Methods,
+			Path:     c.Match.Path,
+			Resource: c.Match.Resource,
+			Headers:  c.Match.Headers,
+			NthCall:  c.Match.NthCall,
+		},
+		Action: chaos.Action{
+			Type:         c.Action.Type,
+			Latency:      latency,
+			Status:       c.Action.Status,
+			Body:         c.Action.Body,
+			PartialBytes: c.Action.PartialBytes,
+			ChunkBytes:   c.Action.ChunkBytes,
+			ChunkDelayMS: c.Action.ChunkDelayMS,
+			TimeoutMS:    c.Action.TimeoutMS,
+
+			RetryAfterSeconds: c.Action.RetryAfterSeconds,
+			RetryAfterFormat:  c.Action.RetryAfterFormat,
+		},
+		Probability: c.Probability,
+	}
+}
+
+// RateLimitConfig holds token bucket rate limiting settings
+type RateLimitConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	WritesPerSecond float64       `mapstructure:"writes_per_second"`
+	ReadsPerSecond  float64       `mapstructure:"reads_per_second"`
+	Burst           int           `mapstructure:"burst"`
+	Limits          []LimitConfig `mapstructure:"limits"`
+}
+
+// LimitConfig holds a rate limit read from the config file
+type LimitConfig struct {
+	Name     string   `mapstructure:"name"`
+	Methods  []string `mapstructure:"methods"`
+	Resource string   `mapstructure:"resource"`
+	Rate     float64  `mapstructure:"rate"`
+	Burst    int      `mapstructure:"burst"`
+}
+
+// ToLimit converts a configured limit to the rate limiter's Limit type
+func (c LimitConfig) ToLimit() ratelimit.Limit {
+	return ratelimit.Limit{
+		Name:     c.Name,
+		Methods:  c.Methods,
+		Resource: c.Resource,
+		Rate:     c.Rate,
+		Burst:    c.Burst,
+	}
+}
+
+// LifecycleConfig holds instance lifecycle settings
+type LifecycleConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Pending      time.Duration `mapstructure:"pending"`
+	Provisioning time.Duration `mapstructure:"provisioning"`
+	Stopping     time.Duration `mapstructure:"stopping"`
+	Rebooting    time.Duration `mapstructure:"rebooting"`
+	Terminating  time.Duration `mapstructure:"terminating"`
+	TickInterval time.Duration `mapstructure:"tick_interval"`
+}
+
+// TFStateConfig holds Terraform state backend settings
+type TFStateConfig struct {
+	Strict  bool          `mapstructure:"strict"`   // enforce matching lineage and increasing serials
+	LockTTL time.Duration `mapstructure:"lock_ttl"` // 0 keeps locks until released
+}
+
+// OAuthConfig holds OAuth2 token endpoint settings
+type OAuthConfig struct {
+	TokenTTL   time.Duration `mapstructure:"token_ttl"`
+	SigningKey string        `mapstructure:"signing_key"`
+}
+
+// LogConfig holds logging settings
+type LogConfig struct {
+	Level  string `mapstructure:"level"`  // debug, info, warn or error
+	Format string `mapstructure:"format"` // text or json
+	File   string `mapstructure:"file"`   // empty logs to stderr
 }
 
 // setupConfig initializes viper with flags, env vars, and config file support
 func setupConfig(cmd *cobra.Command) {
 	// Define flags
-	cmd.Flags().StringP("config", "c", "", "Config file path (YAML, JSON, or TOML)")
+	// The config file and database are shared with the migrate subcommand
+	cmd.PersistentFlags().StringP("config", "c", "", "Config file path (YAML, JSON, or TOML)")
 	cmd.Flags().String("addr", ":8080", "HTTP server address")
 	cmd.Flags().String("token", "", "Authentication token")
-	cmd.Flags().String("sqlite-dsn", "", "SQLite database path")
+	cmd.Flags().String("storage", "sqlite", "Storage backend: sqlite or memory (memory is lost on exit)")
+	cmd.PersistentFlags().String("sqlite-dsn", "", "SQLite database path")
+	cmd.Flags().Duration("shutdown-delay", 0, "How long /readyz reports draining before the server stops accepting connections")
 
 	// Chaos flags
 	cmd.Flags().Bool("chaos-enabled", false, "Enable chaos engineering")
@@ -64,35 +256,138 @@ func setupConfig(cmd *cobra.Command) {
 	cmd.Flags().String("chaos-latency-projects", "", "Projects latency range in ms")
 	cmd.Flags().String("chaos-latency-instances", "", "Instances latency range in ms")
 	cmd.Flags().String("chaos-latency-metadata", "", "Metadata latency range in ms")
+	cmd.Flags().String("chaos-latency-buckets", "", "Buckets latency range in ms")
+	cmd.Flags().String("chaos-latency-objects", "", "Objects latency range in ms")
+	cmd.Flags().String("chaos-latency-tfstate", "", "Terraform state backend latency range in ms")
 	cmd.Flags().Float64("chaos-errrate-projects", 0.0, "Error rate for projects (0.0-1.0)")
 	cmd.Flags().Float64("chaos-errrate-projects-get", 0.0, "Error rate for projects GET (0.0-1.0)")
 	cmd.Flags().Float64("chaos-errrate-instances", 0.0, "Error rate for instances (0.0-1.0)")
 	cmd.Flags().Float64("chaos-errrate-metadata", 0.0, "Error rate for metadata (0.0-1.0)")
+	cmd.Flags().Float64("chaos-errrate-buckets", 0.0, "Error rate for buckets (0.0-1.0)")
+	cmd.Flags().Float64("chaos-errrate-objects", 0.0, "Error rate for objects (0.0-1.0)")
+	cmd.Flags().Float64("chaos-errrate-tfstate", 0.0, "Error rate for Terraform state GET/POST/DELETE (0.0-1.0)")
+	cmd.Flags().Float64("chaos-errrate-tfstate-lock", 0.0, "Error rate for Terraform state LOCK/UNLOCK (0.0-1.0)")
 	cmd.Flags().IntSlice("chaos-error-types", []int{503, 500, 429}, "Error HTTP status codes to inject")
 	cmd.Flags().IntSlice("chaos-error-weights", []int{3, 2, 1}, "Weights for error types")
+	cmd.Flags().Float64("chaos-page-truncate-rate", 0.0, "Rate of list pages returning fewer items than requested (0.0-1.0)")
+	cmd.Flags().Float64("chaos-page-expired-token-rate", 0.0, "Rate of page tokens rejected as expired (0.0-1.0)")
+	cmd.Flags().Float64("chaos-page-duplicate-rate", 0.0, "Rate of list pages repeating an item on the next page (0.0-1.0)")
+	cmd.Flags().Float64("chaos-page-skip-rate", 0.0, "Rate of list pages skipping an item at the page boundary (0.0-1.0)")
+	cmd.Flags().Float64("chaos-net-reset-rate", 0.0, "Rate of requests answered with a TCP connection reset (0.0-1.0)")
+	cmd.Flags().Float64("chaos-net-slow-body-rate", 0.0, "Rate of responses whose body is trickled out slowly (0.0-1.0)")
+	cmd.Flags().Float64("chaos-net-truncate-rate", 0.0, "Rate of responses with a truncated body (0.0-1.0)")
+	cmd.Flags().Float64("chaos-net-timeout-rate", 0.0, "Rate of requests that hang without a response (0.0-1.0)")
+	cmd.Flags().Int("chaos-retry-after", 0, "Retry-After seconds sent with injected 429/503 responses (0 = omit)")
+	cmd.Flags().String("chaos-retry-after-format", "seconds", "Retry-After format: \"seconds\" or \"date\" (HTTP-date)")
+	cmd.Flags().Int("chaos-rate-limit-limit", 0, "X-RateLimit-Limit sent with injected 429 responses (0 = omit X-RateLimit-* headers)")
+	cmd.Flags().Float64("chaos-token-expiry-rate", 0.0, "Rate of valid OAuth access tokens rejected as expired (0.0-1.0)")
+	cmd.Flags().Float64("chaos-readiness-flap-rate", 0.0, "Rate of /readyz probes that report not ready (0.0-1.0)")
+
+	// Rate limit flags
+	cmd.Flags().Bool("ratelimit-enabled", false, "Enforce token bucket rate limits per token and resource type")
+	cmd.Flags().Float64("ratelimit-writes-per-second", 0.0, "Writes allowed per second per token and resource type (0 = unlimited)")
+	cmd.Flags().Float64("ratelimit-reads-per-second", 0.0, "Reads allowed per second per token and resource type (0 = unlimited)")
+	cmd.Flags().Int("ratelimit-burst", 0, "Bucket capacity for the read and write limits (0 = the rate rounded up)")
+
+	// Lifecycle flags
+	cmd.Flags().Bool("lifecycle-enabled", false, "Move instances through transitional states (pending, provisioning, stopping, rebooting, terminating)")
+	cmd.Flags().Duration("lifecycle-pending", 2*time.Second, "Time an instance stays pending")
+	cmd.Flags().Duration("lifecycle-provisioning", 5*time.Second, "Time an instance stays provisioning")
+	cmd.Flags().Duration("lifecycle-stopping", 3*time.Second, "Time an instance stays stopping")
+	cmd.Flags().Duration("lifecycle-rebooting", 3*time.Second, "Time an instance stays rebooting")
+	cmd.Flags().Duration("lifecycle-terminating", 3*time.Second, "Time an instance stays terminating before it is deleted")
+	cmd.Flags().Duration("lifecycle-tick-interval", service.DefaultLifecycleTickInterval, "How often the lifecycle worker advances instances")
+
+	// Terraform state flags
+	cmd.Flags().Bool("tfstate-strict", false, "Reject state writes with a different lineage or an older serial (409)")
+	cmd.Flags().Duration("tfstate-lock-ttl", 0, "How long a state lock lasts before another LOCK may take it over (0 = until released)")
+
+	// Logging flags
+	cmd.Flags().String("log-level", "info", "Log level: debug, info, warn or error")
+	cmd.Flags().String("log-format", "text", "Log format: text or json")
+	cmd.Flags().String("log-file", "", "Append logs to this file instead of stderr")
+
+	// OAuth flags
+	cmd.Flags().Duration("oauth-token-ttl", service.DefaultTokenTTL, "Lifetime of access tokens issued by /v1/oauth/token")
+	cmd.Flags().String("oauth-signing-key", "", "Key used to sign access tokens (empty = random per start)")
 
 	// Bind flags to viper
 	viper.BindPFlag("addr", cmd.Flags().Lookup("addr"))
 	viper.BindPFlag("token", cmd.Flags().Lookup("token"))
-	viper.BindPFlag("sqlite_dsn", cmd.Flags().Lookup("sqlite-dsn"))
+	viper.BindPFlag("storage", cmd.Flags().Lookup("storage"))
+	viper.BindPFlag("sqlite_dsn", cmd.PersistentFlags().Lookup("sqlite-dsn"))
+	viper.BindPFlag("shutdown_delay", cmd.Flags().Lookup("shutdown-delay"))
 	viper.BindPFlag("chaos.enabled", cmd.Flags().Lookup("chaos-enabled"))
 	viper.BindPFlag("chaos.seed", cmd.Flags().Lookup("chaos-seed"))
 	viper.BindPFlag("chaos.latency.global_ms", cmd.Flags().Lookup("chaos-latency-global"))
 	viper.BindPFlag("chaos.latency.projects_ms", cmd.Flags().Lookup("chaos-latency-projects"))
 	viper.BindPFlag("chaos.latency.instances_ms", cmd.Flags().Lookup("chaos-latency-instances"))
 	viper.BindPFlag("chaos.latency.metadata_ms", cmd.Flags().Lookup("chaos-latency-metadata"))
+	viper.BindPFlag("chaos.latency.buckets_ms", cmd.Flags().Lookup("chaos-latency-buckets"))
+	viper.BindPFlag("chaos.latency.objects_ms", cmd.Flags().Lookup("chaos-latency-objects"))
+	viper.BindPFlag("chaos.latency.tfstate_ms", cmd.Flags().Lookup("chaos-latency-tfstate"))
 	viper.BindPFlag("chaos.error_rate.projects", cmd.Flags().Lookup("chaos-errrate-projects"))
 	viper.BindPFlag("chaos.error_rate.projects_get", cmd.Flags().Lookup("chaos-errrate-projects-get"))
 	viper.BindPFlag("chaos.error_rate.instances", cmd.Flags().Lookup("chaos-errrate-instances"))
 	viper.BindPFlag("chaos.error_rate.metadata", cmd.Flags().Lookup("chaos-errrate-metadata"))
+	viper.BindPFlag("chaos.error_rate.buckets", cmd.Flags().Lookup("chaos-errrate-buckets"))
+	viper.BindPFlag("chaos.error_rate.objects", cmd.Flags().Lookup("chaos-errrate-objects"))
+	viper.BindPFlag("chaos.error_rate.tfstate", cmd.Flags().Lookup("chaos-errrate-tfstate"))
+	viper.BindPFlag("chaos.error_rate.tfstate_lock", cmd.Flags().Lookup("chaos-errrate-tfstate-lock"))
 	viper.BindPFlag("chaos.error_types", cmd.Flags().Lookup("chaos-error-types"))
 	viper.BindPFlag("chaos.error_weights", cmd.Flags().Lookup("chaos-error-weights"))
+	viper.BindPFlag("chaos.pagination.truncate_rate", cmd.Flags().Lookup("chaos-page-truncate-rate"))
+	viper.BindPFlag("chaos.pagination.expired_token_rate", cmd.Flags().Lookup("chaos-page-expired-token-rate"))
+	viper.BindPFlag("chaos.pagination.duplicate_rate", cmd.Flags().Lookup("chaos-page-duplicate-rate"))
+	viper.BindPFlag("chaos.pagination.skip_rate", cmd.Flags().Lookup("chaos-page-skip-rate"))
+	viper.BindPFlag("chaos.network.reset_rate", cmd.Flags().Lookup("chaos-net-reset-rate"))
+	viper.BindPFlag("chaos.network.slow_body_rate", cmd.Flags().Lookup("chaos-net-slow-body-rate"))
+	viper.BindPFlag("chaos.network.truncate_rate", cmd.Flags().Lookup("chaos-net-truncate-rate"))
+	viper.BindPFlag("chaos.network.timeout_rate", cmd.Flags().Lookup("chaos-net-timeout-rate"))
+	viper.BindPFlag("chaos.retry.after_seconds", cmd.Flags().Lookup("chaos-retry-after"))
+	viper.BindPFlag("chaos.retry.after_format", cmd.Flags().Lookup("chaos-retry-after-format"))
+	viper.BindPFlag("chaos.retry.rate_limit_limit", cmd.Flags().Lookup("chaos-rate-limit-limit"))
+	viper.BindPFlag("chaos.auth.token_expiry_rate", cmd.Flags().Lookup("chaos-token-expiry-rate"))
+	viper.BindPFlag("chaos.health.readiness_flap_rate", cmd.Flags().Lookup("chaos-readiness-flap-rate"))
+	viper.BindPFlag("rate_limit.enabled", cmd.Flags().Lookup("ratelimit-enabled"))
+	viper.BindPFlag("rate_limit.writes_per_second", cmd.Flags().Lookup("ratelimit-writes-per-second"))
+	viper.BindPFlag("rate_limit.reads_per_second", cmd.Flags().Lookup("ratelimit-reads-per-second"))
+	viper.BindPFlag("rate_limit.burst", cmd.Flags().Lookup("ratelimit-burst"))
+	viper.BindPFlag("lifecycle.enabled", cmd.Flags().Lookup("lifecycle-enabled"))
+	viper.BindPFlag("lifecycle.pending", cmd.Flags().Lookup("lifecycle-pending"))
+	viper.BindPFlag("lifecycle.provisioning", cmd.Flags().Lookup("lifecycle-provisioning"))
+	viper.BindPFlag("lifecycle.stopping", cmd.Flags().Lookup("lifecycle-stopping"))
+	viper.BindPFlag("lifecycle.rebooting", cmd.Flags().Lookup("lifecycle-rebooting"))
+	viper.BindPFlag("lifecycle.terminating", cmd.Flags().Lookup("lifecycle-terminating"))
+	viper.BindPFlag("lifecycle.tick_interval", cmd.Flags().Lookup("lifecycle-tick-interval"))
+	viper.BindPFlag("tfstate.strict", cmd.Flags().Lookup("tfstate-strict"))
+	viper.BindPFlag("tfstate.lock_ttl", cmd.Flags().Lookup("tfstate-lock-ttl"))
+	viper.BindPFlag("log.level", cmd.Flags().Lookup("log-level"))
+	viper.BindPFlag("log.format", cmd.Flags().Lookup("log-format"))
+	viper.BindPFlag("log.file", cmd.Flags().Lookup("log-file"))
+	viper.BindPFlag("oauth.token_ttl", cmd.Flags().Lookup("oauth-token-ttl"))
+	viper.BindPFlag("oauth.signing_key", cmd.Flags().Lookup("oauth-signing-key"))
 
 	// Set up environment variable binding with NAH_ prefix
 	viper.SetEnvPrefix("NAH")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 	viper.AutomaticEnv()
 
+	// Network faults also accept the short names used by the chaos package
+	viper.BindEnv("chaos.network.reset_rate", "NAH_CHAOS_NETWORK_RESET_RATE", "NAH_NET_RESET_RATE")
+	viper.BindEnv("chaos.network.slow_body_rate", "NAH_CHAOS_NETWORK_SLOW_BODY_RATE", "NAH_NET_SLOW_BODY_RATE")
+	viper.BindEnv("chaos.network.truncate_rate", "NAH_CHAOS_NETWORK_TRUNCATE_RATE", "NAH_NET_TRUNCATE_RATE")
+	viper.BindEnv("chaos.network.timeout_rate", "NAH_CHAOS_NETWORK_TIMEOUT_RATE", "NAH_NET_TIMEOUT_RATE")
+
+	// Retry hints likewise
+	viper.BindEnv("chaos.retry.after_seconds", "NAH_CHAOS_RETRY_AFTER_SECONDS", "NAH_RETRY_AFTER_SECONDS")
+	viper.BindEnv("chaos.retry.after_format", "NAH_CHAOS_RETRY_AFTER_FORMAT", "NAH_RETRY_AFTER_FORMAT")
+	viper.BindEnv("chaos.retry.rate_limit_limit", "NAH_CHAOS_RETRY_RATE_LIMIT_LIMIT", "NAH_RATE_LIMIT_LIMIT")
+
+	// And the access token and readiness fault rates
+	viper.BindEnv("chaos.auth.token_expiry_rate", "NAH_CHAOS_AUTH_TOKEN_EXPIRY_RATE", "NAH_TOKEN_EXPIRY_RATE")
+	viper.BindEnv("chaos.health.readiness_flap_rate", "NAH_CHAOS_HEALTH_READINESS_FLAP_RATE", "NAH_READINESS_FLAP_RATE")
+
 	// Set defaults
 	viper.SetDefault("addr", ":8080")
 	viper.SetDefault("chaos.error_types", []int{503, 500, 429})
@@ -125,6 +420,51 @@ func loadConfig(cmd *cobra.Command) (*Config, error) {
 		cfg.Chaos.ErrorRate.ProjectsGet = cfg.Chaos.ErrorRate.Projects
 	}
 
+	// If tfstate_lock error rate not explicitly set, inherit from tfstate
+	if cfg.Chaos.ErrorRate.TFStateLock == 0 && cfg.Chaos.ErrorRate.TFState > 0 {
+		cfg.Chaos.ErrorRate.TFStateLock = cfg.Chaos.ErrorRate.TFState
+	}
+
+	if cfg.Chaos.Retry.AfterSeconds < 0 {
+		return nil, fmt.Errorf("invalid chaos retry after_seconds: must not be negative")
+	}
+	if f := cfg.Chaos.Retry.AfterFormat; f != "" && f != chaos.RetryAfterFormatSeconds && f != chaos.RetryAfterFormatDate {
+		return nil, fmt.Errorf("invalid chaos retry after_format %q: must be \"seconds\" or \"date\"", f)
+	}
+
+	for i, rule := range cfg.Chaos.Rules {
+		if err := rule.ToRule().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid chaos rule %d: %w", i+1, err)
+		}
+	}
+
+	if err := cfg.ToRateLimitConfig().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	if cfg.Storage != StorageSQLite && cfg.Storage != StorageMemory {
+		return nil, fmt.Errorf("invalid storage %q: must be %q or %q", cfg.Storage, StorageSQLite, StorageMemory)
+	}
+
+	if cfg.ShutdownDelay < 0 {
+		return nil, fmt.Errorf("invalid shutdown_delay: must not be negative")
+	}
+
+	if cfg.TFState.LockTTL < 0 {
+		return nil, fmt.Errorf("invalid tfstate lock_ttl: must not be negative")
+	}
+
+	if cfg.OAuth.TokenTTL <= 0 {
+		return nil, fmt.Errorf("invalid oauth token_ttl: must be positive")
+	}
+
+	if _, err := cfg.Log.level(); err != nil {
+		return nil, err
+	}
+	if f := cfg.Log.Format; f != "text" && f != "json" {
+		return nil, fmt.Errorf("invalid log format %q: must be \"text\" or \"json\"", f)
+	}
+
 	return &cfg, nil
 }
 
@@ -137,12 +477,36 @@ func (c *Config) ToChaosConfig() *chaos.Config {
 		ProjectsLatencyRange: parseLatencyRange(c.Chaos.Latency.ProjectsMS),
 		InstancesLatencyRange: parseLatencyRange(c.Chaos.Latency.InstancesMS),
 		MetadataLatencyRange: parseLatencyRange(c.Chaos.Latency.MetadataMS),
+		BucketsLatencyRange:  parseLatencyRange(c.Chaos.Latency.BucketsMS),
+		ObjectsLatencyRange:  parseLatencyRange(c.Chaos.Latency.ObjectsMS),
+		TFStateLatencyRange:  parseLatencyRange(c.Chaos.Latency.TFStateMS),
 		ProjectsErrorRate:    c.Chaos.ErrorRate.Projects,
 		ProjectsGetErrorRate: c.Chaos.ErrorRate.ProjectsGet,
 		InstancesErrorRate:   c.Chaos.ErrorRate.Instances,
 		MetadataErrorRate:    c.Chaos.ErrorRate.Metadata,
+		BucketsErrorRate:     c.Chaos.ErrorRate.Buckets,
+		ObjectsErrorRate:     c.Chaos.ErrorRate.Objects,
+		TFStateErrorRate:     c.Chaos.ErrorRate.TFState,
+		TFStateLockErrorRate: c.Chaos.ErrorRate.TFStateLock,
 		ErrorTypes:           c.Chaos.ErrorTypes,
 		ErrorWeights:         c.Chaos.ErrorWeights,
+		PageTruncateRate:     c.Chaos.Pagination.TruncateRate,
+		PageExpiredTokenRate: c.Chaos.Pagination.ExpiredTokenRate,
+		PageDuplicateRate:    c.Chaos.Pagination.DuplicateRate,
+		PageSkipRate:         c.Chaos.Pagination.SkipRate,
+		NetResetRate:         c.Chaos.Network.ResetRate,
+		NetSlowBodyRate:      c.Chaos.Network.SlowBodyRate,
+		NetTruncateRate:      c.Chaos.Network.TruncateRate,
+		NetTimeoutRate:       c.Chaos.Network.TimeoutRate,
+		RetryAfterSeconds:    c.Chaos.Retry.AfterSeconds,
+		RetryAfterFormat:     c.Chaos.Retry.AfterFormat,
+		RateLimitLimit:       c.Chaos.Retry.RateLimitLimit,
+		TokenExpiryRate:      c.Chaos.Auth.TokenExpiryRate,
+		ReadinessFlapRate:    c.Chaos.Health.ReadinessFlapRate,
+	}
+
+	for _, rule := range c.Chaos.Rules {
+		cfg.Rules = append(cfg.Rules, rule.ToRule())
 	}
 
 	// Use defaults if not set
@@ -156,6 +520,94 @@ func (c *Config) ToChaosConfig() *chaos.Config {
 	return cfg
 }
 
+// ToRateLimitConfig converts our config to the rate limiter's Config type. The read
+// and write rates become limits named "reads" and "writes", ahead of any configured limits.
+func (c *Config) ToRateLimitConfig() *ratelimit.Config {
+	cfg := &ratelimit.Config{Enabled: c.RateLimit.Enabled}
+
+	if c.RateLimit.WritesPerSecond > 0 {
+		cfg.Limits = append(cfg.Limits, ratelimit.Limit{
+			Name:    "writes",
+			Methods: ratelimit.WriteMethods,
+			Rate:    c.RateLimit.WritesPerSecond,
+			Burst:   c.RateLimit.Burst,
+		})
+	}
+	if c.RateLimit.ReadsPerSecond > 0 {
+		cfg.Limits = append(cfg.Limits, ratelimit.Limit{
+			Name:    "reads",
+			Methods: ratelimit.ReadMethods,
+			Rate:    c.RateLimit.ReadsPerSecond,
+			Burst:   c.RateLimit.Burst,
+		})
+	}
+	for _, limit := range c.RateLimit.Limits {
+		cfg.Limits = append(cfg.Limits, limit.ToLimit())
+	}
+
+	return cfg
+}
+
+// ToLifecycleConfig converts our config to the service's LifecycleConfig type
+func (c *Config) ToLifecycleConfig() service.LifecycleConfig {
+	return service.LifecycleConfig{
+		Enabled:              c.Lifecycle.Enabled,
+		PendingDuration:      c.Lifecycle.Pending,
+		ProvisioningDuration: c.Lifecycle.Provisioning,
+		StoppingDuration:     c.Lifecycle.Stopping,
+		RebootingDuration:    c.Lifecycle.Rebooting,
+		TerminatingDuration:  c.Lifecycle.Terminating,
+		TickInterval:         c.Lifecycle.TickInterval,
+	}
+}
+
+// ToTFStateConfig converts our config to the service's TFStateConfig type
+func (c *Config) ToTFStateConfig() service.TFStateConfig {
+	return service.TFStateConfig{Strict: c.TFState.Strict, LockTTL: c.TFState.LockTTL}
+}
+
+// ToOAuthConfig converts our config to the service's OAuthConfig type
+func (c *Config) ToOAuthConfig() service.OAuthConfig {
+	cfg := service.OAuthConfig{TokenTTL: c.OAuth.TokenTTL}
+	if c.OAuth.SigningKey != "" {
+		cfg.SigningKey = []byte(c.OAuth.SigningKey)
+	}
+	return cfg
+}
+
+// NewLogger creates the server's logger. The returned function closes the log file, if any.
+func (c LogConfig) NewLogger() (*slog.Logger, func() error, error) {
+	level, err := c.level()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	closeFn := func() error { return nil }
+	if c.File != "" {
+		file, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		out, closeFn = file, file.Close
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	if c.Format == "json" {
+		return slog.New(slog.NewJSONHandler(out, opts)), closeFn, nil
+	}
+	return slog.New(slog.NewTextHandler(out, opts)), closeFn, nil
+}
+
+// level parses the configured log level
+func (c LogConfig) level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return level, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", c.Level)
+	}
+	return level, nil
+}
+
 // parseLatencyRange parses a "min-max" string into a LatencyRange
 func parseLatencyRange(value string) *chaos.LatencyRange {
 	if value == "" {
@@ -186,9 +638,24 @@ Environment Variables:
 
   NAH_ADDR=:9090                    Set server address
   NAH_TOKEN=secret                  Set auth token
+  NAH_STORAGE=memory                Keep all state in memory
   NAH_SQLITE_DSN=./data.db          Set database path
   NAH_CHAOS_ENABLED=true            Enable chaos engineering
   NAH_CHAOS_LATENCY_GLOBAL_MS=10-100  Set global latency range
+  NAH_CHAOS_PAGINATION_TRUNCATE_RATE=0.2  Return short list pages 20% of the time
+  NAH_NET_RESET_RATE=0.05           Reset 5% of connections mid-request
+  NAH_RETRY_AFTER_SECONDS=2         Send Retry-After: 2 with injected 429/503s
+  NAH_RATE_LIMIT_ENABLED=true       Enforce rate limits
+  NAH_RATE_LIMIT_WRITES_PER_SECOND=5  Allow 5 writes/s per token and resource type
+  NAH_LIFECYCLE_ENABLED=true        Enable instance lifecycle states
+  NAH_TFSTATE_STRICT=true           Reject stale Terraform state writes
+  NAH_TFSTATE_LOCK_TTL=30m          Let a new LOCK take over state locks older than 30 minutes
+  NAH_LOG_FORMAT=json               Write JSON logs
+  NAH_LOG_LEVEL=debug               Set the log level
+  NAH_OAUTH_TOKEN_TTL=5m            Issue access tokens valid for 5 minutes
+  NAH_TOKEN_EXPIRY_RATE=0.1         Reject 10% of valid access tokens as expired
+  NAH_READINESS_FLAP_RATE=0.2       Fail 20% of readiness probes
+  NAH_SHUTDOWN_DELAY=5s             Report draining on /readyz for 5s before shutting down
 
 Config File:
   Use --config to specify a YAML, JSON, or TOML config file.
@@ -196,7 +663,9 @@ Config File:
 
     addr: ":8080"
     token: "secret"
+    storage: sqlite
     sqlite_dsn: "./nahcloud.db"
+    shutdown_delay: 5s
     chaos:
       enabled: true
       seed: 12345
@@ -204,8 +673,88 @@ Config File:
         global_ms: "10-100"
       error_rate:
         projects: 0.1
+        tfstate_lock: 0.2
       error_types: [503, 500, 429]
       error_weights: [3, 2, 1]
+      pagination:
+        truncate_rate: 0.1
+        expired_token_rate: 0.05
+        duplicate_rate: 0.05
+        skip_rate: 0.05
+      network:
+        reset_rate: 0.02
+        slow_body_rate: 0.05
+        truncate_rate: 0.02
+        timeout_rate: 0.01
+      retry:
+        after_seconds: 2
+        after_format: date
+        rate_limit_limit: 100
+      auth:
+        token_expiry_rate: 0.05
+      health:
+        readiness_flap_rate: 0.1
+      rules:
+        - name: second-create-fails
+          match:
+            methods: [POST]
+            resource: instances
+            nth_call: 2
+          action:
+            type: error
+            status: 502
+            body: '{"error":"BAD_GATEWAY","message":"upstream failed"}'
+        - name: slow-locks
+          match:
+            path: /v1/tfstate/**
+            methods: [LOCK]
+          action:
+            type: latency
+            latency: {min: 500, max: 2000}
+        - name: flaky-list
+          probability: 0.1
+          match:
+            methods: [GET]
+            path: /v1/projects
+          action:
+            type: partial
+    rate_limit:
+      enabled: true
+      writes_per_second: 5
+      burst: 10
+      limits:
+        - name: tfstate-writes
+          resource: tfstate
+          methods: [POST, LOCK, UNLOCK]
+          rate: 1
+          burst: 2
+    lifecycle:
+      enabled: true
+      pending: 2s
+      provisioning: 5s
+    tfstate:
+      strict: true
+      lock_ttl: 30m
+    log:
+      level: info
+      format: json
+      file: ./nahcloud.log
+    oauth:
+      token_ttl: 15m
+      signing_key: "change-me"
+
+Chaos Rules:
+  Rules are evaluated in order for every /v1 request, before the per-resource
+  latency ranges and error rates. A rule matches on methods, a path glob
+  ("*" matches one segment, a trailing "/**" any suffix), a resource type,
+  header values ("*" for any value) and nth_call. Its action is one of
+  latency, error (status and optional body), drop, reset, partial or
+  truncate (partial_bytes of the body, default half), slow (chunk_bytes
+  every chunk_delay_ms) or timeout (timeout_ms). Probability 0 means always.
+  Error actions may override retry.after_seconds and retry.after_format
+  with retry_after_seconds and retry_after_format. Rules use the same
+  fields as the /v1/_admin/chaos API, so a rule read from it can be
+  pasted into the config file unchanged.
 
 Priority (highest to lowest):
   1. Command-line flags
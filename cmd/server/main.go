@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,7 +15,8 @@ import (
 	"github.com/hypertf/nahcloud/api"
 	"github.com/hypertf/nahcloud/service"
 	"github.com/hypertf/nahcloud/service/chaos"
-	"github.com/hypertf/nahcloud/storage/sqlite"
+	"github.com/hypertf/nahcloud/service/metrics"
+	"github.com/hypertf/nahcloud/service/ratelimit"
 )
 
 func main() {
@@ -39,6 +40,7 @@ handling and resilience.` + printConfigHelp(),
 	}
 
 	setupConfig(rootCmd)
+	rootCmd.AddCommand(newMigrateCommand())
 
 	return rootCmd.Execute()
 }
@@ -50,29 +52,73 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Initialize database
-	db, err := sqlite.NewDB(config.SQLiteDSN)
+	// Initialize logging; the standard logger writes through it too
+	logger, closeLog, err := config.Log.NewLogger()
 	if err != nil {
-		return fmt.Errorf("failed to initialize database: %w", err)
+		return err
 	}
-	defer db.Close()
+	defer closeLog()
+	slog.SetDefault(logger)
 
-	// Initialize repositories
-	projectRepo := sqlite.NewProjectRepository(db)
-	instanceRepo := sqlite.NewInstanceRepository(db)
-	metadataRepo := sqlite.NewMetadataRepository(db)
-	bucketRepo := sqlite.NewBucketRepository(db)
-	objectRepo := sqlite.NewObjectRepository(db)
+	// Metrics are served at /metrics; database metrics are observed from the start
+	registry := metrics.NewRegistry()
+
+	// Initialize storage
+	store, err := openBackend(config, registry)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	registry.NewGaugeFunc("nahcloud_resources", "Stored resources, by type.", []string{"type"}, func() []metrics.Sample {
+		counts, err := store.CountResources()
+		if err != nil {
+			logger.Error("Failed to count resources", "error", err)
+			return nil
+		}
+		var samples []metrics.Sample
+		for resource, n := range counts {
+			samples = append(samples, metrics.Sample{LabelValues: []string{resource}, Value: float64(n)})
+		}
+		return samples
+	})
 
 	// Initialize service layer
-	svc := service.NewService(projectRepo, instanceRepo, metadataRepo, bucketRepo, objectRepo)
+	svc := service.NewService(store.Projects, store.Instances, store.Metadata, store.Buckets, store.Objects, store.Operations, store.APIKeys, store.TFStates)
+	svc.SetLifecycleConfig(config.ToLifecycleConfig())
+	svc.SetOAuthConfig(config.ToOAuthConfig())
+	svc.SetTFStateConfig(config.ToTFStateConfig())
+
+	// Operations a previous process was running will never finish
+	if n, err := svc.FailInterruptedOperations(); err != nil {
+		return fmt.Errorf("failed to fail interrupted operations: %w", err)
+	} else if n > 0 {
+		logger.Warn("Marked interrupted operations as failed", "count", n)
+	}
+
+	// Background workers stop when the service shuts down
+	svc.StartLifecycleWorker()
+
+	// Background operations and workers must finish before storage is closed
+	stopBackground := func(ctx context.Context) {
+		if err := svc.Shutdown(ctx); err != nil {
+			logger.Error("Background operations did not finish", "error", err)
+		}
+	}
 
 	// Initialize chaos service with config
 	chaosConfig := config.ToChaosConfig()
 	chaosService := chaos.NewChaosServiceWithConfig(chaosConfig)
 
+	// Initialize rate limiter with config
+	rateLimitConfig := config.ToRateLimitConfig()
+	rateLimiter := ratelimit.NewLimiter(rateLimitConfig)
+
 	// Initialize API handlers
-	handler := api.NewHandler(svc, chaosService, config.Token)
+	handler := api.NewHandler(svc, chaosService, rateLimiter, config.Token)
+	handler.SetLogger(logger)
+	handler.SetMetrics(registry)
+	addStorageChecks(handler, store)
 
 	// Setup router
 	router := api.SetupRouter(handler, Version)
@@ -89,9 +135,15 @@ func runServer(cmd *cobra.Command, args []string) error {
 	// Start server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
-		log.Printf("NahCloud server starting on %s", config.Addr)
+		logger.Info("NahCloud server starting", "addr", config.Addr, "version", Version, "storage", config.Storage)
 		if chaosConfig.Enabled {
-			log.Printf("Chaos engineering enabled (seed: %d)", chaosConfig.Seed)
+			logger.Info("Chaos engineering enabled", "seed", chaosConfig.Seed)
+		}
+		if rateLimitConfig.Enabled {
+			logger.Info("Rate limiting enabled", "limits", len(rateLimitConfig.Limits))
+		}
+		if config.Lifecycle.Enabled {
+			logger.Info("Instance lifecycle enabled")
 		}
 		serverErrors <- server.ListenAndServe()
 	}()
@@ -102,22 +154,34 @@ func runServer(cmd *cobra.Command, args []string) error {
 
 	select {
 	case err := <-serverErrors:
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		stopBackground(ctx)
 		return fmt.Errorf("server error: %w", err)
 	case sig := <-shutdown:
-		log.Printf("Received signal %v, starting graceful shutdown", sig)
+		logger.Info("Received signal, starting graceful shutdown", "signal", sig.String())
+
+		// Fail readiness first, so orchestrators stop routing new requests here
+		handler.Drain()
+		if config.ShutdownDelay > 0 {
+			logger.Info("Draining before shutdown", "delay", config.ShutdownDelay.String())
+			time.Sleep(config.ShutdownDelay)
+		}
 
 		// Give outstanding requests 30 seconds to complete
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		if err := server.Shutdown(ctx); err != nil {
-			log.Printf("Graceful shutdown failed: %v", err)
+			logger.Error("Graceful shutdown failed", "error", err)
 			if err := server.Close(); err != nil {
-				log.Printf("Force close failed: %v", err)
+				logger.Error("Force close failed", "error", err)
 			}
 		}
+
+		stopBackground(ctx)
 	}
 
-	log.Println("Server stopped")
+	logger.Info("Server stopped")
 	return nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hypertf/nahcloud/storage/sqlite"
+)
+
+// newMigrateCommand creates the migrate command, which manages the database schema.
+// The server applies pending migrations itself when it starts.
+func newMigrateCommand() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema",
+		Long: `Manage the versioned schema migrations of the SQLite database.
+
+The server applies pending migrations when it starts, so these commands are only
+needed to inspect a database or to roll a migration back.`,
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "List migrations and whether each has been applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDB(cmd, func(db *sqlite.DB) error {
+				statuses, err := db.MigrationStatus(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+				for _, s := range statuses {
+					status, appliedAt := "pending", "-"
+					if s.Applied {
+						status, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+					}
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, _ := cmd.Flags().GetInt("steps")
+			return withDB(cmd, func(db *sqlite.DB) error {
+				applied, err := db.MigrateUp(cmd.Context(), steps)
+				printVersions(cmd, "Applied", applied)
+				return err
+			})
+		},
+	}
+	upCmd.Flags().Int("steps", 0, "Number of migrations to apply (0 = all pending)")
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recently applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, _ := cmd.Flags().GetInt("steps")
+			if steps < 1 {
+				return fmt.Errorf("invalid steps %d: must be at least 1", steps)
+			}
+			return withDB(cmd, func(db *sqlite.DB) error {
+				reverted, err := db.MigrateDown(cmd.Context(), steps)
+				printVersions(cmd, "Reverted", reverted)
+				return err
+			})
+		},
+	}
+	downCmd.Flags().Int("steps", 1, "Number of migrations to revert")
+
+	migrateCmd.AddCommand(statusCmd, upCmd, downCmd)
+	return migrateCmd
+}
+
+// withDB opens the configured database without migrating it and runs fn
+func withDB(cmd *cobra.Command, fn func(db *sqlite.DB) error) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := sqlite.OpenDB(config.SQLiteDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return fn(db)
+}
+
+// printVersions reports the migrations a command changed
+func printVersions(cmd *cobra.Command, verb string, versions []int) {
+	if len(versions) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No migrations to change")
+		return
+	}
+	for _, v := range versions {
+		fmt.Fprintln(cmd.OutOrStdout(), verb+" migration "+strconv.Itoa(v))
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"github.com/hypertf/nahcloud/api"
+	"github.com/hypertf/nahcloud/service/metrics"
+	"github.com/hypertf/nahcloud/storage"
+)
+
+// Storage backends
+const (
+	StorageSQLite = storage.SQLite
+	StorageMemory = storage.Memory
+)
+
+// openBackend opens the configured storage backend. Database metrics are
+// recorded in registry from the start.
+func openBackend(config *Config, registry *metrics.Registry) (*storage.Backend, error) {
+	backend, err := storage.Open(config.Storage, config.SQLiteDSN)
+	if err != nil {
+		return nil, err
+	}
+	if backend.DB != nil {
+		queryDuration := registry.NewHistogramVec("nahcloud_db_query_duration_seconds",
+			"Database query latency, by statement type.", metrics.DefaultBuckets, "statement")
+		backend.DB.SetQueryObserver(func(statement string, duration time.Duration) {
+			queryDuration.Observe(duration.Seconds(), statement)
+		})
+	}
+	return backend, nil
+}
+
+// addStorageChecks registers the backend's readiness checks
+func addStorageChecks(h *api.Handler, backend *storage.Backend) {
+	if backend.DB != nil {
+		h.AddReadinessCheck("database", backend.DB.PingContext)
+		h.AddReadinessCheck("migrations", backend.DB.CheckSchema)
+	}
+}
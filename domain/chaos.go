@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ChaosConfig holds chaos engineering configuration, as served by the chaos admin API
+type ChaosConfig struct {
+	Enabled bool  `json:"enabled"`
+	Seed    int64 `json:"seed"`
+
+	// Global latency range in milliseconds
+	GlobalLatencyRange *ChaosLatencyRange `json:"global_latency,omitempty"`
+
+	// Per-resource latency ranges
+	ProjectsLatencyRange  *ChaosLatencyRange `json:"projects_latency,omitempty"`
+	InstancesLatencyRange *ChaosLatencyRange `json:"instances_latency,omitempty"`
+	MetadataLatencyRange  *ChaosLatencyRange `json:"metadata_latency,omitempty"`
+	BucketsLatencyRange   *ChaosLatencyRange `json:"buckets_latency,omitempty"`
+	ObjectsLatencyRange   *ChaosLatencyRange `json:"objects_latency,omitempty"`
+	TFStateLatencyRange   *ChaosLatencyRange `json:"tfstate_latency,omitempty"`
+
+	// Per-resource error rates
+	ProjectsErrorRate    float64 `json:"projects_error_rate"`
+	ProjectsGetErrorRate float64 `json:"projects_get_error_rate"`
+	InstancesErrorRate   float64 `json:"instances_error_rate"`
+	MetadataErrorRate    float64 `json:"metadata_error_rate"`
+	BucketsErrorRate     float64 `json:"buckets_error_rate"`
+	ObjectsErrorRate     float64 `json:"objects_error_rate"`
+	TFStateErrorRate     float64 `json:"tfstate_error_rate"`
+	TFStateLockErrorRate float64 `json:"tfstate_lock_error_rate"` // LOCK and UNLOCK requests
+
+	// Error configuration
+	ErrorTypes   []int `json:"error_types"`
+	ErrorWeights []int `json:"error_weights"`
+
+	// Pagination fault rates, applied to paginated list requests
+	PageTruncateRate     float64 `json:"page_truncate_rate"`      // return fewer items than requested
+	PageExpiredTokenRate float64 `json:"page_expired_token_rate"` // reject a page token as expired
+	PageDuplicateRate    float64 `json:"page_duplicate_rate"`     // repeat an item across a page boundary
+	PageSkipRate         float64 `json:"page_skip_rate"`          // drop an item across a page boundary
+
+	// Network fault rates, applied to every API request
+	NetResetRate    float64 `json:"net_reset_rate"`     // reset the connection without responding
+	NetSlowBodyRate float64 `json:"net_slow_body_rate"` // trickle the response body
+	NetTruncateRate float64 `json:"net_truncate_rate"`  // send a truncated body with a matching Content-Length
+	NetTimeoutRate  float64 `json:"net_timeout_rate"`   // hang without responding
+
+	// Chance a valid OAuth access token is rejected as expired
+	TokenExpiryRate float64 `json:"token_expiry_rate"`
+
+	// Chance a readiness probe reports the server as not ready
+	ReadinessFlapRate float64 `json:"readiness_flap_rate"`
+
+	// Retry hints sent with injected 429 and 503 responses
+	RetryAfterSeconds int    `json:"retry_after_seconds"`          // Retry-After delay; 0 omits the header
+	RetryAfterFormat  string `json:"retry_after_format,omitempty"` // RetryAfterFormatSeconds (default) or RetryAfterFormatDate
+	RateLimitLimit    int    `json:"rate_limit_limit"`             // X-RateLimit-Limit on injected 429s; 0 omits the X-RateLimit-* headers
+
+	// Rules are evaluated in order before the per-resource latency ranges and error rates
+	Rules []ChaosRule `json:"rules"`
+}
+
+// ChaosLatencyRange defines min-max latency in milliseconds
+type ChaosLatencyRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Retry-After formats
+const (
+	RetryAfterFormatSeconds = "seconds" // delay in seconds, e.g. "Retry-After: 30"
+	RetryAfterFormatDate    = "date"    // HTTP-date, e.g. "Retry-After: Wed, 21 Oct 2026 07:28:00 GMT"
+)
+
+// Chaos rule action types
+const (
+	ChaosActionLatency = "latency" // delay the request, then serve it
+	ChaosActionError   = "error"   // respond with a status code and body instead of serving the request
+	ChaosActionDrop    = "drop"    // close the connection without responding
+	ChaosActionPartial = "partial" // serve the request but cut the connection partway through the body
+
+	ChaosActionReset    = "reset"    // reset the TCP connection without responding
+	ChaosActionSlowBody = "slow"     // serve the request but trickle the body out in small chunks
+	ChaosActionTruncate = "truncate" // serve a cut-down body with a matching Content-Length
+	ChaosActionTimeout  = "timeout"  // hang without responding, then close the connection
+)
+
+// ChaosRule injects a fault into API requests that match all of its conditions
+type ChaosRule struct {
+	Name        string      `json:"name,omitempty"`
+	Match       ChaosMatch  `json:"match"`
+	Action      ChaosAction `json:"action"`
+	Probability float64     `json:"probability,omitempty"` // chance a matching request is affected; 0 means always
+}
+
+// ChaosMatch selects the requests a rule applies to. Empty fields match every request.
+type ChaosMatch struct {
+	Methods  []string          `json:"methods,omitempty"`
+	Path     string            `json:"path,omitempty"`     // glob where "*" matches one path segment and a trailing "/**" matches any suffix
+	Resource string            `json:"resource,omitempty"` // resource type, e.g. "instances" or "tfstate"
+	Headers  map[string]string `json:"headers,omitempty"`  // required header values; "*" only requires the header to be present
+	NthCall  int               `json:"nth_call,omitempty"` // only the Nth request matching the other conditions, counting from 1
+}
+
+// ChaosAction describes the fault a rule injects
+type ChaosAction struct {
+	Type         string             `json:"type"`
+	Latency      *ChaosLatencyRange `json:"latency,omitempty"`        // latency: delay range in milliseconds
+	Status       int                `json:"status,omitempty"`         // error: HTTP status; 0 picks one of ErrorTypes by weight
+	Body         string             `json:"body,omitempty"`           // error: response body; defaults to a JSON error for the status
+	PartialBytes int                `json:"partial_bytes,omitempty"`  // partial, truncate: body bytes sent; 0 sends half
+	ChunkBytes   int                `json:"chunk_bytes,omitempty"`    // slow: bytes per chunk; 0 uses the server default
+	ChunkDelayMS int                `json:"chunk_delay_ms,omitempty"` // slow: delay between chunks; 0 uses the server default
+	TimeoutMS    int                `json:"timeout_ms,omitempty"`     // timeout: how long to hang; 0 uses the server default
+
+	// error: retry hints for 429 and 503 responses; zero values use the service config
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+	RetryAfterFormat  string `json:"retry_after_format,omitempty"`
+}
+
+// ChaosJournalEntry records a single injected fault
+type ChaosJournalEntry struct {
+	Sequence  int64     `json:"sequence"`
+	Time      time.Time `json:"time"`
+	Seed      int64     `json:"seed"`
+	Key       string    `json:"key"` // chaos key the request's fault decisions were derived from
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Rule      string    `json:"rule,omitempty"`
+	Fault     string    `json:"fault"` // rule action type, or page_* for pagination faults
+	Status    int       `json:"status,omitempty"`
+	LatencyMS int       `json:"latency_ms,omitempty"`
+}
+
+// ChaosJournalFilter selects entries from the fault journal
+type ChaosJournalFilter struct {
+	Key   string
+	Rule  string
+	Since int64 // only entries with a greater sequence number
+	Limit int   // only the most recent matching entries; 0 returns all
+}
+
+// Validate checks that a configuration can be applied
+func (c *ChaosConfig) Validate() error {
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"projects_error_rate", c.ProjectsErrorRate},
+		{"projects_get_error_rate", c.ProjectsGetErrorRate},
+		{"instances_error_rate", c.InstancesErrorRate},
+		{"metadata_error_rate", c.MetadataErrorRate},
+		{"buckets_error_rate", c.BucketsErrorRate},
+		{"objects_error_rate", c.ObjectsErrorRate},
+		{"tfstate_error_rate", c.TFStateErrorRate},
+		{"tfstate_lock_error_rate", c.TFStateLockErrorRate},
+		{"page_truncate_rate", c.PageTruncateRate},
+		{"page_expired_token_rate", c.PageExpiredTokenRate},
+		{"page_duplicate_rate", c.PageDuplicateRate},
+		{"page_skip_rate", c.PageSkipRate},
+		{"net_reset_rate", c.NetResetRate},
+		{"net_slow_body_rate", c.NetSlowBodyRate},
+		{"net_truncate_rate", c.NetTruncateRate},
+		{"net_timeout_rate", c.NetTimeoutRate},
+		{"token_expiry_rate", c.TokenExpiryRate},
+		{"readiness_flap_rate", c.ReadinessFlapRate},
+	}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			return InvalidInputError(fmt.Sprintf("%s must be between 0 and 1", r.name), nil)
+		}
+	}
+
+	latencies := []struct {
+		name    string
+		latency *ChaosLatencyRange
+	}{
+		{"global_latency", c.GlobalLatencyRange},
+		{"projects_latency", c.ProjectsLatencyRange},
+		{"instances_latency", c.InstancesLatencyRange},
+		{"metadata_latency", c.MetadataLatencyRange},
+		{"buckets_latency", c.BucketsLatencyRange},
+		{"objects_latency", c.ObjectsLatencyRange},
+		{"tfstate_latency", c.TFStateLatencyRange},
+	}
+	for _, l := range latencies {
+		if l.latency != nil && (l.latency.Min < 0 || l.latency.Max < l.latency.Min) {
+			return InvalidInputError(fmt.Sprintf("%s must have 0 <= min <= max", l.name), nil)
+		}
+	}
+
+	if err := validateRetryAfter(c.RetryAfterSeconds, c.RetryAfterFormat); err != nil {
+		return InvalidInputError(err.Error(), nil)
+	}
+	if c.RateLimitLimit < 0 {
+		return InvalidInputError("rate_limit_limit must not be negative", nil)
+	}
+
+	names := make(map[string]bool)
+	for _, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.Name == "" {
+			continue
+		}
+		if names[rule.Name] {
+			return AlreadyExistsError("chaos rule", "name", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// Validate checks that a rule can be evaluated
+func (r ChaosRule) Validate() error {
+	invalid := func(message string) error {
+		return InvalidInputError(message, map[string]interface{}{"rule": r.Name})
+	}
+
+	if r.Probability < 0 || r.Probability > 1 {
+		return invalid("probability must be between 0 and 1")
+	}
+	if r.Match.NthCall < 0 {
+		return invalid("nth_call must not be negative")
+	}
+	if r.Match.Path != "" {
+		if _, err := path.Match(strings.TrimSuffix(r.Match.Path, "/**"), ""); err != nil {
+			return invalid(fmt.Sprintf("invalid path pattern %q", r.Match.Path))
+		}
+	}
+
+	switch r.Action.Type {
+	case ChaosActionLatency:
+		if r.Action.Latency == nil || r.Action.Latency.Min < 0 || r.Action.Latency.Max < r.Action.Latency.Min {
+			return invalid("latency action requires a valid latency range")
+		}
+	case ChaosActionError:
+		if r.Action.Status != 0 && (r.Action.Status < 400 || r.Action.Status > 599) {
+			return invalid("error action status must be between 400 and 599")
+		}
+		if err := validateRetryAfter(r.Action.RetryAfterSeconds, r.Action.RetryAfterFormat); err != nil {
+			return invalid(err.Error())
+		}
+	case ChaosActionDrop, ChaosActionReset:
+	case ChaosActionPartial, ChaosActionTruncate:
+		if r.Action.PartialBytes < 0 {
+			return invalid("partial_bytes must not be negative")
+		}
+	case ChaosActionSlowBody:
+		if r.Action.ChunkBytes < 0 || r.Action.ChunkDelayMS < 0 {
+			return invalid("chunk_bytes and chunk_delay_ms must not be negative")
+		}
+	case ChaosActionTimeout:
+		if r.Action.TimeoutMS < 0 {
+			return invalid("timeout_ms must not be negative")
+		}
+	default:
+		return invalid(fmt.Sprintf("unknown action type %q", r.Action.Type))
+	}
+	return nil
+}
+
+// validateRetryAfter checks a Retry-After delay and format
+func validateRetryAfter(seconds int, format string) error {
+	if seconds < 0 {
+		return errors.New("retry_after_seconds must not be negative")
+	}
+	switch format {
+	case "", RetryAfterFormatSeconds, RetryAfterFormatDate:
+		return nil
+	}
+	return errors.New(`retry_after_format must be "seconds" or "date"`)
+}
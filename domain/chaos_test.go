@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRetryAfter(t *testing.T) {
+	assert.NoError(t, validateRetryAfter(0, ""))
+	assert.NoError(t, validateRetryAfter(10, RetryAfterFormatSeconds))
+	assert.NoError(t, validateRetryAfter(10, RetryAfterFormatDate))
+	assert.Error(t, validateRetryAfter(-1, ""))
+	assert.Error(t, validateRetryAfter(10, "minutes"))
+}
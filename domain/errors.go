@@ -12,8 +12,10 @@ const (
 	ErrorCodeForeignKeyViolation = "FOREIGN_KEY_VIOLATION"
 	ErrorCodeInternalError      = "INTERNAL_ERROR"
 	ErrorCodeUnauthorized       = "UNAUTHORIZED"
+	ErrorCodeForbidden          = "FORBIDDEN"
 	ErrorCodeTooManyRequests    = "TOO_MANY_REQUESTS"
 	ErrorCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	ErrorCodeConflict           = "CONFLICT"
 )
 
 // NahError represents a domain error with structured information
@@ -27,6 +29,23 @@ func (e *NahError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// RequestIDHeader names the header carrying the ID the server assigned to a request
+const RequestIDHeader = "X-Request-Id"
+
+// WithRequestID returns a copy of the error whose details include a request ID
+// The error itself is left unchanged, and an empty ID returns it as is.
+func (e *NahError) WithRequestID(id string) *NahError {
+	if id == "" {
+		return e
+	}
+	details := make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details["request_id"] = id
+	return &NahError{Code: e.Code, Message: e.Message, Details: details}
+}
+
 // NewError creates a new domain error
 func NewError(code, message string, details ...map[string]interface{}) *NahError {
 	var d map[string]interface{}
@@ -81,6 +100,11 @@ func UnauthorizedError(message string) *NahError {
 	return NewError(ErrorCodeUnauthorized, message)
 }
 
+// ForbiddenError creates an error for authenticated requests that lack permission
+func ForbiddenError(message string, details map[string]interface{}) *NahError {
+	return NewError(ErrorCodeForbidden, message, details)
+}
+
 // TooManyRequestsError creates a too many requests error
 func TooManyRequestsError(message string) *NahError {
 	return NewError(ErrorCodeTooManyRequests, message)
@@ -91,6 +115,26 @@ func ServiceUnavailableError(message string) *NahError {
 	return NewError(ErrorCodeServiceUnavailable, message)
 }
 
+// ConflictError creates an error for requests that conflict with the resource's current state
+func ConflictError(message string, details map[string]interface{}) *NahError {
+	return NewError(ErrorCodeConflict, message, details)
+}
+
+// InvalidPageTokenError creates an error for a malformed or mismatched page token
+func InvalidPageTokenError(token string) *NahError {
+	return InvalidInputError("invalid page token", map[string]interface{}{
+		"page_token": token,
+	})
+}
+
+// ExpiredPageTokenError creates an error for a page token that is no longer valid
+func ExpiredPageTokenError(token string) *NahError {
+	return InvalidInputError("page token expired", map[string]interface{}{
+		"page_token": token,
+		"reason":     "expired",
+	})
+}
+
 // IsNotFound checks if error is a not found error
 func IsNotFound(err error) bool {
 	if nahErr, ok := err.(*NahError); ok {
@@ -121,4 +165,20 @@ func IsInvalidInput(err error) bool {
 		return nahErr.Code == ErrorCodeInvalidInput
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// IsForbidden checks if error is a forbidden error
+func IsForbidden(err error) bool {
+	if nahErr, ok := err.(*NahError); ok {
+		return nahErr.Code == ErrorCodeForbidden
+	}
+	return false
+}
+
+// IsConflict checks if error is a conflict error
+func IsConflict(err error) bool {
+	if nahErr, ok := err.(*NahError); ok {
+		return nahErr.Code == ErrorCodeConflict
+	}
+	return false
+}
@@ -137,6 +137,17 @@ func TestUnauthorizedError(t *testing.T) {
 	assert.Nil(t, err.Details)
 }
 
+func TestForbiddenError(t *testing.T) {
+	details := map[string]interface{}{"scope": "instances:write"}
+	err := ForbiddenError("missing scope", details)
+
+	assert.Equal(t, ErrorCodeForbidden, err.Code)
+	assert.Equal(t, "missing scope", err.Message)
+	assert.Equal(t, details, err.Details)
+	assert.True(t, IsForbidden(err))
+	assert.False(t, IsForbidden(UnauthorizedError("invalid token")))
+}
+
 func TestTooManyRequestsError(t *testing.T) {
 	err := TooManyRequestsError("rate limited")
 	
@@ -153,6 +164,15 @@ func TestServiceUnavailableError(t *testing.T) {
 	assert.Nil(t, err.Details)
 }
 
+func TestConflictError(t *testing.T) {
+	details := map[string]interface{}{"current_status": "running"}
+	err := ConflictError("instance must be stopped", details)
+
+	assert.Equal(t, ErrorCodeConflict, err.Code)
+	assert.Equal(t, "instance must be stopped", err.Message)
+	assert.Equal(t, details, err.Details)
+}
+
 func TestIsNotFound(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -276,4 +296,47 @@ func TestIsInvalidInput(t *testing.T) {
 			assert.Equal(t, tt.expected, IsInvalidInput(tt.err))
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestIsConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "conflict error",
+			err:      ConflictError("instance must be stopped", nil),
+			expected: true,
+		},
+		{
+			name:     "already exists error",
+			err:      AlreadyExistsError("project", "name", "test"),
+			expected: false,
+		},
+		{
+			name:     "generic error",
+			err:      assert.AnError,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsConflict(tt.err))
+		})
+	}
+}
+
+func TestNahError_WithRequestID(t *testing.T) {
+	original := NotFoundError("project", "p-1")
+
+	withID := original.WithRequestID("req-1")
+	assert.Equal(t, "req-1", withID.Details["request_id"])
+	assert.Equal(t, "p-1", withID.Details["identifier"])
+	assert.Equal(t, original.Code, withID.Code)
+	assert.NotContains(t, original.Details, "request_id", "the original error is unchanged")
+
+	assert.Equal(t, "req-1", InternalError("boom").WithRequestID("req-1").Details["request_id"])
+	assert.Same(t, original, original.WithRequestID(""))
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 )
 
@@ -14,24 +15,90 @@ type Project struct {
 
 // Instance represents a compute instance within a project
 type Instance struct {
-	ID        string    `json:"id" db:"id"`
-	ProjectID string    `json:"project_id" db:"project_id"`
-	Name      string    `json:"name" db:"name"`
-	Region    string    `json:"region" db:"region"`
-	CPU       int       `json:"cpu" db:"cpu"`
-	MemoryMB  int       `json:"memory_mb" db:"memory_mb"`
-	Image     string    `json:"image" db:"image"`
-	Status    string    `json:"status" db:"status"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID              string    `json:"id" db:"id"`
+	ProjectID       string    `json:"project_id" db:"project_id"`
+	Name            string    `json:"name" db:"name"`
+	Region          string    `json:"region" db:"region"`
+	CPU             int       `json:"cpu" db:"cpu"`
+	MemoryMB        int       `json:"memory_mb" db:"memory_mb"`
+	Image           string    `json:"image" db:"image"`
+	Status          string    `json:"status" db:"status"`
+	StatusChangedAt time.Time `json:"status_changed_at" db:"status_changed_at"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // InstanceStatus constants
 const (
-	StatusRunning = "running"
-	StatusStopped = "stopped"
+	StatusPending      = "pending"
+	StatusProvisioning = "provisioning"
+	StatusRunning      = "running"
+	StatusStopping     = "stopping"
+	StatusStopped      = "stopped"
+	StatusRebooting    = "rebooting"
+	StatusTerminating  = "terminating"
+)
+
+// ValidInstanceStatuses is the list of allowed instance statuses
+var ValidInstanceStatuses = []string{
+	StatusPending,
+	StatusProvisioning,
+	StatusRunning,
+	StatusStopping,
+	StatusStopped,
+	StatusRebooting,
+	StatusTerminating,
+}
+
+// IsTransitionalStatus reports whether an instance in this status is still
+// being moved to a stable state by the lifecycle worker
+func IsTransitionalStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusProvisioning, StatusStopping, StatusRebooting, StatusTerminating:
+		return true
+	}
+	return false
+}
+
+// Instance action constants
+const (
+	InstanceActionStart  = "start"
+	InstanceActionStop   = "stop"
+	InstanceActionReboot = "reboot"
+	InstanceActionResize = "resize"
+)
+
+// Operation status constants
+const (
+	OperationStatusPending = "pending"
+	OperationStatusRunning = "running"
+	OperationStatusDone    = "done"
+	OperationStatusFailed  = "failed"
 )
 
+// Operation represents a long-running mutation performed on a resource
+// Kind names the mutation, e.g. "instance.stop"
+// TargetID may be empty until a create operation has produced its resource
+// ProjectID is the project an instance operation's target belongs to, and empty otherwise
+// Error is only set when Status is failed
+type Operation struct {
+	ID          string     `json:"id" db:"id"`
+	Kind        string     `json:"kind" db:"kind"`
+	TargetType  string     `json:"target_type" db:"target_type"`
+	TargetID    string     `json:"target_id" db:"target_id"`
+	ProjectID   string     `json:"project_id,omitempty" db:"project_id"`
+	Status      string     `json:"status" db:"status"`
+	Error       *NahError  `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// IsComplete reports whether the operation has reached a terminal status
+func (o *Operation) IsComplete() bool {
+	return o.Status == OperationStatusDone || o.Status == OperationStatusFailed
+}
+
 // Region constants
 const (
 	RegionUSEast1    = "us-east-1"
@@ -95,6 +162,127 @@ type TFStateLock struct {
 	Path      string   `json:"Path,omitempty"`
 }
 
+// TFState is the current Terraform state stored under an ID
+type TFState struct {
+	ID        string    `json:"id"`
+	Serial    int64     `json:"serial"`
+	Lineage   string    `json:"lineage"`
+	Version   int       `json:"version"` // the version that was written last
+	Size      int       `json:"size"`
+	State     string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Locked and Lock describe the lock held on the state; only listings fill them in
+	Locked bool               `json:"locked"`
+	Lock   *TFStateLockStatus `json:"lock,omitempty"`
+}
+
+// TFStateVersion describes one immutable version of a Terraform state, recorded
+// every time the state is written
+type TFStateVersion struct {
+	StateID      string    `json:"state_id"`
+	Version      int       `json:"version"`
+	Serial       int64     `json:"serial"`
+	Lineage      string    `json:"lineage"`
+	Size         int       `json:"size"`
+	Checksum     string    `json:"checksum"`                // hex SHA-256 of the state
+	LockID       string    `json:"lock_id,omitempty"`       // lock held by the writer, if any
+	RollbackFrom int       `json:"rollback_from,omitempty"` // version restored by a rollback
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TFStateLockRecord is a held lock on a Terraform state
+type TFStateLockRecord struct {
+	StateID   string     `json:"state_id"`
+	LockID    string     `json:"lock_id"`
+	Info      string     `json:"-"` // lock payload as Terraform sent it
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil if the lock never expires
+}
+
+// TFStateLockStatus describes a lock currently held on a Terraform state
+type TFStateLockStatus struct {
+	StateID    string      `json:"state_id"`
+	Lock       TFStateLock `json:"lock"`
+	AcquiredAt time.Time   `json:"acquired_at"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+}
+
+// API key scope access levels
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// ScopeResources is the list of resource types API key scopes can grant access to
+var ScopeResources = []string{"projects", "instances", "operations", "metadata", "buckets", "objects", "tfstate"}
+
+// ResourceForPath returns the resource type addressed by an API path
+func ResourceForPath(urlPath string) string {
+	resource, _, _ := strings.Cut(strings.TrimPrefix(urlPath, "/v1/"), "/")
+	if resource == "bucket" {
+		return "objects"
+	}
+	return resource
+}
+
+// APIKey represents a scoped credential for the API
+// Scopes take the form "<resource>:<read|write>"; "<resource>:*" and "*" grant every access
+// A key with a ProjectID can only reach that project and its instances
+// The secret itself is never stored, only its hash
+type APIKey struct {
+	ID        string     `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Prefix    string     `json:"prefix" db:"prefix"`
+	Scopes    []string   `json:"scopes" db:"scopes"`
+	ProjectID string     `json:"project_id,omitempty" db:"project_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// Allows reports whether the key's scopes grant an access level on a resource type
+func (k *APIKey) Allows(resource, access string) bool {
+	for _, scope := range k.Scopes {
+		if scope == "*" || scope == resource+":*" || scope == resource+":"+access {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// CreatedAPIKey is returned once when a key is created; it is the only time the secret is shown
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest represents the request to create an API key
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ProjectID string   `json:"project_id,omitempty"`
+}
+
+// AccessToken is issued by the OAuth2 token endpoint in exchange for client credentials
+type AccessToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"` // seconds until the token expires
+	Scope       string `json:"scope,omitempty"`
+}
+
+// APIKeyListOptions represents query options for listing API keys
+type APIKeyListOptions struct {
+	IncludeRevoked bool
+	PageOptions
+}
+
 // CreateProjectRequest represents the request to create a project
 type CreateProjectRequest struct {
 	Name string `json:"name"`
@@ -125,9 +313,17 @@ type UpdateInstanceRequest struct {
 	Status   *string `json:"status,omitempty"`
 }
 
+// InstanceActionRequest represents the optional body of an instance action
+// CPU and MemoryMB are only used by the resize action
+type InstanceActionRequest struct {
+	CPU      *int `json:"cpu,omitempty"`
+	MemoryMB *int `json:"memory_mb,omitempty"`
+}
+
 // ProjectListOptions represents query options for listing projects
 type ProjectListOptions struct {
 	Name string
+	PageOptions
 }
 
 // InstanceListOptions represents query options for listing instances
@@ -136,6 +332,7 @@ type InstanceListOptions struct {
 	Name      string
 	Region    string
 	Status    string
+	PageOptions
 }
 
 // CreateMetadataRequest represents the request to create metadata
@@ -153,6 +350,13 @@ type UpdateMetadataRequest struct {
 // MetadataListOptions represents query options for listing metadata
 type MetadataListOptions struct {
 	Prefix string
+	PageOptions
+}
+
+// TFStateListOptions represents query options for listing Terraform states
+type TFStateListOptions struct {
+	Prefix string
+	PageOptions
 }
 
 // CreateBucketRequest represents the request to create a bucket
@@ -168,6 +372,7 @@ type UpdateBucketRequest struct {
 // BucketListOptions represents query options for listing buckets
 type BucketListOptions struct {
 	Name string
+	PageOptions
 }
 
 // CreateObjectRequest represents the request to create an object
@@ -187,4 +392,20 @@ type UpdateObjectRequest struct {
 type ObjectListOptions struct {
 	BucketID string
 	Prefix   string
+	PageOptions
+}
+
+// UpdateOperationRequest represents an update to an operation's progress
+type UpdateOperationRequest struct {
+	Status   *string   `json:"status,omitempty"`
+	TargetID *string   `json:"target_id,omitempty"`
+	Error    *NahError `json:"error,omitempty"`
+}
+
+// OperationListOptions represents query options for listing operations
+type OperationListOptions struct {
+	TargetType string
+	TargetID   string
+	Status     string
+	PageOptions
 }
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Page size limits for list endpoints
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// PageOptions represents cursor-based pagination parameters for list queries
+// A zero PageSize returns every remaining item
+type PageOptions struct {
+	PageSize  int
+	PageToken string
+}
+
+// ListResponse is the envelope returned by every list endpoint
+// NextPageToken is empty on the last page
+type ListResponse[T any] struct {
+	Items         []T    `json:"items"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// NewListResponse wraps a page of items, encoding an empty page as [] rather than null
+func NewListResponse[T any](items []T, nextPageToken string) ListResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return ListResponse[T]{Items: items, NextPageToken: nextPageToken}
+}
+
+// pageCursor is the decoded form of a page token
+// Resource guards against a token from one listing being replayed against another
+type pageCursor struct {
+	Resource string `json:"r"`
+	Key      string `json:"k"`
+	ID       string `json:"id"`
+}
+
+// EncodePageToken builds an opaque page token positioned after the item with the given sort key and ID
+func EncodePageToken(resource, key, id string) string {
+	b, _ := json.Marshal(pageCursor{Resource: resource, Key: key, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodePageToken returns the sort key and ID a page token was positioned after
+func DecodePageToken(resource, token string) (key string, id string, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", InvalidPageTokenError(token)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Resource != resource || c.ID == "" {
+		return "", "", InvalidPageTokenError(token)
+	}
+	return c.Key, c.ID, nil
+}
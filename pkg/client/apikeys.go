@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// API key administration
+
+// CreateAPIKey creates a scoped API key. The returned secret is not retrievable later.
+func (c *Client) CreateAPIKey(ctx context.Context, req domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error) {
+	var key domain.CreatedAPIKey
+	err := c.do(ctx, "POST", "/_admin/keys", req, &key)
+	return &key, err
+}
+
+// GetAPIKey retrieves an API key by ID
+func (c *Client) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := c.do(ctx, "GET", "/_admin/keys/"+url.PathEscape(id), nil, &key)
+	return &key, err
+}
+
+// ListAPIKeys lists every API key matching opts, following page tokens
+func (c *Client) ListAPIKeys(ctx context.Context, opts domain.APIKeyListOptions) ([]*domain.APIKey, error) {
+	return collect(paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.APIKey], error) {
+		params := url.Values{}
+		if opts.IncludeRevoked {
+			params.Set("include_revoked", "true")
+		}
+		return listPage[*domain.APIKey](ctx, c, "/_admin/keys", params, page)
+	}))
+}
+
+// RevokeAPIKey revokes an API key
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/_admin/keys/"+url.PathEscape(id), nil, nil)
+}
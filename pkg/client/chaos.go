@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Chaos administration
+
+// GetChaosConfig retrieves the server's live chaos configuration
+func (c *Client) GetChaosConfig(ctx context.Context) (*domain.ChaosConfig, error) {
+	var config domain.ChaosConfig
+	err := c.do(ctx, "GET", "/_admin/chaos", nil, &config)
+	return &config, err
+}
+
+// SetChaosConfig replaces the server's live chaos configuration
+func (c *Client) SetChaosConfig(ctx context.Context, config domain.ChaosConfig) (*domain.ChaosConfig, error) {
+	var updated domain.ChaosConfig
+	err := c.do(ctx, "PUT", "/_admin/chaos", config, &updated)
+	return &updated, err
+}
+
+// AddChaosRule appends a named rule to the server's chaos rules
+func (c *Client) AddChaosRule(ctx context.Context, rule domain.ChaosRule) (*domain.ChaosRule, error) {
+	var added domain.ChaosRule
+	err := c.do(ctx, "POST", "/_admin/chaos/rules", rule, &added)
+	return &added, err
+}
+
+// RemoveChaosRule removes a chaos rule by name
+func (c *Client) RemoveChaosRule(ctx context.Context, name string) error {
+	return c.do(ctx, "DELETE", "/_admin/chaos/rules/"+url.PathEscape(name), nil, nil)
+}
+
+// ReseedChaos changes the server's chaos seed and restarts request ordinals
+func (c *Client) ReseedChaos(ctx context.Context, seed int64) error {
+	return c.do(ctx, "POST", "/_admin/chaos/seed", map[string]int64{"seed": seed}, nil)
+}
+
+// ChaosJournal retrieves the faults the server has injected, oldest first
+func (c *Client) ChaosJournal(ctx context.Context, filter domain.ChaosJournalFilter) ([]domain.ChaosJournalEntry, error) {
+	params := url.Values{}
+	if filter.Key != "" {
+		params.Set("key", filter.Key)
+	}
+	if filter.Rule != "" {
+		params.Set("rule", filter.Rule)
+	}
+	if filter.Since > 0 {
+		params.Set("since", strconv.FormatInt(filter.Since, 10))
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	path := "/_admin/chaos/journal"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp domain.ListResponse[domain.ChaosJournalEntry]
+	if err := c.do(ctx, "GET", path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// ClearChaosJournal discards the server's record of injected faults
+func (c *Client) ClearChaosJournal(ctx context.Context) error {
+	return c.do(ctx, "DELETE", "/_admin/chaos/journal", nil, nil)
+}
+
+// ApplyChaos changes the live chaos configuration and returns a function that
+// restores the previous one. It is meant for scoping chaos to a single test:
+//
+//	restore, err := c.ApplyChaos(ctx, func(cfg *domain.ChaosConfig) {
+//		cfg.Enabled = true
+//		cfg.InstancesErrorRate = 1.0
+//	})
+//	require.NoError(t, err)
+//	t.Cleanup(func() { restore(context.Background()) })
+func (c *Client) ApplyChaos(ctx context.Context, update func(*domain.ChaosConfig)) (func(context.Context) error, error) {
+	previous, err := c.GetChaosConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := *previous
+	config.Rules = append([]domain.ChaosRule(nil), previous.Rules...)
+	update(&config)
+	if _, err := c.SetChaosConfig(ctx, config); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		_, err := c.SetChaosConfig(ctx, *previous)
+		return err
+	}, nil
+}
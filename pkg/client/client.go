@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -19,6 +21,7 @@ import (
 type Client struct {
 	baseURL    string
 	token      string
+	tokens     *tokenSource // set in client credentials mode
 	httpClient *http.Client
 	
 	// Retry configuration
@@ -33,6 +36,13 @@ type Config struct {
 	HTTPClient            *http.Client
 	RetryMax              int
 	RetryInitialBackoffMs int
+
+	// Client credentials mode: when ClientID is set, the client exchanges the
+	// credentials for OAuth2 access tokens and refreshes them automatically,
+	// instead of sending Token. TokenURL defaults to the server's token endpoint.
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
 }
 
 // NewClient creates a new NahCloud API client
@@ -55,30 +65,44 @@ func NewClient(config Config) *Client {
 		config.RetryInitialBackoffMs = 1000
 	}
 	
-	return &Client{
+	client := &Client{
 		baseURL:               strings.TrimRight(config.BaseURL, "/"),
 		token:                 config.Token,
 		httpClient:            config.HTTPClient,
 		retryMax:              config.RetryMax,
 		retryInitialBackoffMs: config.RetryInitialBackoffMs,
 	}
+	
+	if config.ClientID != "" {
+		if config.TokenURL == "" {
+			config.TokenURL = client.baseURL + "/v1/oauth/token"
+		}
+		client.tokens = &tokenSource{
+			tokenURL:     config.TokenURL,
+			clientID:     config.ClientID,
+			clientSecret: config.ClientSecret,
+			httpClient:   config.HTTPClient,
+		}
+	}
+	
+	return client
 }
 
 // do performs an HTTP request with retry logic
 func (c *Client) do(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var reqBody io.Reader
+	var payload []byte
 	
 	if body != nil {
 		if s, ok := body.(string); ok {
 			// Handle plain text body (for metadata)
-			reqBody = strings.NewReader(s)
+			payload = []byte(s)
 		} else {
 			// Handle JSON body
 			jsonData, err := json.Marshal(body)
 			if err != nil {
 				return fmt.Errorf("failed to marshal request body: %w", err)
 			}
-			reqBody = bytes.NewBuffer(jsonData)
+			payload = jsonData
 		}
 	}
 	
@@ -87,8 +111,11 @@ func (c *Client) do(ctx context.Context, method, path string, body interface{},
 	var lastErr error
 	backoff := time.Duration(c.retryInitialBackoffMs) * time.Millisecond
 	
+	// A rejected access token is refreshed and the request repeated once, straight away
+	refreshed, refreshing := false, false
+	
 	for attempt := 0; attempt <= c.retryMax; attempt++ {
-		if attempt > 0 {
+		if attempt > 0 && !refreshing {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -96,6 +123,13 @@ func (c *Client) do(ctx context.Context, method, path string, body interface{},
 			}
 			backoff *= 2 // Exponential backoff
 		}
+		refreshing = false
+		
+		// Every attempt sends the body from the start
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
 		
 		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 		if err != nil {
@@ -111,8 +145,19 @@ func (c *Client) do(ctx context.Context, method, path string, body interface{},
 			}
 		}
 		
-		if c.token != "" {
-			req.Header.Set("Authorization", "Bearer "+c.token)
+		token := c.token
+		if c.tokens != nil {
+			if token, err = c.tokens.token(ctx); err != nil {
+				var temporary *temporaryError
+				if errors.As(err, &temporary) {
+					lastErr = err
+					continue
+				}
+				return err
+			}
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
 		}
 		
 		resp, err := c.httpClient.Do(req)
@@ -123,16 +168,21 @@ func (c *Client) do(ctx context.Context, method, path string, body interface{},
 		
 		defer resp.Body.Close()
 		
+		if resp.StatusCode == http.StatusUnauthorized && c.tokens != nil && !refreshed {
+			c.tokens.invalidate(token)
+			refreshed, refreshing = true, true
+			attempt--
+			continue
+		}
+		
 		// Check if we should retry
 		if shouldRetry(resp.StatusCode) {
 			respBody, _ := io.ReadAll(resp.Body)
 			
-			// Handle Retry-After header for 429 responses
-			if resp.StatusCode == http.StatusTooManyRequests {
-				if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-					if seconds, err := strconv.Atoi(retryAfter); err == nil {
-						backoff = time.Duration(seconds) * time.Second
-					}
+			// Handle Retry-After header for 429 and 503 responses
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+				if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+					backoff = delay
 				}
 			}
 			
@@ -180,6 +230,23 @@ func shouldRetry(statusCode int) bool {
 	return statusCode == http.StatusTooManyRequests || statusCode >= 500
 }
 
+// parseRetryAfter reads a Retry-After header given either as seconds or as an HTTP-date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
 // Project operations
 
 // CreateProject creates a new project
@@ -196,16 +263,26 @@ func (c *Client) GetProject(ctx context.Context, id string) (*domain.Project, er
 	return &project, err
 }
 
-// ListProjects lists projects with optional filtering
+// ListProjects lists every project matching opts, following page tokens
 func (c *Client) ListProjects(ctx context.Context, opts domain.ProjectListOptions) ([]*domain.Project, error) {
-	path := "/projects"
+	return collect(c.ListProjectsIter(ctx, opts))
+}
+
+// ListProjectsPage fetches a single page of projects
+func (c *Client) ListProjectsPage(ctx context.Context, opts domain.ProjectListOptions) (*domain.ListResponse[*domain.Project], error) {
+	params := url.Values{}
 	if opts.Name != "" {
-		path += "?name=" + url.QueryEscape(opts.Name)
+		params.Set("name", opts.Name)
 	}
-	
-	var projects []*domain.Project
-	err := c.do(ctx, "GET", path, nil, &projects)
-	return projects, err
+	return listPage[*domain.Project](ctx, c, "/projects", params, opts.PageOptions)
+}
+
+// ListProjectsIter returns an iterator over every project matching opts, fetching pages as needed
+func (c *Client) ListProjectsIter(ctx context.Context, opts domain.ProjectListOptions) iter.Seq2[*domain.Project, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.Project], error) {
+		opts.PageOptions = page
+		return c.ListProjectsPage(ctx, opts)
+	})
 }
 
 // UpdateProject updates an existing project
@@ -236,28 +313,35 @@ func (c *Client) GetInstance(ctx context.Context, id string) (*domain.Instance,
 	return &instance, err
 }
 
-// ListInstances lists instances with optional filtering
+// ListInstances lists every instance matching opts, following page tokens
 func (c *Client) ListInstances(ctx context.Context, opts domain.InstanceListOptions) ([]*domain.Instance, error) {
-	path := "/instances"
+	return collect(c.ListInstancesIter(ctx, opts))
+}
+
+// ListInstancesPage fetches a single page of instances
+func (c *Client) ListInstancesPage(ctx context.Context, opts domain.InstanceListOptions) (*domain.ListResponse[*domain.Instance], error) {
 	params := url.Values{}
-	
 	if opts.ProjectID != "" {
 		params.Set("project_id", opts.ProjectID)
 	}
 	if opts.Name != "" {
 		params.Set("name", opts.Name)
 	}
+	if opts.Region != "" {
+		params.Set("region", opts.Region)
+	}
 	if opts.Status != "" {
 		params.Set("status", opts.Status)
 	}
-	
-	if len(params) > 0 {
-		path += "?" + params.Encode()
-	}
-	
-	var instances []*domain.Instance
-	err := c.do(ctx, "GET", path, nil, &instances)
-	return instances, err
+	return listPage[*domain.Instance](ctx, c, "/instances", params, opts.PageOptions)
+}
+
+// ListInstancesIter returns an iterator over every instance matching opts, fetching pages as needed
+func (c *Client) ListInstancesIter(ctx context.Context, opts domain.InstanceListOptions) iter.Seq2[*domain.Instance, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.Instance], error) {
+		opts.PageOptions = page
+		return c.ListInstancesPage(ctx, opts)
+	})
 }
 
 // UpdateInstance updates an existing instance
@@ -272,6 +356,54 @@ func (c *Client) DeleteInstance(ctx context.Context, id string) error {
 	return c.do(ctx, "DELETE", "/instances/"+url.PathEscape(id), nil, nil)
 }
 
+// StartInstance starts a stopped instance
+func (c *Client) StartInstance(ctx context.Context, id string) (*domain.Operation, error) {
+	return c.instanceAction(ctx, id, domain.InstanceActionStart, nil)
+}
+
+// StopInstance stops a running instance
+func (c *Client) StopInstance(ctx context.Context, id string) (*domain.Operation, error) {
+	return c.instanceAction(ctx, id, domain.InstanceActionStop, nil)
+}
+
+// RebootInstance reboots a running instance
+func (c *Client) RebootInstance(ctx context.Context, id string) (*domain.Operation, error) {
+	return c.instanceAction(ctx, id, domain.InstanceActionReboot, nil)
+}
+
+// ResizeInstance changes the CPU and/or memory of a stopped instance
+func (c *Client) ResizeInstance(ctx context.Context, id string, req domain.InstanceActionRequest) (*domain.Operation, error) {
+	return c.instanceAction(ctx, id, domain.InstanceActionResize, req)
+}
+
+// CreateInstanceAsync starts creating an instance and returns the pending operation
+func (c *Client) CreateInstanceAsync(ctx context.Context, req domain.CreateInstanceRequest) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "POST", "/instances?async=true", req, &op)
+	return &op, err
+}
+
+// UpdateInstanceAsync starts updating an instance and returns the pending operation
+func (c *Client) UpdateInstanceAsync(ctx context.Context, id string, req domain.UpdateInstanceRequest) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "PATCH", "/instances/"+url.PathEscape(id)+"?async=true", req, &op)
+	return &op, err
+}
+
+// DeleteInstanceAsync starts deleting an instance and returns the pending operation
+func (c *Client) DeleteInstanceAsync(ctx context.Context, id string) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "DELETE", "/instances/"+url.PathEscape(id)+"?async=true", nil, &op)
+	return &op, err
+}
+
+// instanceAction performs an instance action and returns the resulting operation
+func (c *Client) instanceAction(ctx context.Context, id, action string, body interface{}) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "POST", "/instances/"+url.PathEscape(id)+"/actions/"+action, body, &op)
+	return &op, err
+}
+
 // Metadata operations
 
 // CreateMetadata creates new metadata
@@ -295,19 +427,165 @@ func (c *Client) UpdateMetadata(ctx context.Context, id string, req domain.Updat
 	return &metadata, err
 }
 
-// ListMetadata lists metadata with optional prefix filtering
+// ListMetadata lists every metadata entry matching opts, following page tokens
 func (c *Client) ListMetadata(ctx context.Context, opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
-	path := "/metadata"
+	return collect(c.ListMetadataIter(ctx, opts))
+}
+
+// ListMetadataPage fetches a single page of metadata
+func (c *Client) ListMetadataPage(ctx context.Context, opts domain.MetadataListOptions) (*domain.ListResponse[*domain.Metadata], error) {
+	params := url.Values{}
 	if opts.Prefix != "" {
-		path += "?prefix=" + url.QueryEscape(opts.Prefix)
+		params.Set("prefix", opts.Prefix)
 	}
-	
-	var metadata []*domain.Metadata
-	err := c.do(ctx, "GET", path, nil, &metadata)
-	return metadata, err
+	return listPage[*domain.Metadata](ctx, c, "/metadata", params, opts.PageOptions)
+}
+
+// ListMetadataIter returns an iterator over every metadata entry matching opts, fetching pages as needed
+func (c *Client) ListMetadataIter(ctx context.Context, opts domain.MetadataListOptions) iter.Seq2[*domain.Metadata, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.Metadata], error) {
+		opts.PageOptions = page
+		return c.ListMetadataPage(ctx, opts)
+	})
 }
 
 // DeleteMetadata deletes metadata by ID
 func (c *Client) DeleteMetadata(ctx context.Context, id string) error {
 	return c.do(ctx, "DELETE", "/metadata/"+id, nil, nil)
-}
\ No newline at end of file
+}
+
+// Bucket operations
+
+// ListBuckets lists every bucket matching opts, following page tokens
+func (c *Client) ListBuckets(ctx context.Context, opts domain.BucketListOptions) ([]*domain.Bucket, error) {
+	return collect(c.ListBucketsIter(ctx, opts))
+}
+
+// ListBucketsPage fetches a single page of buckets
+func (c *Client) ListBucketsPage(ctx context.Context, opts domain.BucketListOptions) (*domain.ListResponse[*domain.Bucket], error) {
+	params := url.Values{}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+	return listPage[*domain.Bucket](ctx, c, "/buckets", params, opts.PageOptions)
+}
+
+// ListBucketsIter returns an iterator over every bucket matching opts, fetching pages as needed
+func (c *Client) ListBucketsIter(ctx context.Context, opts domain.BucketListOptions) iter.Seq2[*domain.Bucket, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.Bucket], error) {
+		opts.PageOptions = page
+		return c.ListBucketsPage(ctx, opts)
+	})
+}
+
+// CreateBucketAsync starts creating a bucket and returns the pending operation
+func (c *Client) CreateBucketAsync(ctx context.Context, req domain.CreateBucketRequest) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "POST", "/buckets?async=true", req, &op)
+	return &op, err
+}
+
+// UpdateBucketAsync starts updating a bucket and returns the pending operation
+func (c *Client) UpdateBucketAsync(ctx context.Context, id string, req domain.UpdateBucketRequest) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "PATCH", "/buckets/"+url.PathEscape(id)+"?async=true", req, &op)
+	return &op, err
+}
+
+// DeleteBucketAsync starts deleting a bucket and returns the pending operation
+func (c *Client) DeleteBucketAsync(ctx context.Context, id string) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "DELETE", "/buckets/"+url.PathEscape(id)+"?async=true", nil, &op)
+	return &op, err
+}
+
+// Object operations
+
+// ListObjects lists every object in opts.BucketID matching opts, following page tokens
+func (c *Client) ListObjects(ctx context.Context, opts domain.ObjectListOptions) ([]*domain.Object, error) {
+	return collect(c.ListObjectsIter(ctx, opts))
+}
+
+// ListObjectsPage fetches a single page of objects from opts.BucketID
+func (c *Client) ListObjectsPage(ctx context.Context, opts domain.ObjectListOptions) (*domain.ListResponse[*domain.Object], error) {
+	params := url.Values{}
+	if opts.Prefix != "" {
+		params.Set("prefix", opts.Prefix)
+	}
+	return listPage[*domain.Object](ctx, c, "/bucket/"+url.PathEscape(opts.BucketID)+"/objects", params, opts.PageOptions)
+}
+
+// ListObjectsIter returns an iterator over every object in opts.BucketID matching opts, fetching pages as needed
+func (c *Client) ListObjectsIter(ctx context.Context, opts domain.ObjectListOptions) iter.Seq2[*domain.Object, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.Object], error) {
+		opts.PageOptions = page
+		return c.ListObjectsPage(ctx, opts)
+	})
+}
+
+// Operation operations
+
+// GetOperation retrieves an operation by ID
+func (c *Client) GetOperation(ctx context.Context, id string) (*domain.Operation, error) {
+	var op domain.Operation
+	err := c.do(ctx, "GET", "/operations/"+url.PathEscape(id), nil, &op)
+	return &op, err
+}
+
+// ListOperations lists every operation matching opts, following page tokens
+func (c *Client) ListOperations(ctx context.Context, opts domain.OperationListOptions) ([]*domain.Operation, error) {
+	return collect(c.ListOperationsIter(ctx, opts))
+}
+
+// ListOperationsPage fetches a single page of operations
+func (c *Client) ListOperationsPage(ctx context.Context, opts domain.OperationListOptions) (*domain.ListResponse[*domain.Operation], error) {
+	params := url.Values{}
+	if opts.TargetType != "" {
+		params.Set("target_type", opts.TargetType)
+	}
+	if opts.TargetID != "" {
+		params.Set("target_id", opts.TargetID)
+	}
+	if opts.Status != "" {
+		params.Set("status", opts.Status)
+	}
+	return listPage[*domain.Operation](ctx, c, "/operations", params, opts.PageOptions)
+}
+
+// ListOperationsIter returns an iterator over every operation matching opts, fetching pages as needed
+func (c *Client) ListOperationsIter(ctx context.Context, opts domain.OperationListOptions) iter.Seq2[*domain.Operation, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.Operation], error) {
+		opts.PageOptions = page
+		return c.ListOperationsPage(ctx, opts)
+	})
+}
+
+// WaitForOperation polls an operation until it completes or ctx is cancelled.
+// A failed operation is returned together with its error.
+func (c *Client) WaitForOperation(ctx context.Context, id string, pollInterval time.Duration) (*domain.Operation, error) {
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+
+	for {
+		op, err := c.GetOperation(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if op.Status == domain.OperationStatusFailed {
+			if op.Error != nil {
+				return op, op.Error
+			}
+			return op, fmt.Errorf("operation %s failed", id)
+		}
+		if op.IsComplete() {
+			return op, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return op, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
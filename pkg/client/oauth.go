@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// maxTokenRefreshMargin caps how long before expiry a cached access token is replaced
+const maxTokenRefreshMargin = 30 * time.Second
+
+// tokenSource fetches OAuth2 access tokens with the client credentials grant and
+// caches them until shortly before they expire
+type tokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mu      sync.Mutex
+	current string
+	refresh time.Time // when the cached token should be replaced
+}
+
+// temporaryError marks a token endpoint failure that is worth retrying
+type temporaryError struct {
+	err error
+}
+
+func (e *temporaryError) Error() string { return e.err.Error() }
+
+func (e *temporaryError) Unwrap() error { return e.err }
+
+// token returns a valid access token, fetching a new one if needed
+func (s *tokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != "" && time.Now().Before(s.refresh) {
+		return s.current, nil
+	}
+
+	token, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Refresh a tenth of the lifetime early, so a token never expires in flight
+	ttl := time.Duration(token.ExpiresIn) * time.Second
+	s.current = token.AccessToken
+	s.refresh = time.Now().Add(ttl - min(ttl/10, maxTokenRefreshMargin))
+	return s.current, nil
+}
+
+// invalidate discards a token the server rejected, unless it was already replaced
+func (s *tokenSource) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == token {
+		s.current = ""
+	}
+}
+
+// fetch requests a new access token from the token endpoint
+func (s *tokenSource) fetch(ctx context.Context) (*domain.AccessToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, &temporaryError{fmt.Errorf("token request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &temporaryError{fmt.Errorf("failed to read token response: %w", err)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if shouldRetry(resp.StatusCode) {
+			return nil, &temporaryError{fmt.Errorf("token request failed: HTTP %d: %s", resp.StatusCode, string(body))}
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			if resp.StatusCode == http.StatusUnauthorized {
+				return nil, domain.UnauthorizedError(oauthErr.ErrorDescription)
+			}
+			return nil, fmt.Errorf("token request failed: %s: %s", oauthErr.Error, oauthErr.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request failed: HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var token domain.AccessToken
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		// A cut-off body is as transient as a dropped connection
+		return nil, &temporaryError{fmt.Errorf("invalid token response: %s", string(body))}
+	}
+	return &token, nil
+}
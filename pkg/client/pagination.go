@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// listPage fetches a single page from a list endpoint
+func listPage[T any](ctx context.Context, c *Client, path string, params url.Values, page domain.PageOptions) (*domain.ListResponse[T], error) {
+	if page.PageSize > 0 {
+		params.Set("page_size", strconv.Itoa(page.PageSize))
+	}
+	if page.PageToken != "" {
+		params.Set("page_token", page.PageToken)
+	}
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp domain.ListResponse[T]
+	if err := c.do(ctx, "GET", path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// paginate returns an iterator over every item of a listing, starting at page and
+// following next page tokens until the last page. Iteration stops after the first error.
+func paginate[T any](page domain.PageOptions, fetch func(domain.PageOptions) (*domain.ListResponse[T], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			resp, err := fetch(page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range resp.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if resp.NextPageToken == "" {
+				return
+			}
+			page.PageToken = resp.NextPageToken
+		}
+	}
+}
+
+// collect drains an iterator into a slice
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hypertf/nahcloud/service/ratelimit"
+)
+
+// Rate limit administration
+
+// GetRateLimits retrieves the server's rate limit configuration and bucket state
+func (c *Client) GetRateLimits(ctx context.Context) (*ratelimit.Status, error) {
+	var status ratelimit.Status
+	err := c.do(ctx, "GET", "/_admin/ratelimit", nil, &status)
+	return &status, err
+}
+
+// SetRateLimits replaces the server's rate limit configuration, discarding all buckets
+func (c *Client) SetRateLimits(ctx context.Context, config ratelimit.Config) (*ratelimit.Status, error) {
+	var status ratelimit.Status
+	err := c.do(ctx, "PUT", "/_admin/ratelimit", config, &status)
+	return &status, err
+}
+
+// ResetRateLimits refills every bucket on the server
+func (c *Client) ResetRateLimits(ctx context.Context) error {
+	return c.do(ctx, "DELETE", "/_admin/ratelimit/buckets", nil, nil)
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Terraform states
+
+// ListTFStates lists every state matching opts, with the lock held on each, following page tokens
+func (c *Client) ListTFStates(ctx context.Context, opts domain.TFStateListOptions) ([]*domain.TFState, error) {
+	return collect(c.ListTFStatesIter(ctx, opts))
+}
+
+// ListTFStatesPage fetches a single page of states
+func (c *Client) ListTFStatesPage(ctx context.Context, opts domain.TFStateListOptions) (*domain.ListResponse[*domain.TFState], error) {
+	params := url.Values{}
+	if opts.Prefix != "" {
+		params.Set("prefix", opts.Prefix)
+	}
+	return listPage[*domain.TFState](ctx, c, "/tfstate", params, opts.PageOptions)
+}
+
+// ListTFStatesIter returns an iterator over every state matching opts, fetching pages as needed
+func (c *Client) ListTFStatesIter(ctx context.Context, opts domain.TFStateListOptions) iter.Seq2[*domain.TFState, error] {
+	return paginate(opts.PageOptions, func(page domain.PageOptions) (*domain.ListResponse[*domain.TFState], error) {
+		opts.PageOptions = page
+		return c.ListTFStatesPage(ctx, opts)
+	})
+}
+
+// Terraform state locks
+
+// GetTFStateLock retrieves the lock held on a state, as Terraform sent it
+func (c *Client) GetTFStateLock(ctx context.Context, stateID string) (*domain.TFStateLock, error) {
+	var lock domain.TFStateLock
+	err := c.do(ctx, "GET", "/tfstate/"+url.PathEscape(stateID)+"/lock", nil, &lock)
+	return &lock, err
+}
+
+// ListTFStateLocks lists every lock currently held on a state
+func (c *Client) ListTFStateLocks(ctx context.Context) ([]*domain.TFStateLockStatus, error) {
+	var resp domain.ListResponse[*domain.TFStateLockStatus]
+	err := c.do(ctx, "GET", "/_admin/tfstate/locks", nil, &resp)
+	return resp.Items, err
+}
+
+// ForceUnlockTFState releases whichever lock is held on a state and returns it
+func (c *Client) ForceUnlockTFState(ctx context.Context, stateID string) (*domain.TFStateLockStatus, error) {
+	var status domain.TFStateLockStatus
+	err := c.do(ctx, "DELETE", "/_admin/tfstate/locks/"+url.PathEscape(stateID), nil, &status)
+	return &status, err
+}
@@ -0,0 +1,165 @@
+package nahtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/hypertf/nahcloud/api"
+	"github.com/hypertf/nahcloud/pkg/client"
+	"github.com/hypertf/nahcloud/service"
+	"github.com/hypertf/nahcloud/service/chaos"
+	"github.com/hypertf/nahcloud/service/ratelimit"
+	"github.com/hypertf/nahcloud/storage"
+)
+
+// Storage backends
+const (
+	StorageMemory = storage.Memory
+	StorageSQLite = storage.SQLite
+)
+
+// DefaultChaosSeed seeds chaos when Config.Chaos doesn't, so faults repeat from run to run
+const DefaultChaosSeed = 1
+
+// Config configures a test server. The zero value serves an empty in-memory
+// cloud without auth or chaos.
+type Config struct {
+	// Token is required as a bearer token by the server and sent by the client
+	Token string
+
+	// Storage is StorageMemory (the default) or StorageSQLite, which uses a
+	// database in the test's temporary directory
+	Storage string
+
+	// Chaos is the initial chaos configuration; nil disables chaos
+	Chaos *chaos.Config
+
+	// ChaosRules are added to the chaos configuration, enabling chaos if needed
+	ChaosRules []chaos.Rule
+
+	// Lifecycle controls instance lifecycle states; disabled by default
+	Lifecycle service.LifecycleConfig
+
+	// TFState controls Terraform state validation and locking; lenient, with
+	// locks that never expire, by default
+	TFState service.TFStateConfig
+
+	// Seed is called with the service before the server starts, to create the
+	// resources a test expects to exist. Chaos doesn't apply to it.
+	Seed func(svc *service.Service) error
+
+	// Client configures the returned client. BaseURL, Token and HTTPClient are
+	// filled in when empty, and retries back off from 10ms rather than 1s.
+	Client client.Config
+}
+
+// Server is a fully wired NahCloud server running on a local port
+type Server struct {
+	*httptest.Server
+
+	// Client is a client for the server, configured by Config.Client
+	Client *client.Client
+
+	// Service and Chaos give tests direct access to the server's state
+	Service *service.Service
+	Chaos   *chaos.ChaosService
+}
+
+// NewServer starts a test server and returns it with a client for it. The
+// server and its storage are cleaned up when the test finishes.
+func NewServer(t testing.TB, config Config) *Server {
+	t.Helper()
+
+	repos := openStorage(t, config.Storage)
+	svc := service.NewService(repos.Projects, repos.Instances, repos.Metadata, repos.Buckets, repos.Objects, repos.Operations, repos.APIKeys, repos.TFStates)
+	svc.SetLifecycleConfig(config.Lifecycle)
+	svc.SetTFStateConfig(config.TFState)
+
+	if config.Seed != nil {
+		if err := config.Seed(svc); err != nil {
+			t.Fatalf("nahtest: failed to seed data: %v", err)
+		}
+	}
+
+	chaosCfg := chaosConfig(config)
+	if err := chaosCfg.Validate(); err != nil {
+		t.Fatalf("nahtest: invalid chaos config: %v", err)
+	}
+	chaosService := chaos.NewChaosServiceWithConfig(chaosCfg)
+	rateLimiter := ratelimit.NewLimiter(&ratelimit.Config{})
+
+	handler := api.NewHandler(svc, chaosService, rateLimiter, config.Token)
+	handler.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	srv := httptest.NewServer(api.SetupRouter(handler, "test"))
+
+	svc.StartLifecycleWorker()
+	t.Cleanup(func() {
+		srv.Close()
+		svc.Shutdown(context.Background())
+	})
+
+	clientConfig := config.Client
+	if clientConfig.BaseURL == "" {
+		clientConfig.BaseURL = srv.URL
+	}
+	if clientConfig.Token == "" && clientConfig.ClientID == "" {
+		clientConfig.Token = config.Token
+	}
+	if clientConfig.HTTPClient == nil {
+		clientConfig.HTTPClient = srv.Client()
+	}
+	if clientConfig.RetryInitialBackoffMs == 0 {
+		clientConfig.RetryInitialBackoffMs = 10
+	}
+
+	return &Server{
+		Server:  srv,
+		Client:  client.NewClient(clientConfig),
+		Service: svc,
+		Chaos:   chaosService,
+	}
+}
+
+// chaosConfig returns the initial chaos configuration for a test server
+func chaosConfig(config Config) *chaos.Config {
+	var cfg chaos.Config
+	if config.Chaos != nil {
+		cfg = *config.Chaos
+	}
+	if len(config.ChaosRules) > 0 {
+		cfg.Enabled = true
+		cfg.Rules = append(append([]chaos.Rule(nil), cfg.Rules...), config.ChaosRules...)
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = DefaultChaosSeed
+	}
+	if len(cfg.ErrorTypes) == 0 {
+		cfg.ErrorTypes = []int{503, 500, 429}
+		cfg.ErrorWeights = []int{3, 2, 1}
+	}
+	return &cfg
+}
+
+// openStorage opens the given storage backend for the duration of a test
+func openStorage(t testing.TB, backend string) storage.Repositories {
+	t.Helper()
+
+	var dsn string
+	switch backend {
+	case "":
+		backend = StorageMemory
+	case StorageSQLite:
+		dsn = "file:" + filepath.Join(t.TempDir(), "nah.db") + "?_busy_timeout=5000&_fk=1"
+	}
+	store, err := storage.Open(backend, dsn)
+	if err != nil {
+		t.Fatalf("nahtest: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store.Repositories
+}
@@ -0,0 +1,184 @@
+package nahtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/hypertf/nahcloud/pkg/client"
+	"github.com/hypertf/nahcloud/service"
+	"github.com/hypertf/nahcloud/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer(t *testing.T) {
+	for _, storage := range []string{StorageMemory, StorageSQLite} {
+		t.Run(storage, func(t *testing.T) {
+			srv := NewServer(t, Config{
+				Storage: storage,
+				Seed: func(svc *service.Service) error {
+					_, err := svc.CreateProject(domain.CreateProjectRequest{Name: "seeded"})
+					return err
+				},
+			})
+			ctx := context.Background()
+
+			projects, err := srv.Client.ListProjects(ctx, domain.ProjectListOptions{})
+			require.NoError(t, err)
+			require.Len(t, projects, 1)
+			assert.Equal(t, "seeded", projects[0].Name)
+
+			_, err = srv.Client.CreateInstance(ctx, domain.CreateInstanceRequest{
+				ProjectID: projects[0].ID, Name: "web", Region: "us-east-1", CPU: 1, MemoryMB: 512, Image: "ubuntu:22.04",
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewServer_Token(t *testing.T) {
+	srv := NewServer(t, Config{Token: "secret"})
+
+	_, err := srv.Client.ListProjects(context.Background(), domain.ProjectListOptions{})
+	require.NoError(t, err)
+
+	anonymous := client.NewClient(client.Config{BaseURL: srv.URL, RetryMax: 1})
+	_, err = anonymous.ListProjects(context.Background(), domain.ProjectListOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), domain.ErrorCodeUnauthorized)
+}
+
+func TestNewServer_ChaosRules(t *testing.T) {
+	srv := NewServer(t, Config{
+		ChaosRules: []chaos.Rule{{
+			Name:   "first-get-fails",
+			Match:  chaos.Match{Methods: []string{"GET"}, Resource: chaos.ResourceProjects, NthCall: 1},
+			Action: chaos.Action{Type: chaos.ActionError, Status: http.StatusServiceUnavailable},
+		}},
+	})
+
+	// The client retries past the injected 503
+	_, err := srv.Client.ListProjects(context.Background(), domain.ProjectListOptions{})
+	require.NoError(t, err)
+
+	journal := srv.Chaos.Journal(chaos.JournalFilter{})
+	require.Len(t, journal, 1)
+	assert.Equal(t, "first-get-fails", journal[0].Rule)
+}
+
+func TestNewServer_TFStateLockRace(t *testing.T) {
+	for _, storage := range []string{StorageMemory, StorageSQLite} {
+		t.Run(storage, func(t *testing.T) {
+			srv := NewServer(t, Config{Storage: storage})
+
+			const clients = 16
+			statuses := make([]int, clients)
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < clients; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					body := fmt.Sprintf(`{"ID":"lock-%d","Operation":"OperationTypeApply"}`, i)
+					req, err := http.NewRequest("LOCK", srv.URL+"/v1/tfstate/app", strings.NewReader(body))
+					if !assert.NoError(t, err) {
+						return
+					}
+					<-start
+					resp, err := srv.Server.Client().Do(req)
+					if !assert.NoError(t, err) {
+						return
+					}
+					resp.Body.Close()
+					statuses[i] = resp.StatusCode
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+
+			won := 0
+			for _, status := range statuses {
+				if status == http.StatusOK {
+					won++
+				} else {
+					assert.Equal(t, http.StatusLocked, status)
+				}
+			}
+			assert.Equal(t, 1, won, "exactly one concurrent LOCK succeeds")
+		})
+	}
+}
+
+func TestNewServer_TFStateLockAdmin(t *testing.T) {
+	srv := NewServer(t, Config{Token: "secret"})
+	ctx := context.Background()
+
+	req, err := http.NewRequest("LOCK", srv.URL+"/v1/tfstate/app", strings.NewReader(`{"ID":"ci-1","Who":"ci@runner","Operation":"OperationTypeApply"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := srv.Server.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	lock, err := srv.Client.GetTFStateLock(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, "ci-1", lock.ID)
+	assert.Equal(t, "ci@runner", lock.Who)
+
+	locks, err := srv.Client.ListTFStateLocks(ctx)
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	assert.Equal(t, "app", locks[0].StateID)
+	assert.Equal(t, "OperationTypeApply", locks[0].Lock.Operation)
+
+	released, err := srv.Client.ForceUnlockTFState(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, "ci-1", released.Lock.ID)
+
+	_, err = srv.Client.GetTFStateLock(ctx, "app")
+	assert.ErrorContains(t, err, domain.ErrorCodeNotFound)
+	_, err = srv.Client.ForceUnlockTFState(ctx, "app")
+	assert.ErrorContains(t, err, domain.ErrorCodeNotFound)
+}
+
+func TestNewServer_TFStateWorkspaces(t *testing.T) {
+	srv := NewServer(t, Config{
+		Seed: func(svc *service.Service) error {
+			for _, id := range []string{"app", "env:default", "env:prod", "env:staging"} {
+				if _, err := svc.SetTFState(id, `{"serial":3,"lineage":"abc"}`, ""); err != nil {
+					return err
+				}
+			}
+			_, _, err := svc.TryLockTFState("env:prod", `{"ID":"ci-1","Who":"ci@runner"}`)
+			return err
+		},
+	})
+	ctx := context.Background()
+
+	// Small pages make the client follow page tokens
+	states, err := srv.Client.ListTFStates(ctx, domain.TFStateListOptions{
+		Prefix:      "env:",
+		PageOptions: domain.PageOptions{PageSize: 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, states, 3)
+	assert.Equal(t, "env:default", states[0].ID)
+	assert.Equal(t, int64(3), states[0].Serial)
+	assert.Equal(t, "abc", states[0].Lineage)
+	assert.False(t, states[0].Locked)
+	assert.Equal(t, "env:prod", states[1].ID)
+	assert.True(t, states[1].Locked)
+	require.NotNil(t, states[1].Lock)
+	assert.Equal(t, "ci@runner", states[1].Lock.Lock.Who)
+	assert.Equal(t, "env:staging", states[2].ID)
+
+	all, err := srv.Client.ListTFStates(ctx, domain.TFStateListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// apiKeyPrefix marks NahCloud API key secrets
+const apiKeyPrefix = "nah_"
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	Create(key *domain.APIKey, keyHash string) error
+	GetByID(id string) (*domain.APIKey, error)
+	GetByHash(keyHash string) (*domain.APIKey, error)
+	List(opts domain.APIKeyListOptions) ([]*domain.APIKey, string, error)
+	Revoke(id string) (*domain.APIKey, error)
+}
+
+// CreateAPIKey creates a scoped API key and returns it with its secret
+// The secret is only available in the returned value.
+func (s *Service) CreateAPIKey(req domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error) {
+	if req.Name == "" {
+		return nil, domain.InvalidInputError("api key name cannot be empty", nil)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, domain.InvalidInputError("api key needs at least one scope", nil)
+	}
+	for _, scope := range req.Scopes {
+		if err := validateScope(scope); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.ProjectID != "" {
+		if _, err := s.projectRepo.GetByID(req.ProjectID); err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError("project", "id", req.ProjectID)
+			}
+			return nil, err
+		}
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, domain.InternalError("failed to generate api key")
+	}
+	raw := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := domain.APIKey{
+		ID:        id,
+		Name:      req.Name,
+		Prefix:    raw[:len(apiKeyPrefix)+8],
+		Scopes:    req.Scopes,
+		ProjectID: req.ProjectID,
+	}
+	if err := s.apiKeyRepo.Create(&key, hashAPIKey(raw)); err != nil {
+		return nil, err
+	}
+	return &domain.CreatedAPIKey{APIKey: key, Key: raw}, nil
+}
+
+// GetAPIKey retrieves an API key by ID
+func (s *Service) GetAPIKey(id string) (*domain.APIKey, error) {
+	return s.apiKeyRepo.GetByID(id)
+}
+
+// ListAPIKeys lists a page of API keys and returns the next page token
+func (s *Service) ListAPIKeys(opts domain.APIKeyListOptions) ([]*domain.APIKey, string, error) {
+	return s.apiKeyRepo.List(opts)
+}
+
+// RevokeAPIKey revokes an API key so it can no longer authenticate
+func (s *Service) RevokeAPIKey(id string) (*domain.APIKey, error) {
+	return s.apiKeyRepo.Revoke(id)
+}
+
+// AuthenticateAPIKey looks up the API key for a secret
+// It returns a not found error for secrets that aren't API keys and an
+// unauthorized error for revoked keys.
+func (s *Service) AuthenticateAPIKey(secret string) (*domain.APIKey, error) {
+	key, err := s.apiKeyRepo.GetByHash(hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, domain.UnauthorizedError("api key has been revoked")
+	}
+	return key, nil
+}
+
+// hashAPIKey returns the stored form of an API key secret
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateScope validates an API key scope
+func validateScope(scope string) error {
+	if scope == "*" {
+		return nil
+	}
+	resource, access, ok := strings.Cut(scope, ":")
+	if !ok || !slices.Contains(domain.ScopeResources, resource) ||
+		(access != domain.ScopeRead && access != domain.ScopeWrite && access != "*") {
+		return domain.InvalidInputError("invalid scope", map[string]interface{}{
+			"scope":     scope,
+			"format":    "<resource>:<read|write|*>",
+			"resources": domain.ScopeResources,
+		})
+	}
+	return nil
+}
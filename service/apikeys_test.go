@@ -0,0 +1,88 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys_CreateAndAuthenticate(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	created, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{
+		Name:      "ci",
+		Scopes:    []string{"projects:read", "instances:*"},
+		ProjectID: project.ID,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, "nah_"))
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	assert.Equal(t, project.ID, created.ProjectID)
+
+	key, err := svc.AuthenticateAPIKey(created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+	assert.Equal(t, []string{"projects:read", "instances:*"}, key.Scopes)
+
+	assert.True(t, key.Allows("projects", domain.ScopeRead))
+	assert.False(t, key.Allows("projects", domain.ScopeWrite))
+	assert.True(t, key.Allows("instances", domain.ScopeWrite))
+	assert.False(t, key.Allows("buckets", domain.ScopeRead))
+
+	_, err = svc.AuthenticateAPIKey("nah_not-a-key")
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestAPIKeys_Revoke(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	created, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{Name: "temp", Scopes: []string{"*"}})
+	require.NoError(t, err)
+
+	revoked, err := svc.RevokeAPIKey(created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+
+	_, err = svc.AuthenticateAPIKey(created.Key)
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeUnauthorized, err.(*domain.NahError).Code)
+
+	keys, _, err := svc.ListAPIKeys(domain.APIKeyListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keys, _, err = svc.ListAPIKeys(domain.APIKeyListOptions{IncludeRevoked: true})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].IsRevoked())
+
+	_, err = svc.RevokeAPIKey("missing")
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestAPIKeys_Validation(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	tests := []struct {
+		name string
+		req  domain.CreateAPIKeyRequest
+	}{
+		{name: "missing name", req: domain.CreateAPIKeyRequest{Scopes: []string{"*"}}},
+		{name: "no scopes", req: domain.CreateAPIKeyRequest{Name: "k"}},
+		{name: "unknown resource", req: domain.CreateAPIKeyRequest{Name: "k", Scopes: []string{"widgets:read"}}},
+		{name: "unknown access", req: domain.CreateAPIKeyRequest{Name: "k", Scopes: []string{"projects:admin"}}},
+		{name: "missing access", req: domain.CreateAPIKeyRequest{Name: "k", Scopes: []string{"projects"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateAPIKey(tt.req)
+			assert.True(t, domain.IsInvalidInput(err), "got %v", err)
+		})
+	}
+
+	_, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{Name: "k", Scopes: []string{"*"}, ProjectID: "missing"})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+}
@@ -0,0 +1,110 @@
+package chaos
+
+import (
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// currentConfig returns the configuration in effect. It must not be modified.
+func (c *ChaosService) currentConfig() *Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// snapshot returns the configuration in effect and the rules to evaluate, in order
+func (c *ChaosService) snapshot() (*Config, []*ruleState) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rules := make([]*ruleState, 0, len(c.rules)+len(c.legacy))
+	rules = append(rules, c.rules...)
+	rules = append(rules, c.legacy...)
+	return c.config, rules
+}
+
+// Config returns a copy of the configuration in effect
+func (c *ChaosService) Config() Config {
+	config := *c.currentConfig()
+	config.Rules = append([]Rule(nil), config.Rules...)
+	return config
+}
+
+// UpdateConfig replaces the configuration of a running service. Rule call counts
+// start over, and so do request ordinals if the seed changed.
+func (c *ChaosService) UpdateConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	config.Rules = append([]Rule(nil), config.Rules...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if config.Seed != c.config.Seed {
+		c.ordinal.Store(0)
+	}
+	c.config = &config
+	c.rules = compileRules(config.Rules)
+	c.legacy = compileRules(legacyRules(&config))
+	return nil
+}
+
+// AddRule appends a named rule to the configured rules
+func (c *ChaosService) AddRule(rule Rule) error {
+	if rule.Name == "" {
+		return domain.InvalidInputError("rule name is required", nil)
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.config.Rules {
+		if existing.Name == rule.Name {
+			return domain.AlreadyExistsError("chaos rule", "name", rule.Name)
+		}
+	}
+
+	// Copy on write so in-flight requests keep evaluating the rules they started with
+	config := *c.config
+	config.Rules = append(append([]Rule(nil), c.config.Rules...), rule)
+	c.config = &config
+	c.rules = append(append([]*ruleState(nil), c.rules...), &ruleState{Rule: rule})
+	return nil
+}
+
+// RemoveRule removes the configured rule with the given name
+func (c *ChaosService) RemoveRule(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, rule := range c.config.Rules {
+		if rule.Name != name {
+			continue
+		}
+
+		config := *c.config
+		config.Rules = append(append([]Rule(nil), c.config.Rules[:i]...), c.config.Rules[i+1:]...)
+		c.config = &config
+		c.rules = append(append([]*ruleState(nil), c.rules[:i]...), c.rules[i+1:]...)
+		return nil
+	}
+	return domain.NotFoundError("chaos rule", name)
+}
+
+// Reseed changes the seed fault decisions are derived from and restarts request
+// ordinals and rule call counts, so the faults seen since a reseed can be replayed
+func (c *ChaosService) Reseed(seed int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config := *c.config
+	config.Seed = seed
+	c.config = &config
+	c.rules = compileRules(config.Rules)
+	c.legacy = compileRules(legacyRules(&config))
+	c.ordinal.Store(0)
+	c.probes.Store(0)
+}
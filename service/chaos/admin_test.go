@@ -0,0 +1,126 @@
+package chaos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_UpdateConfig(t *testing.T) {
+	service := NewChaosServiceWithConfig(&Config{})
+
+	req := httptest.NewRequest("GET", "/v1/instances", nil)
+	assert.Equal(t, http.StatusOK, serveChaos(service, req).Code)
+
+	err := service.UpdateConfig(Config{
+		Enabled:            true,
+		InstancesErrorRate: 1.0,
+		ErrorTypes:         []int{503},
+		ErrorWeights:       []int{1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, serveChaos(service, req).Code)
+
+	config := service.Config()
+	assert.True(t, config.Enabled)
+	assert.Equal(t, 1.0, config.InstancesErrorRate)
+
+	t.Run("invalid config is rejected", func(t *testing.T) {
+		tests := []Config{
+			{InstancesErrorRate: 1.5},
+			{GlobalLatencyRange: &LatencyRange{Min: 100, Max: 10}},
+			{Rules: []Rule{{Action: Action{Type: "explode"}}}},
+			{Rules: []Rule{{Name: "twice", Action: Action{Type: ActionDrop}}, {Name: "twice", Action: Action{Type: ActionDrop}}}},
+		}
+		for _, config := range tests {
+			assert.Error(t, service.UpdateConfig(config))
+		}
+		assert.Equal(t, 1.0, service.Config().InstancesErrorRate)
+	})
+}
+
+func TestChaosService_AddRemoveRule(t *testing.T) {
+	service := newRuleChaosService()
+	req := httptest.NewRequest("GET", "/v1/projects", nil)
+
+	rule := Rule{
+		Name:   "teapot",
+		Match:  Match{Resource: ResourceProjects},
+		Action: Action{Type: ActionError, Status: http.StatusTeapot},
+	}
+	require.NoError(t, service.AddRule(rule))
+	assert.Equal(t, http.StatusTeapot, serveChaos(service, req).Code)
+	assert.Len(t, service.Config().Rules, 1)
+
+	err := service.AddRule(rule)
+	require.Error(t, err)
+	assert.True(t, domain.IsAlreadyExists(err))
+
+	err = service.AddRule(Rule{Action: Action{Type: ActionDrop}})
+	require.Error(t, err)
+	assert.True(t, domain.IsInvalidInput(err))
+
+	require.NoError(t, service.RemoveRule("teapot"))
+	assert.Equal(t, http.StatusOK, serveChaos(service, req).Code)
+	assert.Empty(t, service.Config().Rules)
+
+	err = service.RemoveRule("teapot")
+	require.Error(t, err)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestChaosService_Reseed(t *testing.T) {
+	service := newRuleChaosService(Rule{
+		Name:        "coin",
+		Match:       Match{Resource: ResourceProjects},
+		Action:      Action{Type: ActionError, Status: http.StatusServiceUnavailable},
+		Probability: 0.5,
+	})
+
+	codes := func() []int {
+		var codes []int
+		for i := 0; i < 20; i++ {
+			codes = append(codes, serveChaos(service, httptest.NewRequest("GET", "/v1/projects", nil)).Code)
+		}
+		return codes
+	}
+
+	service.Reseed(7)
+	first := codes()
+	assert.Contains(t, first, http.StatusOK)
+	assert.Contains(t, first, http.StatusServiceUnavailable)
+
+	// Ordinals restart, so the same sequence of faults is replayed
+	service.Reseed(7)
+	assert.Equal(t, first, codes())
+	assert.Equal(t, int64(7), service.Config().Seed)
+}
+
+func TestChaosService_ConcurrentUpdates(t *testing.T) {
+	service := newRuleChaosService()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				serveChaos(service, httptest.NewRequest("GET", "/v1/projects", nil))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				service.AddRule(Rule{Name: "slow", Action: Action{Type: ActionLatency, Latency: &LatencyRange{}}})
+				service.RemoveRule("slow")
+				service.Reseed(int64(j))
+			}
+		}()
+	}
+	wg.Wait()
+}
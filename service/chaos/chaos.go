@@ -7,45 +7,30 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/hypertf/nahcloud/domain"
 )
 
 // Config holds chaos engineering configuration
-type Config struct {
-	Enabled bool
-	Seed    int64
-	
-	// Global latency range in milliseconds
-	GlobalLatencyRange *LatencyRange
-	
-	// Per-resource latency ranges
-	ProjectsLatencyRange  *LatencyRange
-	InstancesLatencyRange *LatencyRange
-	MetadataLatencyRange  *LatencyRange
-	
-	// Per-resource error rates
-	ProjectsErrorRate    float64
-	ProjectsGetErrorRate float64
-	InstancesErrorRate   float64
-	MetadataErrorRate    float64
-	
-	// Error configuration
-	ErrorTypes   []int
-	ErrorWeights []int
-}
+type Config = domain.ChaosConfig
 
 // LatencyRange defines min-max latency in milliseconds
-type LatencyRange struct {
-	Min int
-	Max int
-}
+type LatencyRange = domain.ChaosLatencyRange
 
 // ChaosService provides chaos engineering capabilities
 type ChaosService struct {
-	config *Config
-	rng    *rand.Rand
+	mu      sync.RWMutex
+	config  *Config
+	rules   []*ruleState // configured rules, in order
+	legacy  []*ruleState // rules derived from the per-resource rates
+	ordinal atomic.Int64 // requests seen since the last reseed
+	probes  atomic.Int64 // readiness probes seen since the last reseed
+	journal *faultJournal
+	counts  faultCounts
 }
 
 // NewChaosService creates a new chaos service from environment variables
@@ -56,14 +41,11 @@ func NewChaosService() *ChaosService {
 
 // NewChaosServiceWithConfig creates a new chaos service with the provided config
 func NewChaosServiceWithConfig(config *Config) *ChaosService {
-	var rng *rand.Rand
-	if config.Enabled {
-		rng = rand.New(rand.NewSource(config.Seed))
-	}
-
 	return &ChaosService{
-		config: config,
-		rng:    rng,
+		config:  config,
+		rules:   compileRules(config.Rules),
+		legacy:  compileRules(legacyRules(config)),
+		journal: newFaultJournal(DefaultJournalSize),
 	}
 }
 
@@ -90,12 +72,48 @@ func loadConfigFromEnv() *Config {
 	if latency := getEnv("NAH_LATENCY_METADATA_MS", ""); latency != "" {
 		config.MetadataLatencyRange = parseLatencyRange(latency)
 	}
+	if latency := getEnv("NAH_LATENCY_BUCKETS_MS", ""); latency != "" {
+		config.BucketsLatencyRange = parseLatencyRange(latency)
+	}
+	if latency := getEnv("NAH_LATENCY_OBJECTS_MS", ""); latency != "" {
+		config.ObjectsLatencyRange = parseLatencyRange(latency)
+	}
+	if latency := getEnv("NAH_LATENCY_TFSTATE_MS", ""); latency != "" {
+		config.TFStateLatencyRange = parseLatencyRange(latency)
+	}
 	
 	// Load error rates
 	config.ProjectsErrorRate = getFloatEnv("NAH_ERRRATE_PROJECTS", 0.0)
 	config.ProjectsGetErrorRate = getFloatEnv("NAH_ERRRATE_PROJECTS_GET", config.ProjectsErrorRate)
 	config.InstancesErrorRate = getFloatEnv("NAH_ERRRATE_INSTANCES", 0.0)
 	config.MetadataErrorRate = getFloatEnv("NAH_ERRRATE_METADATA", 0.0)
+	config.BucketsErrorRate = getFloatEnv("NAH_ERRRATE_BUCKETS", 0.0)
+	config.ObjectsErrorRate = getFloatEnv("NAH_ERRRATE_OBJECTS", 0.0)
+	config.TFStateErrorRate = getFloatEnv("NAH_ERRRATE_TFSTATE", 0.0)
+	config.TFStateLockErrorRate = getFloatEnv("NAH_ERRRATE_TFSTATE_LOCK", config.TFStateErrorRate)
+	
+	// Load pagination fault rates
+	config.PageTruncateRate = getFloatEnv("NAH_CHAOS_PAGINATION_TRUNCATE_RATE", 0.0)
+	config.PageExpiredTokenRate = getFloatEnv("NAH_CHAOS_PAGINATION_EXPIRED_TOKEN_RATE", 0.0)
+	config.PageDuplicateRate = getFloatEnv("NAH_CHAOS_PAGINATION_DUPLICATE_RATE", 0.0)
+	config.PageSkipRate = getFloatEnv("NAH_CHAOS_PAGINATION_SKIP_RATE", 0.0)
+
+	// Load network fault rates
+	config.NetResetRate = getFloatEnv("NAH_NET_RESET_RATE", 0.0)
+	config.NetSlowBodyRate = getFloatEnv("NAH_NET_SLOW_BODY_RATE", 0.0)
+	config.NetTruncateRate = getFloatEnv("NAH_NET_TRUNCATE_RATE", 0.0)
+	config.NetTimeoutRate = getFloatEnv("NAH_NET_TIMEOUT_RATE", 0.0)
+
+	// Load access token fault rate
+	config.TokenExpiryRate = getFloatEnv("NAH_TOKEN_EXPIRY_RATE", 0.0)
+
+	// Load readiness fault rate
+	config.ReadinessFlapRate = getFloatEnv("NAH_READINESS_FLAP_RATE", 0.0)
+
+	// Load retry hints
+	config.RetryAfterSeconds = int(getIntEnv("NAH_RETRY_AFTER_SECONDS", 0))
+	config.RetryAfterFormat = getEnv("NAH_RETRY_AFTER_FORMAT", "")
+	config.RateLimitLimit = int(getIntEnv("NAH_RATE_LIMIT_LIMIT", 0))
 	
 	// Load error types and weights
 	if types := getEnv("NAH_ERROR_TYPES", ""); types != "" {
@@ -108,157 +126,92 @@ func loadConfigFromEnv() *Config {
 	return config
 }
 
-// ApplyProjectsChaos applies chaos to projects operations
-func (c *ChaosService) ApplyProjectsChaos(ctx context.Context, r *http.Request, method string) error {
-	if !c.config.Enabled {
-		return nil
-	}
-	
-	// Check for bypass header
-	if r.Header.Get("X-Nah-No-Chaos") == "true" {
-		return nil
-	}
-	
-	// Apply latency
-	c.applyLatency(ctx, r, c.config.ProjectsLatencyRange)
-	
-	// Apply error injection
-	errorRate := c.config.ProjectsErrorRate
-	if method == "GET" && c.config.ProjectsGetErrorRate > 0 {
-		errorRate = c.config.ProjectsGetErrorRate
-	}
-	
-	return c.maybeInjectError(errorRate)
-}
-
-// ApplyInstancesChaos applies chaos to instances operations
-func (c *ChaosService) ApplyInstancesChaos(ctx context.Context, r *http.Request) error {
-	if !c.config.Enabled {
-		return nil
-	}
-	
-	// Check for bypass header
-	if r.Header.Get("X-Nah-No-Chaos") == "true" {
-		return nil
-	}
-	
-	// Apply latency
-	c.applyLatency(ctx, r, c.config.InstancesLatencyRange)
-	
-	// Apply error injection
-	return c.maybeInjectError(c.config.InstancesErrorRate)
-}
-
-// ApplyMetadataChaos applies chaos to metadata operations
-func (c *ChaosService) ApplyMetadataChaos(ctx context.Context, r *http.Request) error {
-	if !c.config.Enabled {
-		return nil
+// sleep waits for the given number of milliseconds or until the request is cancelled
+func sleep(ctx context.Context, ms int) {
+	if ms <= 0 {
+		return
 	}
-	
-	// Check for bypass header
-	if r.Header.Get("X-Nah-No-Chaos") == "true" {
-		return nil
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+	case <-ctx.Done():
 	}
-	
-	// Apply latency
-	c.applyLatency(ctx, r, c.config.MetadataLatencyRange)
-	
-	// Apply error injection
-	return c.maybeInjectError(c.config.MetadataErrorRate)
 }
 
-// applyLatency applies latency injection
-func (c *ChaosService) applyLatency(ctx context.Context, r *http.Request, resourceRange *LatencyRange) {
-	// Check for forced latency header
-	if forcedLatency := r.Header.Get("X-Nah-Latency"); forcedLatency != "" {
-		if ms, err := strconv.Atoi(forcedLatency); err == nil && ms > 0 {
-			select {
-			case <-time.After(time.Duration(ms) * time.Millisecond):
-			case <-ctx.Done():
-			}
-			return
-		}
-	}
-	
-	// Determine latency range to use (resource-specific overrides global)
-	latencyRange := c.config.GlobalLatencyRange
-	if resourceRange != nil {
-		latencyRange = resourceRange
-	}
-	
+// randomLatency picks a latency in milliseconds within the range
+func randomLatency(rng *rand.Rand, latencyRange *LatencyRange) int {
 	if latencyRange == nil {
-		return
+		return 0
 	}
-	
-	// Calculate random latency within range
 	latency := latencyRange.Min
 	if latencyRange.Max > latencyRange.Min {
-		latency += c.rng.Intn(latencyRange.Max - latencyRange.Min + 1)
-	}
-	
-	if latency > 0 {
-		select {
-		case <-time.After(time.Duration(latency) * time.Millisecond):
-		case <-ctx.Done():
-		}
+		latency += rng.Intn(latencyRange.Max - latencyRange.Min + 1)
 	}
+	return latency
 }
 
-// maybeInjectError randomly injects an error based on error rate
-func (c *ChaosService) maybeInjectError(errorRate float64) error {
-	if errorRate <= 0.0 || c.rng.Float64() > errorRate {
-		return nil
-	}
-	
-	// Select error type based on weights
-	errorCode := c.selectWeightedErrorType()
-	
-	switch errorCode {
-	case 429:
+// errorForStatus builds the error body served with an injected HTTP status
+func errorForStatus(status int) *domain.NahError {
+	switch status {
+	case http.StatusTooManyRequests:
 		return domain.TooManyRequestsError("chaos: rate limited")
-	case 500:
+	case http.StatusInternalServerError:
 		return domain.InternalError("chaos: internal server error")
-	case 503:
+	case http.StatusServiceUnavailable:
 		return domain.ServiceUnavailableError("chaos: service unavailable")
-	default:
+	}
+
+	text := http.StatusText(status)
+	if text == "" {
 		return domain.InternalError("chaos: unknown error")
 	}
+	code := strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ' || r == '-':
+			return '_'
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, text)
+	return domain.NewError(code, "chaos: "+strings.ToLower(text))
 }
 
 // selectWeightedErrorType selects an error type based on configured weights
-func (c *ChaosService) selectWeightedErrorType() int {
-	if len(c.config.ErrorTypes) == 0 {
+func (c *ChaosService) selectWeightedErrorType(rng *rand.Rand) int {
+	config := c.currentConfig()
+
+	if len(config.ErrorTypes) == 0 {
 		return 500
 	}
 	
-	if len(c.config.ErrorWeights) != len(c.config.ErrorTypes) {
+	if len(config.ErrorWeights) != len(config.ErrorTypes) {
 		// If weights don't match types, use uniform distribution
-		return c.config.ErrorTypes[c.rng.Intn(len(c.config.ErrorTypes))]
+		return config.ErrorTypes[rng.Intn(len(config.ErrorTypes))]
 	}
 	
 	// Calculate total weight
 	totalWeight := 0
-	for _, weight := range c.config.ErrorWeights {
+	for _, weight := range config.ErrorWeights {
 		totalWeight += weight
 	}
 	
 	if totalWeight == 0 {
-		return c.config.ErrorTypes[0]
+		return config.ErrorTypes[0]
 	}
 	
 	// Select based on weights
-	target := c.rng.Intn(totalWeight)
+	target := rng.Intn(totalWeight)
 	currentWeight := 0
 	
-	for i, weight := range c.config.ErrorWeights {
+	for i, weight := range config.ErrorWeights {
 		currentWeight += weight
 		if target < currentWeight {
-			return c.config.ErrorTypes[i]
+			return config.ErrorTypes[i]
 		}
 	}
 	
 	// Fallback
-	return c.config.ErrorTypes[0]
+	return config.ErrorTypes[0]
 }
 
 // Utility functions for parsing environment variables
@@ -339,4 +292,4 @@ func parseIntList(value string) []int {
 	}
 	
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,151 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// DefaultJournalSize is the number of injected faults the journal keeps
+const DefaultJournalSize = 1000
+
+// JournalEntry records a single injected fault
+type JournalEntry = domain.ChaosJournalEntry
+
+// JournalFilter selects entries from the fault journal
+type JournalFilter = domain.ChaosJournalFilter
+
+// faultJournal keeps the most recent injected faults in a ring buffer
+type faultJournal struct {
+	mu       sync.Mutex
+	entries  []JournalEntry
+	next     int // position the next entry is written to once the ring is full
+	sequence int64
+}
+
+func newFaultJournal(size int) *faultJournal {
+	return &faultJournal{entries: make([]JournalEntry, 0, size)}
+}
+
+// record appends an entry, assigning its sequence number and time
+func (j *faultJournal) record(entry JournalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.sequence++
+	entry.Sequence = j.sequence
+	entry.Time = time.Now().UTC()
+
+	if len(j.entries) < cap(j.entries) {
+		j.entries = append(j.entries, entry)
+		return
+	}
+	j.entries[j.next] = entry
+	j.next = (j.next + 1) % len(j.entries)
+}
+
+// list returns the entries matching filter, oldest first
+func (j *faultJournal) list(filter JournalFilter) []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	matched := []JournalEntry{}
+	for i := range j.entries {
+		entry := j.entries[(j.next+i)%len(j.entries)]
+		if entry.Sequence <= filter.Since {
+			continue
+		}
+		if filter.Key != "" && entry.Key != filter.Key {
+			continue
+		}
+		if filter.Rule != "" && entry.Rule != filter.Rule {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[len(matched)-filter.Limit:]
+	}
+	return matched
+}
+
+// clear removes every entry. Sequence numbers keep increasing.
+func (j *faultJournal) clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = j.entries[:0]
+	j.next = 0
+}
+
+// Journal returns the recorded faults matching filter, oldest first
+func (c *ChaosService) Journal(filter JournalFilter) []JournalEntry {
+	return c.journal.list(filter)
+}
+
+// ClearJournal discards the recorded faults
+func (c *ChaosService) ClearJournal() {
+	c.journal.clear()
+}
+
+type faultTrackerKey struct{}
+
+// faultTracker collects the faults injected into a single request
+type faultTracker struct {
+	mu     sync.Mutex
+	faults []string
+}
+
+// TrackFaults returns a request whose injected faults are collected, and a function
+// listing the faults injected into it so far. Access logs use it to report chaos.
+func TrackFaults(r *http.Request) (*http.Request, func() []string) {
+	tracker := &faultTracker{}
+	r = r.WithContext(context.WithValue(r.Context(), faultTrackerKey{}, tracker))
+	return r, func() []string {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		return append([]string(nil), tracker.faults...)
+	}
+}
+
+// faultCounts counts injected faults by type since the service started
+type faultCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// FaultCounts returns the number of faults injected since the service started, by
+// fault type. Unlike the journal, the counts are never cleared.
+func (c *ChaosService) FaultCounts() map[string]int64 {
+	c.counts.mu.Lock()
+	defer c.counts.mu.Unlock()
+
+	counts := make(map[string]int64, len(c.counts.counts))
+	for fault, n := range c.counts.counts {
+		counts[fault] = n
+	}
+	return counts
+}
+
+// record journals and counts a fault injected into a request, and notes it on the
+// request's tracker
+func (c *ChaosService) record(r *http.Request, entry JournalEntry) {
+	c.journal.record(entry)
+
+	c.counts.mu.Lock()
+	if c.counts.counts == nil {
+		c.counts.counts = make(map[string]int64)
+	}
+	c.counts.counts[entry.Fault]++
+	c.counts.mu.Unlock()
+
+	if tracker, ok := r.Context().Value(faultTrackerKey{}).(*faultTracker); ok {
+		tracker.mu.Lock()
+		tracker.faults = append(tracker.faults, entry.Fault)
+		tracker.mu.Unlock()
+	}
+}
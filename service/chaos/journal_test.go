@@ -0,0 +1,122 @@
+package chaos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosKey_DeterministicAcrossOrder(t *testing.T) {
+	rule := Rule{
+		Name:        "coin",
+		Action:      Action{Type: ActionError},
+		Probability: 0.5,
+	}
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	outcomes := func(order []string) map[string]int {
+		service := newRuleChaosService(rule)
+		service.UpdateConfig(Config{Enabled: true, Seed: 99, ErrorTypes: []int{500, 503}, ErrorWeights: []int{1, 1}, Rules: []Rule{rule}})
+
+		codes := make(map[string]int)
+		for _, key := range order {
+			req := httptest.NewRequest("GET", "/v1/projects", nil)
+			req.Header.Set(ChaosKeyHeader, key)
+			rec := serveChaos(service, req)
+			assert.Equal(t, key, rec.Header().Get(ChaosKeyHeader))
+			codes[key] = rec.Code
+		}
+		return codes
+	}
+
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+	assert.Equal(t, outcomes(keys), outcomes(reversed))
+}
+
+func TestChaosKey_OrdinalWhenUnset(t *testing.T) {
+	service := newRuleChaosService()
+
+	for i := 1; i <= 3; i++ {
+		rec := serveChaos(service, httptest.NewRequest("GET", "/v1/projects", nil))
+		assert.Equal(t, fmt.Sprint(i), rec.Header().Get(ChaosKeyHeader))
+	}
+}
+
+func TestJournal_RecordsFaults(t *testing.T) {
+	service := newRuleChaosService(
+		Rule{Name: "slow", Match: Match{Resource: ResourceProjects}, Action: Action{Type: ActionLatency, Latency: &LatencyRange{Min: 1, Max: 1}}},
+		Rule{Name: "fail-create", Match: Match{Methods: []string{"POST"}}, Action: Action{Type: ActionError, Status: http.StatusBadGateway}},
+	)
+
+	req := httptest.NewRequest("POST", "/v1/projects", nil)
+	req.Header.Set(ChaosKeyHeader, "replay-me")
+	serveChaos(service, req)
+	serveChaos(service, httptest.NewRequest("GET", "/v1/instances", nil))
+
+	entries := service.Journal(JournalFilter{})
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "slow", entries[0].Rule)
+	assert.Equal(t, ActionLatency, entries[0].Fault)
+	assert.Equal(t, 1, entries[0].LatencyMS)
+
+	assert.Equal(t, "fail-create", entries[1].Rule)
+	assert.Equal(t, ActionError, entries[1].Fault)
+	assert.Equal(t, http.StatusBadGateway, entries[1].Status)
+	assert.Equal(t, "replay-me", entries[1].Key)
+	assert.Equal(t, int64(42), entries[1].Seed)
+	assert.Equal(t, "POST", entries[1].Method)
+	assert.Equal(t, "/v1/projects", entries[1].Path)
+	assert.Greater(t, entries[1].Sequence, entries[0].Sequence)
+
+	assert.Len(t, service.Journal(JournalFilter{Rule: "slow"}), 1)
+	assert.Len(t, service.Journal(JournalFilter{Key: "nope"}), 0)
+	assert.Len(t, service.Journal(JournalFilter{Since: entries[0].Sequence}), 1)
+
+	service.ClearJournal()
+	assert.Empty(t, service.Journal(JournalFilter{}))
+}
+
+func TestJournal_KeepsMostRecent(t *testing.T) {
+	journal := newFaultJournal(3)
+	for i := 1; i <= 5; i++ {
+		journal.record(JournalEntry{Key: fmt.Sprint(i)})
+	}
+
+	var keys []string
+	for _, entry := range journal.list(JournalFilter{}) {
+		keys = append(keys, entry.Key)
+	}
+	assert.Equal(t, []string{"3", "4", "5"}, keys)
+
+	limited := journal.list(JournalFilter{Limit: 1})
+	require.Len(t, limited, 1)
+	assert.Equal(t, int64(5), limited[0].Sequence)
+}
+
+func TestTrackFaults(t *testing.T) {
+	service := newRuleChaosService(
+		Rule{Name: "slow", Match: Match{Resource: ResourceProjects}, Action: Action{Type: ActionLatency, Latency: &LatencyRange{Min: 1, Max: 1}}},
+		Rule{Name: "fail", Match: Match{Resource: ResourceProjects}, Action: Action{Type: ActionError, Status: http.StatusServiceUnavailable}},
+	)
+
+	req, faults := TrackFaults(httptest.NewRequest("GET", "/v1/projects", nil))
+	rec := httptest.NewRecorder()
+	rec.Header().Set("X-Request-Id", "req-1")
+	service.Middleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+	assert.Equal(t, []string{ActionLatency, ActionError}, faults())
+	assert.Equal(t, map[string]int64{ActionLatency: 1, ActionError: 1}, service.FaultCounts())
+	assert.Contains(t, rec.Body.String(), `"request_id":"req-1"`, "injected errors echo the request ID")
+
+	req, faults = TrackFaults(httptest.NewRequest("GET", "/v1/instances", nil))
+	serveChaos(service, req)
+	assert.Empty(t, faults())
+}
@@ -0,0 +1,122 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Middleware evaluates the chaos rules against each request before it reaches the handler
+func (c *ChaosService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config, rules := c.snapshot()
+		if !config.Enabled || r.Header.Get("X-Nah-No-Chaos") == "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Handlers reuse the request's decision state for pagination faults
+		state := c.forRequest(r, config.Seed)
+		r = r.WithContext(context.WithValue(r.Context(), requestChaosKey{}, state))
+		w.Header().Set(ChaosKeyHeader, state.key)
+
+		rule := c.evaluate(r, rules, state)
+		if rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entry := state.entry(r, JournalEntry{Rule: rule.Name, Fault: rule.Action.Type})
+		switch rule.Action.Type {
+		case ActionError:
+			entry.Status = c.writeFault(w, config, rule.Action, state.rng)
+			c.record(r, entry)
+		case ActionDrop:
+			// The server closes the connection without writing a response
+			c.record(r, entry)
+			panic(http.ErrAbortHandler)
+		case ActionPartial:
+			c.record(r, entry)
+			servePartial(w, r, next, rule.Action.PartialBytes)
+		case ActionReset:
+			c.record(r, entry)
+			resetConnection(w)
+		case ActionSlowBody:
+			c.record(r, entry)
+			serveSlow(w, r, next, rule.Action.ChunkBytes, rule.Action.ChunkDelayMS)
+		case ActionTruncate:
+			c.record(r, entry)
+			serveTruncated(w, r, next, rule.Action.PartialBytes)
+		case ActionTimeout:
+			c.record(r, entry)
+			hang(r, rule.Action.TimeoutMS)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// evaluate applies the latency of every matching latency rule and returns the first
+// matching rule that replaces the response, or nil if the request should be served
+func (c *ChaosService) evaluate(r *http.Request, rules []*ruleState, state *requestChaos) *Rule {
+	ctx := r.Context()
+
+	// A forced latency header replaces any latency rules
+	forced := 0
+	if value := r.Header.Get("X-Nah-Latency"); value != "" {
+		if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+			forced = ms
+			sleep(ctx, forced)
+		}
+	}
+
+	resource := domain.ResourceForPath(r.URL.Path)
+	for _, rule := range rules {
+		if !rule.matches(r, resource) {
+			continue
+		}
+		if rule.Probability > 0 && !roll(state.rng, rule.Probability) {
+			continue
+		}
+		if rule.Action.Type == ActionLatency {
+			// Draw the latency even when it is overridden, so later decisions don't shift
+			latency := randomLatency(state.rng, rule.Action.Latency)
+			if forced == 0 {
+				c.record(r, state.entry(r, JournalEntry{Rule: rule.Name, Fault: ActionLatency, LatencyMS: latency}))
+				sleep(ctx, latency)
+			}
+			continue
+		}
+		return &rule.Rule
+	}
+	return nil
+}
+
+// writeFault writes the response of an error action and returns its status
+func (c *ChaosService) writeFault(w http.ResponseWriter, config *Config, action Action, rng *rand.Rand) int {
+	status := action.Status
+	if status == 0 {
+		status = c.selectWeightedErrorType(rng)
+	}
+
+	body := []byte(action.Body)
+	if len(body) == 0 {
+		body, _ = json.Marshal(errorForStatus(status).WithRequestID(w.Header().Get(domain.RequestIDHeader)))
+		body = append(body, '\n')
+	}
+
+	if json.Valid(body) {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	setRetryHeaders(w.Header(), config, action, status, time.Now())
+	w.WriteHeader(status)
+	w.Write(body)
+	return status
+}
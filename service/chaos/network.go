@@ -0,0 +1,146 @@
+package chaos
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// servePartial serves the request but sends only the first n bytes of the body,
+// then cuts the connection. The full Content-Length is advertised so clients see
+// an unexpected end of the body rather than a short, valid response.
+func servePartial(w http.ResponseWriter, r *http.Request, next http.Handler, n int) {
+	buffered := bufferResponse(r, next)
+	body := buffered.body.Bytes()
+	n = cutPoint(n, len(body))
+
+	buffered.copyHeader(w)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(buffered.status)
+	w.Write(body[:n])
+	flush(w)
+	panic(http.ErrAbortHandler)
+}
+
+// serveTruncated serves the request with only the first n bytes of the body and a
+// Content-Length to match, so the response completes cleanly but does not parse
+func serveTruncated(w http.ResponseWriter, r *http.Request, next http.Handler, n int) {
+	buffered := bufferResponse(r, next)
+	body := buffered.body.Bytes()
+	n = cutPoint(n, len(body))
+
+	buffered.copyHeader(w)
+	w.Header().Set("Content-Length", strconv.Itoa(n))
+	w.WriteHeader(buffered.status)
+	w.Write(body[:n])
+}
+
+// serveSlow serves the request but writes the body in chunks with a delay between
+// them. It stops early if the client goes away.
+func serveSlow(w http.ResponseWriter, r *http.Request, next http.Handler, chunkBytes, delayMS int) {
+	if chunkBytes <= 0 {
+		chunkBytes = DefaultChunkBytes
+	}
+	if delayMS <= 0 {
+		delayMS = DefaultChunkDelayMS
+	}
+
+	buffered := bufferResponse(r, next)
+	body := buffered.body.Bytes()
+
+	buffered.copyHeader(w)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(buffered.status)
+	for len(body) > 0 {
+		n := min(chunkBytes, len(body))
+		if _, err := w.Write(body[:n]); err != nil {
+			return
+		}
+		flush(w)
+		body = body[n:]
+
+		if len(body) > 0 {
+			sleep(r.Context(), delayMS)
+			if r.Context().Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// resetConnection takes over the connection and closes it with a TCP reset, so the
+// client sees "connection reset by peer" instead of a response
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	// Discarding unsent data on close makes the kernel send RST rather than FIN
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// hang holds the request without responding until the timeout elapses or the
+// client gives up, then closes the connection
+func hang(r *http.Request, timeoutMS int) {
+	if timeoutMS <= 0 {
+		timeoutMS = DefaultTimeoutMS
+	}
+	sleep(r.Context(), timeoutMS)
+	panic(http.ErrAbortHandler)
+}
+
+// cutPoint returns how many of size body bytes to send, defaulting to half
+func cutPoint(n, size int) int {
+	if n <= 0 || n >= size {
+		return size / 2
+	}
+	return n
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// bufferedResponse captures a handler's response so it can be replayed
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// bufferResponse runs the handler and captures its response
+func bufferResponse(r *http.Request, next http.Handler) *bufferedResponse {
+	buffered := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+	next.ServeHTTP(buffered, r)
+	return buffered
+}
+
+// copyHeader copies the captured headers to w
+func (b *bufferedResponse) copyHeader(w http.ResponseWriter) {
+	for name, values := range b.header {
+		w.Header()[name] = values
+	}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
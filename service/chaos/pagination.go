@@ -0,0 +1,97 @@
+package chaos
+
+import (
+	"math/rand"
+	"net/http"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Pagination fault types
+const (
+	PageFaultTruncate     = "truncate"
+	PageFaultExpiredToken = "expired_token"
+	PageFaultDuplicate    = "duplicate"
+	PageFaultSkip         = "skip"
+)
+
+// PageFetcher fetches one page of a listing and returns the next page token
+type PageFetcher[T any] func(page domain.PageOptions) ([]T, string, error)
+
+// ApplyPaginationChaos fetches a page of a listing, corrupting it according to the
+// configured pagination fault rates. At most one fault is applied per request.
+func ApplyPaginationChaos[T any](c *ChaosService, r *http.Request, page domain.PageOptions, fetch PageFetcher[T]) ([]T, string, error) {
+	config := c.currentConfig()
+	if !config.Enabled || page.PageSize <= 0 || r.Header.Get("X-Nah-No-Chaos") == "true" {
+		return fetch(page)
+	}
+
+	state := c.forRequest(r, config.Seed)
+	fault := selectPageFault(state.rng, config, page)
+	if fault != "" {
+		c.record(r, state.entry(r, JournalEntry{Fault: "page_" + fault}))
+	}
+
+	switch fault {
+	case PageFaultExpiredToken:
+		return nil, "", domain.ExpiredPageTokenError(page.PageToken)
+
+	case PageFaultTruncate:
+		// Fewer items than requested, with a next token that still resumes correctly
+		page.PageSize = 1 + state.rng.Intn(page.PageSize-1)
+		return fetch(page)
+
+	case PageFaultDuplicate:
+		// Hand out the token for one item earlier, so the next page repeats the last item
+		items, next, err := fetch(page)
+		if err != nil || next == "" || len(items) < 2 {
+			return items, next, err
+		}
+		shorter := page
+		shorter.PageSize = len(items) - 1
+		if _, next, err = fetch(shorter); err != nil {
+			return nil, "", err
+		}
+		return items, next, nil
+
+	case PageFaultSkip:
+		// Hand out the token for one item later, so the next page misses an item.
+		// The last page is served as is, since without a next page to resume from
+		// the skipped item would silently end the listing early.
+		longer := page
+		longer.PageSize++
+		items, next, err := fetch(longer)
+		if err != nil || len(items) <= page.PageSize {
+			return items, next, err
+		}
+		if next == "" {
+			return fetch(page)
+		}
+		return items[:page.PageSize], next, nil
+	}
+
+	return fetch(page)
+}
+
+// selectPageFault picks the pagination fault to apply to a list request, or "" for none
+func selectPageFault(rng *rand.Rand, config *Config, page domain.PageOptions) string {
+	// Only continuation requests carry a token that can expire
+	if page.PageToken != "" && roll(rng, config.PageExpiredTokenRate) {
+		return PageFaultExpiredToken
+	}
+	if page.PageSize > 1 && roll(rng, config.PageTruncateRate) {
+		return PageFaultTruncate
+	}
+	if page.PageSize > 1 && roll(rng, config.PageDuplicateRate) {
+		return PageFaultDuplicate
+	}
+	if roll(rng, config.PageSkipRate) {
+		return PageFaultSkip
+	}
+	return ""
+}
+
+// roll reports whether an event with the given probability occurs
+func roll(rng *rand.Rand, rate float64) bool {
+	return rate > 0.0 && rng.Float64() < rate
+}
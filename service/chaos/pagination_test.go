@@ -0,0 +1,107 @@
+package chaos
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceFetcher pages through items using the index of the next item as the token
+func sliceFetcher(items []string) PageFetcher[string] {
+	return func(page domain.PageOptions) ([]string, string, error) {
+		start := 0
+		if page.PageToken != "" {
+			start, _ = strconv.Atoi(page.PageToken)
+		}
+		end := start + page.PageSize
+		if end >= len(items) {
+			return items[start:], "", nil
+		}
+		return items[start:end], strconv.Itoa(end), nil
+	}
+}
+
+func newPaginationChaosService(config *Config) *ChaosService {
+	config.Enabled = true
+	config.Seed = 42
+	return NewChaosServiceWithConfig(config)
+}
+
+func TestApplyPaginationChaos(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e", "f"}
+	req, _ := http.NewRequest("GET", "/v1/projects", nil)
+
+	t.Run("no faults", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{})
+		page, next, err := ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 3}, sliceFetcher(items))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, page)
+		assert.Equal(t, "3", next)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{PageTruncateRate: 1.0})
+		page, next, err := ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 3}, sliceFetcher(items))
+		require.NoError(t, err)
+		assert.Less(t, len(page), 3)
+		assert.NotEmpty(t, page)
+		assert.Equal(t, strconv.Itoa(len(page)), next)
+	})
+
+	t.Run("expired token only on continuation", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{PageExpiredTokenRate: 1.0})
+		_, next, err := ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 3}, sliceFetcher(items))
+		require.NoError(t, err)
+
+		_, _, err = ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 3, PageToken: next}, sliceFetcher(items))
+		require.Error(t, err)
+		assert.True(t, domain.IsInvalidInput(err))
+	})
+
+	t.Run("duplicate across boundary", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{PageDuplicateRate: 1.0})
+		page, next, err := ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 3}, sliceFetcher(items))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, page)
+
+		nextPage, _, err := sliceFetcher(items)(domain.PageOptions{PageSize: 3, PageToken: next})
+		require.NoError(t, err)
+		assert.Equal(t, "c", nextPage[0])
+	})
+
+	t.Run("skip across boundary", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{PageSkipRate: 1.0})
+		page, next, err := ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 3}, sliceFetcher(items))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, page)
+
+		nextPage, _, err := sliceFetcher(items)(domain.PageOptions{PageSize: 3, PageToken: next})
+		require.NoError(t, err)
+		assert.Equal(t, "e", nextPage[0])
+	})
+
+	t.Run("skip leaves the last page intact", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{PageSkipRate: 1.0})
+		page, next, err := ApplyPaginationChaos(service, req, domain.PageOptions{PageSize: 5}, sliceFetcher(items))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, page)
+
+		nextPage, next, err := sliceFetcher(items)(domain.PageOptions{PageSize: 5, PageToken: next})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"f"}, nextPage)
+		assert.Empty(t, next)
+	})
+
+	t.Run("bypass header", func(t *testing.T) {
+		service := newPaginationChaosService(&Config{PageTruncateRate: 1.0})
+		bypass, _ := http.NewRequest("GET", "/v1/projects", nil)
+		bypass.Header.Set("X-Nah-No-Chaos", "true")
+		page, _, err := ApplyPaginationChaos(service, bypass, domain.PageOptions{PageSize: 3}, sliceFetcher(items))
+		require.NoError(t, err)
+		assert.Len(t, page, 3)
+	})
+}
@@ -0,0 +1,36 @@
+package chaos
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+)
+
+// FaultReadinessFlap is journaled when a readiness probe is failed by chaos
+const FaultReadinessFlap = "readiness_flap"
+
+// FailReadiness reports whether a readiness probe should report the server as not
+// ready, according to the configured readiness flap rate. Probes are keyed apart
+// from API requests, so polling readiness doesn't shift the faults requests see.
+func (c *ChaosService) FailReadiness(r *http.Request) bool {
+	config := c.currentConfig()
+	if !config.Enabled || config.ReadinessFlapRate <= 0 || r.Header.Get("X-Nah-No-Chaos") == "true" {
+		return false
+	}
+
+	key := r.Header.Get(ChaosKeyHeader)
+	if key == "" {
+		key = "readyz:" + strconv.FormatInt(c.probes.Add(1), 10)
+	}
+	state := &requestChaos{
+		seed: config.Seed,
+		key:  key,
+		rng:  rand.New(rand.NewSource(deriveSeed(config.Seed, key))),
+	}
+
+	if !roll(state.rng, config.ReadinessFlapRate) {
+		return false
+	}
+	c.record(r, state.entry(r, JournalEntry{Fault: FaultReadinessFlap}))
+	return true
+}
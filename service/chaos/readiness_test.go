@@ -0,0 +1,43 @@
+package chaos
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailReadiness(t *testing.T) {
+	service := NewChaosServiceWithConfig(&Config{Enabled: true, Seed: 42, ReadinessFlapRate: 0.5})
+
+	probe := func() bool {
+		return service.FailReadiness(httptest.NewRequest("GET", "/readyz", nil))
+	}
+
+	var first []bool
+	for i := 0; i < 20; i++ {
+		first = append(first, probe())
+	}
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false, "readiness flaps rather than failing outright")
+
+	journal := service.Journal(JournalFilter{})
+	for _, entry := range journal {
+		assert.Equal(t, FaultReadinessFlap, entry.Fault)
+		assert.Equal(t, "/readyz", entry.Path)
+	}
+
+	// Probes don't consume request ordinals, and replay after a reseed
+	assert.Equal(t, int64(0), service.ordinal.Load())
+	service.Reseed(42)
+	var replayed []bool
+	for i := 0; i < 20; i++ {
+		replayed = append(replayed, probe())
+	}
+	assert.Equal(t, first, replayed)
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	req.Header.Set("X-Nah-No-Chaos", "true")
+	assert.False(t, NewChaosServiceWithConfig(&Config{Enabled: true, ReadinessFlapRate: 1.0}).FailReadiness(req))
+	assert.False(t, NewChaosServiceWithConfig(&Config{Enabled: false, ReadinessFlapRate: 1.0}).FailReadiness(httptest.NewRequest("GET", "/readyz", nil)))
+}
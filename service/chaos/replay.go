@@ -0,0 +1,67 @@
+package chaos
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strconv"
+)
+
+// ChaosKeyHeader names the header carrying a request's chaos key. Clients may set it
+// to choose the key; responses always carry the key that was used.
+const ChaosKeyHeader = "X-Nah-Chaos-Key"
+
+// requestChaos holds the fault decision state of a single request. Every random
+// decision for the request is drawn from a generator derived from the seed and the
+// request's key, so the same seed and key always produce the same faults no matter
+// how requests interleave.
+type requestChaos struct {
+	seed int64
+	key  string
+	rng  *rand.Rand
+
+	tokenExpired *bool // access token fault decision, once made
+}
+
+type requestChaosKey struct{}
+
+// forRequest returns the fault decision state of a request, creating it if the
+// chaos middleware has not already done so
+func (c *ChaosService) forRequest(r *http.Request, seed int64) *requestChaos {
+	if state, ok := r.Context().Value(requestChaosKey{}).(*requestChaos); ok {
+		return state
+	}
+
+	// Without a client-supplied key, requests are keyed by their ordinal since the last reseed
+	key := r.Header.Get(ChaosKeyHeader)
+	if key == "" {
+		key = strconv.FormatInt(c.ordinal.Add(1), 10)
+	}
+
+	return &requestChaos{
+		seed: seed,
+		key:  key,
+		rng:  rand.New(rand.NewSource(deriveSeed(seed, key))),
+	}
+}
+
+// entry fills in the request details of a journal entry
+func (s *requestChaos) entry(r *http.Request, entry JournalEntry) JournalEntry {
+	entry.Seed = s.seed
+	entry.Key = s.key
+	entry.Method = r.Method
+	entry.Path = r.URL.Path
+	return entry
+}
+
+// deriveSeed combines the configured seed with a request key
+func deriveSeed(seed int64, key string) int64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(seed))
+
+	h := fnv.New64a()
+	h.Write(buf[:])
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
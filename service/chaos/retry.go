@@ -0,0 +1,53 @@
+package chaos
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Retry-After formats
+const (
+	RetryAfterFormatSeconds = domain.RetryAfterFormatSeconds
+	RetryAfterFormatDate    = domain.RetryAfterFormatDate
+)
+
+// setRetryHeaders adds the Retry-After and X-RateLimit-* headers a real service
+// would send with a 429 or 503. The action's retry hints take precedence over
+// the configuration's.
+func setRetryHeaders(h http.Header, config *Config, action Action, status int, now time.Time) {
+	if status != http.StatusTooManyRequests && status != http.StatusServiceUnavailable {
+		return
+	}
+
+	seconds := action.RetryAfterSeconds
+	if seconds == 0 {
+		seconds = config.RetryAfterSeconds
+	}
+	format := action.RetryAfterFormat
+	if format == "" {
+		format = config.RetryAfterFormat
+	}
+	// HTTP-dates and reset timestamps have one second resolution; round up so
+	// clients that honour them never retry early
+	retryAt := now.Add(time.Duration(seconds) * time.Second)
+	if truncated := retryAt.Truncate(time.Second); truncated.Before(retryAt) {
+		retryAt = truncated.Add(time.Second)
+	}
+
+	if seconds > 0 {
+		if format == RetryAfterFormatDate {
+			h.Set("Retry-After", retryAt.UTC().Format(http.TimeFormat))
+		} else {
+			h.Set("Retry-After", strconv.Itoa(seconds))
+		}
+	}
+
+	if status == http.StatusTooManyRequests && config.RateLimitLimit > 0 {
+		h.Set("X-RateLimit-Limit", strconv.Itoa(config.RateLimitLimit))
+		h.Set("X-RateLimit-Remaining", "0")
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(retryAt.Unix(), 10))
+	}
+}
@@ -0,0 +1,106 @@
+package chaos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRetryHeaders(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name      string
+		config    Config
+		action    Action
+		status    int
+		want      map[string]string
+		wantEmpty bool
+	}{
+		{
+			name:   "seconds",
+			config: Config{RetryAfterSeconds: 30},
+			status: http.StatusServiceUnavailable,
+			want:   map[string]string{"Retry-After": "30"},
+		},
+		{
+			name:   "http date rounds up",
+			config: Config{RetryAfterSeconds: 30, RetryAfterFormat: RetryAfterFormatDate},
+			status: http.StatusServiceUnavailable,
+			want:   map[string]string{"Retry-After": "Fri, 16 Oct 2026 12:00:31 GMT"},
+		},
+		{
+			name:   "action overrides config",
+			config: Config{RetryAfterSeconds: 30},
+			action: Action{RetryAfterSeconds: 5, RetryAfterFormat: RetryAfterFormatDate},
+			status: http.StatusTooManyRequests,
+			want:   map[string]string{"Retry-After": "Fri, 16 Oct 2026 12:00:06 GMT"},
+		},
+		{
+			name:   "rate limit headers",
+			config: Config{RetryAfterSeconds: 2, RateLimitLimit: 100},
+			status: http.StatusTooManyRequests,
+			want: map[string]string{
+				"Retry-After":           "2",
+				"X-RateLimit-Limit":     "100",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1792152003",
+			},
+		},
+		{
+			name:   "no rate limit headers on 503",
+			config: Config{RetryAfterSeconds: 2, RateLimitLimit: 100},
+			status: http.StatusServiceUnavailable,
+			want:   map[string]string{"Retry-After": "2", "X-RateLimit-Limit": ""},
+		},
+		{
+			name:      "other status",
+			config:    Config{RetryAfterSeconds: 2, RateLimitLimit: 100},
+			status:    http.StatusInternalServerError,
+			wantEmpty: true,
+		},
+		{
+			name:      "not configured",
+			status:    http.StatusTooManyRequests,
+			wantEmpty: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			setRetryHeaders(h, &tt.config, tt.action, tt.status, now)
+			if tt.wantEmpty {
+				assert.Empty(t, h)
+				return
+			}
+			for name, value := range tt.want {
+				assert.Equal(t, value, h.Get(name), name)
+			}
+		})
+	}
+}
+
+func TestMiddleware_RetryAfter(t *testing.T) {
+	service := NewChaosServiceWithConfig(&Config{
+		Enabled:           true,
+		Seed:              42,
+		RetryAfterSeconds: 3,
+		RetryAfterFormat:  RetryAfterFormatDate,
+		Rules: []Rule{{
+			Match:  Match{Resource: ResourceInstances},
+			Action: Action{Type: ActionError, Status: http.StatusServiceUnavailable},
+		}},
+	})
+
+	rec := serveChaos(service, httptest.NewRequest("GET", "/v1/instances", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	retryAt, err := http.ParseTime(rec.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(3*time.Second), retryAt, time.Second+100*time.Millisecond)
+}
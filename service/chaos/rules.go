@@ -0,0 +1,197 @@
+package chaos
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Rule action types
+const (
+	ActionLatency = domain.ChaosActionLatency
+	ActionError   = domain.ChaosActionError
+	ActionDrop    = domain.ChaosActionDrop
+	ActionPartial = domain.ChaosActionPartial
+
+	ActionReset    = domain.ChaosActionReset
+	ActionSlowBody = domain.ChaosActionSlowBody
+	ActionTruncate = domain.ChaosActionTruncate
+	ActionTimeout  = domain.ChaosActionTimeout
+)
+
+// Network fault defaults, used when a rule leaves the setting at zero
+const (
+	DefaultChunkBytes   = 16
+	DefaultChunkDelayMS = 100
+	DefaultTimeoutMS    = 30000
+)
+
+// Resource types a rule can match on
+const (
+	ResourceProjects   = "projects"
+	ResourceInstances  = "instances"
+	ResourceOperations = "operations"
+	ResourceMetadata   = "metadata"
+	ResourceBuckets    = "buckets"
+	ResourceObjects    = "objects"
+	ResourceTFState    = "tfstate"
+)
+
+// Rule injects a fault into API requests that match all of its conditions
+type Rule = domain.ChaosRule
+
+// Match selects the requests a rule applies to
+type Match = domain.ChaosMatch
+
+// Action describes the fault a rule injects
+type Action = domain.ChaosAction
+
+// ruleState tracks the calls a rule has matched
+type ruleState struct {
+	Rule
+	calls atomic.Int64
+}
+
+// compileRules prepares rules for evaluation
+func compileRules(rules []Rule) []*ruleState {
+	states := make([]*ruleState, len(rules))
+	for i, rule := range rules {
+		states[i] = &ruleState{Rule: rule}
+	}
+	return states
+}
+
+// matches reports whether a request meets every condition of the rule. A rule with
+// an Nth call condition counts each request that meets its other conditions.
+func (s *ruleState) matches(r *http.Request, resource string) bool {
+	m := s.Match
+
+	if len(m.Methods) > 0 && !containsFold(m.Methods, r.Method) {
+		return false
+	}
+	if m.Resource != "" && m.Resource != resource {
+		return false
+	}
+	if m.Path != "" && !matchPath(m.Path, r.URL.Path) {
+		return false
+	}
+	for name, value := range m.Headers {
+		got := r.Header.Get(name)
+		if got == "" || (value != "*" && got != value) {
+			return false
+		}
+	}
+
+	if m.NthCall > 0 {
+		return s.calls.Add(1) == int64(m.NthCall)
+	}
+	return true
+}
+
+// matchPath matches a URL path against a rule's path pattern
+func matchPath(pattern, urlPath string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		segments := strings.Split(urlPath, "/")
+		depth := strings.Count(prefix, "/") + 1
+		if len(segments) < depth {
+			return false
+		}
+		matched, _ := path.Match(prefix, strings.Join(segments[:depth], "/"))
+		return matched
+	}
+
+	matched, _ := path.Match(pattern, urlPath)
+	return matched
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyRules expresses the per-resource latency ranges, error rates and network
+// fault rates as rules
+func legacyRules(config *Config) []Rule {
+	var rules []Rule
+
+	latencies := []struct {
+		resource string
+		latency  *LatencyRange
+	}{
+		{ResourceProjects, config.ProjectsLatencyRange},
+		{ResourceInstances, config.InstancesLatencyRange},
+		{ResourceMetadata, config.MetadataLatencyRange},
+		{ResourceBuckets, config.BucketsLatencyRange},
+		{ResourceObjects, config.ObjectsLatencyRange},
+		{ResourceTFState, config.TFStateLatencyRange},
+	}
+	for _, l := range latencies {
+		// Resource-specific ranges override the global range
+		latency := l.latency
+		if latency == nil {
+			latency = config.GlobalLatencyRange
+		}
+		if latency == nil {
+			continue
+		}
+		rules = append(rules, Rule{
+			Name:   l.resource + "-latency",
+			Match:  Match{Resource: l.resource},
+			Action: Action{Type: ActionLatency, Latency: latency},
+		})
+	}
+
+	addErrorRule := func(name, resource string, methods []string, rate float64) {
+		if rate <= 0 {
+			return
+		}
+		rules = append(rules, Rule{
+			Name:        name,
+			Match:       Match{Resource: resource, Methods: methods},
+			Action:      Action{Type: ActionError},
+			Probability: rate,
+		})
+	}
+
+	projectsGetRate := config.ProjectsErrorRate
+	if config.ProjectsGetErrorRate > 0 {
+		projectsGetRate = config.ProjectsGetErrorRate
+	}
+	addErrorRule("projects-get-errors", ResourceProjects, []string{"GET"}, projectsGetRate)
+	addErrorRule("projects-errors", ResourceProjects, []string{"POST", "PATCH", "DELETE"}, config.ProjectsErrorRate)
+	addErrorRule("instances-errors", ResourceInstances, nil, config.InstancesErrorRate)
+	addErrorRule("metadata-errors", ResourceMetadata, nil, config.MetadataErrorRate)
+	addErrorRule("buckets-errors", ResourceBuckets, nil, config.BucketsErrorRate)
+	addErrorRule("objects-errors", ResourceObjects, nil, config.ObjectsErrorRate)
+	addErrorRule("tfstate-errors", ResourceTFState, []string{"GET", "POST", "DELETE"}, config.TFStateErrorRate)
+	addErrorRule("tfstate-lock-errors", ResourceTFState, []string{"LOCK", "UNLOCK"}, config.TFStateLockErrorRate)
+
+	network := []struct {
+		action string
+		rate   float64
+	}{
+		{ActionReset, config.NetResetRate},
+		{ActionSlowBody, config.NetSlowBodyRate},
+		{ActionTruncate, config.NetTruncateRate},
+		{ActionTimeout, config.NetTimeoutRate},
+	}
+	for _, n := range network {
+		if n.rate <= 0 {
+			continue
+		}
+		rules = append(rules, Rule{
+			Name:        "network-" + n.action,
+			Action:      Action{Type: n.action},
+			Probability: n.rate,
+		})
+	}
+
+	return rules
+}
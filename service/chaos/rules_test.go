@@ -0,0 +1,221 @@
+package chaos
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+func newRuleChaosService(rules ...Rule) *ChaosService {
+	return NewChaosServiceWithConfig(&Config{
+		Enabled:      true,
+		Seed:         42,
+		ErrorTypes:   []int{503},
+		ErrorWeights: []int{1},
+		Rules:        rules,
+	})
+}
+
+func TestRule_Matches(t *testing.T) {
+	tests := []struct {
+		name    string
+		match   Match
+		method  string
+		path    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "empty match", match: Match{}, method: "GET", path: "/v1/projects", want: true},
+		{name: "method", match: Match{Methods: []string{"post"}}, method: "POST", path: "/v1/projects", want: true},
+		{name: "other method", match: Match{Methods: []string{"POST"}}, method: "GET", path: "/v1/projects", want: false},
+		{name: "resource", match: Match{Resource: ResourceInstances}, method: "GET", path: "/v1/instances/i-1", want: true},
+		{name: "objects resource", match: Match{Resource: ResourceObjects}, method: "GET", path: "/v1/bucket/b/objects", want: true},
+		{name: "other resource", match: Match{Resource: ResourceBuckets}, method: "GET", path: "/v1/bucket/b/objects", want: false},
+		{name: "path segment glob", match: Match{Path: "/v1/instances/*"}, method: "GET", path: "/v1/instances/i-1", want: true},
+		{name: "path glob stops at segment", match: Match{Path: "/v1/instances/*"}, method: "POST", path: "/v1/instances/i-1/actions/stop", want: false},
+		{name: "path suffix glob", match: Match{Path: "/v1/instances/*/**"}, method: "POST", path: "/v1/instances/i-1/actions/stop", want: true},
+		{name: "header value", match: Match{Headers: map[string]string{"X-Team": "blue"}}, method: "GET", path: "/v1/projects", headers: map[string]string{"X-Team": "blue"}, want: true},
+		{name: "other header value", match: Match{Headers: map[string]string{"X-Team": "blue"}}, method: "GET", path: "/v1/projects", headers: map[string]string{"X-Team": "red"}, want: false},
+		{name: "header present", match: Match{Headers: map[string]string{"X-Team": "*"}}, method: "GET", path: "/v1/projects", headers: map[string]string{"X-Team": "red"}, want: true},
+		{name: "header missing", match: Match{Headers: map[string]string{"X-Team": "*"}}, method: "GET", path: "/v1/projects", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rule := &ruleState{Rule: Rule{Match: tt.match}}
+			assert.Equal(t, tt.want, rule.matches(req, domain.ResourceForPath(req.URL.Path)))
+		})
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "error", rule: Rule{Action: Action{Type: ActionError, Status: 502}}},
+		{name: "weighted error", rule: Rule{Action: Action{Type: ActionError}}},
+		{name: "latency", rule: Rule{Action: Action{Type: ActionLatency, Latency: &LatencyRange{Min: 10, Max: 20}}}},
+		{name: "drop", rule: Rule{Action: Action{Type: ActionDrop}}},
+		{name: "partial", rule: Rule{Action: Action{Type: ActionPartial, PartialBytes: 10}}},
+		{name: "reset", rule: Rule{Action: Action{Type: ActionReset}}},
+		{name: "slow", rule: Rule{Action: Action{Type: ActionSlowBody, ChunkBytes: 4, ChunkDelayMS: 50}}},
+		{name: "truncate", rule: Rule{Action: Action{Type: ActionTruncate}}},
+		{name: "timeout", rule: Rule{Action: Action{Type: ActionTimeout, TimeoutMS: 1000}}},
+		{name: "unknown action", rule: Rule{Action: Action{Type: "explode"}}, wantErr: true},
+		{name: "negative chunk size", rule: Rule{Action: Action{Type: ActionSlowBody, ChunkBytes: -1}}, wantErr: true},
+		{name: "negative timeout", rule: Rule{Action: Action{Type: ActionTimeout, TimeoutMS: -1}}, wantErr: true},
+		{name: "latency without range", rule: Rule{Action: Action{Type: ActionLatency}}, wantErr: true},
+		{name: "success status", rule: Rule{Action: Action{Type: ActionError, Status: 200}}, wantErr: true},
+		{name: "probability above one", rule: Rule{Probability: 1.5, Action: Action{Type: ActionDrop}}, wantErr: true},
+		{name: "bad path pattern", rule: Rule{Match: Match{Path: "/v1/[a"}, Action: Action{Type: ActionDrop}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware_ErrorRule(t *testing.T) {
+	service := newRuleChaosService(Rule{
+		Name:   "bad-gateway",
+		Match:  Match{Methods: []string{"POST"}, Resource: ResourceInstances},
+		Action: Action{Type: ActionError, Status: http.StatusBadGateway, Body: `{"error":"BAD_GATEWAY"}`},
+	})
+
+	rec := serveChaos(service, httptest.NewRequest("POST", "/v1/instances", nil))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"BAD_GATEWAY"}`, rec.Body.String())
+
+	rec = serveChaos(service, httptest.NewRequest("GET", "/v1/instances", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMiddleware_NthCall(t *testing.T) {
+	service := newRuleChaosService(Rule{
+		Match:  Match{Resource: ResourceProjects, NthCall: 3},
+		Action: Action{Type: ActionError, Status: http.StatusInternalServerError},
+	})
+
+	var codes []int
+	for i := 0; i < 5; i++ {
+		codes = append(codes, serveChaos(service, httptest.NewRequest("GET", "/v1/projects", nil)).Code)
+	}
+	assert.Equal(t, []int{200, 200, 500, 200, 200}, codes)
+}
+
+func TestMiddleware_RulesRunBeforeLegacyRates(t *testing.T) {
+	service := NewChaosServiceWithConfig(&Config{
+		Enabled:           true,
+		ProjectsErrorRate: 1.0,
+		ErrorTypes:        []int{503},
+		ErrorWeights:      []int{1},
+		Rules: []Rule{{
+			Match:  Match{Resource: ResourceProjects},
+			Action: Action{Type: ActionError, Status: http.StatusTeapot},
+		}},
+	})
+
+	rec := serveChaos(service, httptest.NewRequest("GET", "/v1/projects", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestMiddleware_NetworkFaults(t *testing.T) {
+	body := `{"items":[{"id":"p-1","name":"one"}]}`
+	service := newRuleChaosService(
+		Rule{Match: Match{Path: "/v1/drop"}, Action: Action{Type: ActionDrop}},
+		Rule{Match: Match{Path: "/v1/partial"}, Action: Action{Type: ActionPartial, PartialBytes: 10}},
+		Rule{Match: Match{Path: "/v1/reset"}, Action: Action{Type: ActionReset}},
+		Rule{Match: Match{Path: "/v1/truncate"}, Action: Action{Type: ActionTruncate, PartialBytes: 10}},
+		Rule{Match: Match{Path: "/v1/slow"}, Action: Action{Type: ActionSlowBody, ChunkBytes: 10, ChunkDelayMS: 10}},
+		Rule{Match: Match{Path: "/v1/timeout"}, Action: Action{Type: ActionTimeout, TimeoutMS: 5000}},
+	)
+	server := httptest.NewServer(service.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	})))
+	defer server.Close()
+
+	t.Run("drop", func(t *testing.T) {
+		_, err := http.Get(server.URL + "/v1/drop")
+		assert.Error(t, err)
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/v1/partial")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, body[:10], string(data))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		_, err := http.Get(server.URL + "/v1/reset")
+		assert.Error(t, err)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/v1/truncate")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body[:10], string(data))
+		assert.False(t, json.Valid(data))
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		start := time.Now()
+		resp, err := http.Get(server.URL + "/v1/slow")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(data))
+		// 38 bytes in 10 byte chunks means three pauses
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := &http.Client{Timeout: 50 * time.Millisecond}
+		_, err := client.Get(server.URL + "/v1/timeout")
+		require.Error(t, err)
+		assert.True(t, err.(interface{ Timeout() bool }).Timeout())
+	})
+
+	t.Run("unmatched", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/v1/projects")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(data))
+	})
+}
@@ -0,0 +1,33 @@
+package chaos
+
+import (
+	"net/http"
+)
+
+// FaultTokenExpired is journaled when a valid access token is rejected as expired
+const FaultTokenExpired = "token_expired"
+
+// ExpireTokenEarly reports whether a request's valid OAuth access token should be
+// rejected as expired, according to the configured token expiry rate. The decision
+// is made once per request, however often the token is checked. Requests outside
+// the chaos middleware, which carry no chaos state to keep the decision on, never
+// have their tokens expired.
+func (c *ChaosService) ExpireTokenEarly(r *http.Request) bool {
+	config := c.currentConfig()
+	if !config.Enabled || config.TokenExpiryRate <= 0 || r.Header.Get("X-Nah-No-Chaos") == "true" {
+		return false
+	}
+
+	state, ok := r.Context().Value(requestChaosKey{}).(*requestChaos)
+	if !ok {
+		return false
+	}
+	if state.tokenExpired == nil {
+		expired := roll(state.rng, config.TokenExpiryRate)
+		state.tokenExpired = &expired
+		if expired {
+			c.record(r, state.entry(r, JournalEntry{Fault: FaultTokenExpired}))
+		}
+	}
+	return *state.tokenExpired
+}
@@ -0,0 +1,34 @@
+package chaos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpireTokenEarly(t *testing.T) {
+	service := NewChaosServiceWithConfig(&Config{Enabled: true, Seed: 42, TokenExpiryRate: 1.0})
+
+	var decisions []bool
+	service.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decisions = append(decisions, service.ExpireTokenEarly(r), service.ExpireTokenEarly(r))
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/projects", nil))
+
+	assert.Equal(t, []bool{true, true}, decisions)
+	journal := service.Journal(JournalFilter{})
+	assert.Len(t, journal, 1, "the decision is journaled once per request")
+	assert.Equal(t, FaultTokenExpired, journal[0].Fault)
+
+	// Requests outside the chaos middleware have no state to keep a decision on
+	assert.False(t, service.ExpireTokenEarly(httptest.NewRequest("GET", "/v1/_admin/chaos", nil)))
+	assert.Len(t, service.Journal(JournalFilter{}), 1)
+
+	req := httptest.NewRequest("GET", "/v1/projects", nil)
+	req.Header.Set("X-Nah-No-Chaos", "true")
+	assert.False(t, service.ExpireTokenEarly(req))
+
+	service = NewChaosServiceWithConfig(&Config{Enabled: true, Seed: 42})
+	assert.False(t, service.ExpireTokenEarly(httptest.NewRequest("GET", "/v1/projects", nil)))
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// ValidInstanceActions is the list of supported instance actions
+var ValidInstanceActions = []string{
+	domain.InstanceActionStart,
+	domain.InstanceActionStop,
+	domain.InstanceActionReboot,
+	domain.InstanceActionResize,
+}
+
+// instanceActionRequiredStatus is the status an instance must be in for each action
+var instanceActionRequiredStatus = map[string]string{
+	domain.InstanceActionStart:  domain.StatusStopped,
+	domain.InstanceActionStop:   domain.StatusRunning,
+	domain.InstanceActionReboot: domain.StatusRunning,
+	domain.InstanceActionResize: domain.StatusStopped,
+}
+
+// InstanceAction performs a power or resize action on an instance and returns the resulting operation
+func (s *Service) InstanceAction(id string, action string, req domain.InstanceActionRequest) (*domain.Operation, error) {
+	required, ok := instanceActionRequiredStatus[action]
+	if !ok {
+		return nil, domain.InvalidInputError("invalid instance action", map[string]interface{}{
+			"valid_actions": ValidInstanceActions,
+			"actual":        action,
+		})
+	}
+
+	current, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Status != required {
+		return nil, instanceActionConflict(action, current.Status, required)
+	}
+
+	var update domain.UpdateInstanceRequest
+	switch action {
+	case domain.InstanceActionStart, domain.InstanceActionStop:
+		target := domain.StatusRunning
+		if action == domain.InstanceActionStop {
+			target = domain.StatusStopped
+		}
+		status, err := s.lifecycleStatus(current.Status, target)
+		if err != nil {
+			return nil, err
+		}
+		update.Status = &status
+	case domain.InstanceActionReboot:
+		status := domain.StatusRunning
+		if s.lifecycle.Enabled {
+			status = domain.StatusRebooting
+		}
+		update.Status = &status
+	case domain.InstanceActionResize:
+		if req.CPU == nil && req.MemoryMB == nil {
+			return nil, domain.InvalidInputError("resize requires cpu or memory_mb", nil)
+		}
+		cpu := current.CPU
+		memory := current.MemoryMB
+		if req.CPU != nil {
+			cpu = *req.CPU
+		}
+		if req.MemoryMB != nil {
+			memory = *req.MemoryMB
+		}
+		if err := validateInstanceSpecs(cpu, memory, current.Image); err != nil {
+			return nil, err
+		}
+		update.CPU = &cpu
+		update.MemoryMB = &memory
+	}
+
+	// Another action or the lifecycle worker may have changed the status since it was checked
+	instance, err := s.instanceRepo.UpdateIfStatus(id, required, update)
+	if err != nil {
+		if nahErr, ok := err.(*domain.NahError); ok && nahErr.Code == domain.ErrorCodeConflict {
+			status, _ := nahErr.Details["current_status"].(string)
+			return nil, instanceActionConflict(action, status, required)
+		}
+		return nil, err
+	}
+
+	opStatus := domain.OperationStatusDone
+	if domain.IsTransitionalStatus(instance.Status) {
+		opStatus = domain.OperationStatusRunning
+	}
+
+	op, err := s.newOperation("instance."+action, "instance", instance.ID, instance.ProjectID, opStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transitional actions finish once the lifecycle worker settles the instance
+	if opStatus == domain.OperationStatusRunning {
+		s.goBackground(func(ctx context.Context) {
+			s.finishOperation(op.ID, instance.ID, s.waitForInstanceSettled(ctx, instance.ID, false))
+		})
+	}
+
+	return op, nil
+}
+
+// instanceActionConflict reports that an instance isn't in the status an action requires
+func instanceActionConflict(action, current, required string) error {
+	return domain.ConflictError("cannot "+action+" instance while it is "+current, map[string]interface{}{
+		"action":          action,
+		"current_status":  current,
+		"required_status": required,
+	})
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestInstanceAction_StateMachine(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+	svc.SetLifecycleConfig(LifecycleConfig{})
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "api",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+	})
+	require.NoError(t, err)
+
+	// Running instances can't be started or resized
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionStart, domain.InstanceActionRequest{})
+	assert.True(t, domain.IsConflict(err))
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionResize, domain.InstanceActionRequest{CPU: intPtr(4)})
+	assert.True(t, domain.IsConflict(err))
+
+	op, err := svc.InstanceAction(instance.ID, domain.InstanceActionStop, domain.InstanceActionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "instance.stop", op.Kind)
+	assert.Equal(t, instance.ID, op.TargetID)
+	assert.Equal(t, domain.OperationStatusDone, op.Status)
+
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionReboot, domain.InstanceActionRequest{})
+	assert.True(t, domain.IsConflict(err))
+
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionResize, domain.InstanceActionRequest{})
+	assert.True(t, domain.IsInvalidInput(err))
+
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionResize, domain.InstanceActionRequest{CPU: intPtr(4), MemoryMB: intPtr(2048)})
+	require.NoError(t, err)
+
+	resized, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, resized.CPU)
+	assert.Equal(t, 2048, resized.MemoryMB)
+	assert.Equal(t, domain.StatusStopped, resized.Status)
+
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionStart, domain.InstanceActionRequest{})
+	require.NoError(t, err)
+
+	_, err = svc.InstanceAction(instance.ID, "hibernate", domain.InstanceActionRequest{})
+	assert.True(t, domain.IsInvalidInput(err))
+}
+
+func TestInstanceAction_RebootWithLifecycle(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "db",
+		Region:    domain.RegionUSEast1,
+		CPU:       2,
+		MemoryMB:  4096,
+		Image:     "postgres:16",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(4*time.Second)))
+
+	op, err := svc.InstanceAction(instance.ID, domain.InstanceActionReboot, domain.InstanceActionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationStatusRunning, op.Status)
+
+	current, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRebooting, current.Status)
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	current, err = svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRunning, current.Status)
+}
+
+// staleInstanceRepo returns an old copy of an instance from GetByID, as if
+// another writer changed it right after it was read
+type staleInstanceRepo struct {
+	InstanceRepository
+	stale *domain.Instance
+}
+
+func (r *staleInstanceRepo) GetByID(id string) (*domain.Instance, error) {
+	instance := *r.stale
+	return &instance, nil
+}
+
+func TestInstanceAction_StatusChangedSinceRead(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+	svc.SetLifecycleConfig(LifecycleConfig{})
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "api",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+	})
+	require.NoError(t, err)
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionStop, domain.InstanceActionRequest{})
+	require.NoError(t, err)
+
+	// The action sees the instance still running, but the write must not apply
+	svc.instanceRepo = &staleInstanceRepo{InstanceRepository: svc.instanceRepo, stale: instance}
+	_, err = svc.InstanceAction(instance.ID, domain.InstanceActionReboot, domain.InstanceActionRequest{})
+	require.True(t, domain.IsConflict(err), "expected a conflict, got %v", err)
+	assert.Equal(t, domain.StatusStopped, err.(*domain.NahError).Details["current_status"])
+	assert.Equal(t, domain.StatusRunning, err.(*domain.NahError).Details["required_status"])
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// LifecycleConfig controls how instances move through transitional states.
+// When disabled, instances are written straight into their final status.
+type LifecycleConfig struct {
+	Enabled bool
+
+	// Time an instance spends in each transitional state before advancing
+	PendingDuration      time.Duration
+	ProvisioningDuration time.Duration
+	StoppingDuration     time.Duration
+	RebootingDuration    time.Duration
+	TerminatingDuration  time.Duration
+
+	// How often the background worker looks for instances to advance
+	TickInterval time.Duration
+}
+
+// DefaultLifecycleTickInterval is used when no tick interval is configured
+const DefaultLifecycleTickInterval = 500 * time.Millisecond
+
+// lifecycleTransitions maps each transitional status to the status it advances to.
+// An empty target means the instance is deleted.
+var lifecycleTransitions = []struct {
+	from string
+	to   string
+}{
+	{domain.StatusPending, domain.StatusProvisioning},
+	{domain.StatusProvisioning, domain.StatusRunning},
+	{domain.StatusStopping, domain.StatusStopped},
+	{domain.StatusRebooting, domain.StatusRunning},
+	{domain.StatusTerminating, ""},
+}
+
+// SetLifecycleConfig configures the instance lifecycle engine
+func (s *Service) SetLifecycleConfig(cfg LifecycleConfig) {
+	s.lifecycle = cfg
+}
+
+// durationFor returns how long an instance stays in the given transitional status
+func (c LifecycleConfig) durationFor(status string) time.Duration {
+	switch status {
+	case domain.StatusPending:
+		return c.PendingDuration
+	case domain.StatusProvisioning:
+		return c.ProvisioningDuration
+	case domain.StatusStopping:
+		return c.StoppingDuration
+	case domain.StatusRebooting:
+		return c.RebootingDuration
+	case domain.StatusTerminating:
+		return c.TerminatingDuration
+	}
+	return 0
+}
+
+// StartLifecycleWorker starts advancing instances through transitional states in
+// the background, until Shutdown. It does nothing when the lifecycle is disabled.
+func (s *Service) StartLifecycleWorker() {
+	if !s.lifecycle.Enabled {
+		return
+	}
+	s.goBackground(s.runLifecycleWorker)
+}
+
+// runLifecycleWorker advances instances through transitional states until ctx is cancelled
+func (s *Service) runLifecycleWorker(ctx context.Context) {
+
+	interval := s.lifecycle.TickInterval
+	if interval <= 0 {
+		interval = DefaultLifecycleTickInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.advanceLifecycle(now); err != nil {
+				slog.Error("lifecycle advance failed", "error", err)
+			}
+		}
+	}
+}
+
+// advanceLifecycle moves every instance whose transitional state has elapsed to its next status.
+// Each instance advances at most one step per call, and only if it is still in the
+// status it was listed in; instances changed in the meantime are left alone.
+func (s *Service) advanceLifecycle(now time.Time) error {
+	type step struct {
+		instance *domain.Instance
+		from     string
+		to       string
+	}
+
+	var due []step
+	for _, t := range lifecycleTransitions {
+		instances, _, err := s.instanceRepo.List(domain.InstanceListOptions{Status: t.from})
+		if err != nil {
+			return err
+		}
+		wait := s.lifecycle.durationFor(t.from)
+		for _, instance := range instances {
+			if now.Sub(instance.StatusChangedAt) >= wait {
+				due = append(due, step{instance: instance, from: t.from, to: t.to})
+			}
+		}
+	}
+
+	for _, d := range due {
+		var err error
+		if d.to == "" {
+			err = s.instanceRepo.DeleteIfStatus(d.instance.ID, d.from)
+		} else {
+			next := d.to
+			_, err = s.instanceRepo.UpdateIfStatus(d.instance.ID, d.from, domain.UpdateInstanceRequest{Status: &next})
+		}
+		// The instance may have been removed or moved on concurrently; that's fine
+		if err != nil && !domain.IsNotFound(err) && !domain.IsConflict(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// lifecycleStatus returns the status to store when an instance in current is asked to become requested
+func (s *Service) lifecycleStatus(current, requested string) (string, error) {
+	if !s.lifecycle.Enabled || requested == current {
+		return requested, nil
+	}
+
+	switch requested {
+	case domain.StatusRunning, domain.StatusStopped:
+		if domain.IsTransitionalStatus(current) {
+			return "", domain.ConflictError("cannot change status while instance is "+current, map[string]interface{}{
+				"current_status":   current,
+				"requested_status": requested,
+			})
+		}
+		if requested == domain.StatusRunning {
+			return domain.StatusPending, nil
+		}
+		return domain.StatusStopping, nil
+	}
+
+	return requested, nil
+}
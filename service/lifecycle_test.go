@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/hypertf/nahcloud/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupLifecycleService creates a service backed by an in-memory database with the lifecycle engine enabled
+func setupLifecycleService(t *testing.T) (*Service, *domain.Project) {
+	t.Helper()
+
+	db, err := sqlite.NewDB(":memory:")
+	require.NoError(t, err)
+	// Each connection to :memory: is a separate database, so background operations must share one
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	svc := NewService(
+		sqlite.NewProjectRepository(db),
+		sqlite.NewInstanceRepository(db),
+		sqlite.NewMetadataRepository(db),
+		sqlite.NewBucketRepository(db),
+		sqlite.NewObjectRepository(db),
+		sqlite.NewOperationRepository(db),
+		sqlite.NewAPIKeyRepository(db),
+		sqlite.NewTFStateRepository(db),
+	)
+	// Cleanups run in reverse, so background operations finish before the database closes
+	t.Cleanup(func() { svc.Shutdown(context.Background()) })
+	svc.SetLifecycleConfig(LifecycleConfig{
+		Enabled:              true,
+		PendingDuration:      time.Second,
+		ProvisioningDuration: time.Second,
+		StoppingDuration:     time.Second,
+		RebootingDuration:    time.Second,
+		TerminatingDuration:  time.Second,
+	})
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "lifecycle"})
+	require.NoError(t, err)
+
+	return svc, project
+}
+
+func TestLifecycle_CreateBootsThroughTransitionalStates(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "web",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, instance.Status)
+
+	// Not enough time has passed yet
+	require.NoError(t, svc.advanceLifecycle(time.Now()))
+	current, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, current.Status)
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	current, err = svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusProvisioning, current.Status)
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	current, err = svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRunning, current.Status)
+}
+
+func TestLifecycle_StopAndTerminate(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "worker",
+		Region:    domain.RegionEUWest1,
+		CPU:       2,
+		MemoryMB:  1024,
+		Image:     "debian:12",
+		Status:    domain.StatusStopped,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, instance.Status)
+
+	running := domain.StatusRunning
+	updated, err := svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &running})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, updated.Status)
+
+	// Changing status again mid-transition is rejected
+	stopped := domain.StatusStopped
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &stopped})
+	assert.True(t, domain.IsConflict(err))
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(4*time.Second)))
+
+	updated, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &stopped})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopping, updated.Status)
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	current, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, current.Status)
+
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+	current, err = svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusTerminating, current.Status)
+
+	require.NoError(t, svc.advanceLifecycle(time.Now().Add(2*time.Second)))
+	_, err = svc.GetInstance(instance.ID)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestLifecycle_Disabled(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+	svc.SetLifecycleConfig(LifecycleConfig{})
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "direct",
+		Region:    domain.RegionUSWest1,
+		CPU:       1,
+		MemoryMB:  256,
+		Image:     "alpine:3",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRunning, instance.Status)
+
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+	_, err = svc.GetInstance(instance.ID)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestLifecycle_TimedFromStatusChange(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "web",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+	})
+	require.NoError(t, err)
+
+	// Renaming a pending instance doesn't restart its transition
+	time.Sleep(20 * time.Millisecond)
+	name := "web-renamed"
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Name: &name})
+	require.NoError(t, err)
+	require.NoError(t, svc.advanceLifecycle(instance.StatusChangedAt.Add(time.Second)))
+	current, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusProvisioning, current.Status)
+}
+
+func TestLifecycle_RejectsTransitionalStatusUpdates(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "web",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+		Status:    domain.StatusStopped,
+	})
+	require.NoError(t, err)
+
+	for _, status := range []string{domain.StatusPending, domain.StatusRebooting, domain.StatusTerminating} {
+		status := status
+		_, err := svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &status})
+		assert.True(t, domain.IsInvalidInput(err), status)
+	}
+	current, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, current.Status)
+}
+
+func TestLifecycle_RejectsTransitionalStatusOnCreate(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	for _, status := range []string{domain.StatusPending, domain.StatusProvisioning, domain.StatusStopping, domain.StatusRebooting, domain.StatusTerminating} {
+		_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID,
+			Name:      "web-" + status,
+			Region:    domain.RegionUSEast1,
+			CPU:       1,
+			MemoryMB:  512,
+			Image:     "ubuntu:22.04",
+			Status:    status,
+		})
+		assert.True(t, domain.IsInvalidInput(err), status)
+	}
+	instances, _, err := svc.ListInstances(domain.InstanceListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
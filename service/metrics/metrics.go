@@ -0,0 +1,294 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram bucket upper bounds in seconds, suited to request latencies
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample is a single value of a metric computed at scrape time
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// collector writes one metric family in the Prometheus text format
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and serves them in the Prometheus text exposition format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// NewCounterVec registers a counter partitioned by the given labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name, help, labels}, values: make(map[string]*seriesValue)}
+	r.register(c)
+	return c
+}
+
+// NewHistogramVec registers a histogram partitioned by the given labels
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{desc: desc{name, help, labels}, buckets: buckets, series: make(map[string]*histogram)}
+	r.register(h)
+	return h
+}
+
+// NewCounterFunc registers a counter whose samples are computed by fn at scrape time
+func (r *Registry) NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(&funcCollector{desc: desc{name, help, labels}, kind: "counter", fn: fn})
+}
+
+// NewGaugeFunc registers a gauge whose samples are computed by fn at scrape time
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(&funcCollector{desc: desc{name, help, labels}, kind: "gauge", fn: fn})
+}
+
+// WriteText writes every registered metric in the Prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Handler serves the registry's metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// desc describes a metric family
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, kind)
+}
+
+// labelKey joins label values into a map key
+func (d desc) labelKey(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders label pairs, with an optional extra pair appended
+func (d desc) formatLabels(values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(d.labels)+1)
+	for i, label := range d.labels {
+		pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+escapeLabel(extra[1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// seriesValue is the value of one labelled series
+type seriesValue struct {
+	labels []string
+	value  float64
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*seriesValue
+}
+
+// Inc adds one to the series with the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a non-negative amount to the series with the given label values
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.labelKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.values[key]
+	if !ok {
+		s = &seriesValue{labels: append([]string(nil), labelValues...)}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+// Value returns the current value of the series with the given label values
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.labelKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.values[key]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.formatLabels(s.labels), formatValue(s.value))
+	}
+}
+
+// histogram is the state of one labelled histogram series
+type histogram struct {
+	labels []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec counts observations in buckets, partitioned by labels
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogram
+}
+
+// Observe records a value in the series with the given label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.labelKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labels: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations in the series with the given label values
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.labelKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(s.labels, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.formatLabels(s.labels), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.formatLabels(s.labels), s.count)
+	}
+}
+
+// funcCollector reports samples computed at scrape time
+type funcCollector struct {
+	desc
+	kind string
+	fn   func() []Sample
+}
+
+func (f *funcCollector) write(w *bufio.Writer) {
+	samples := f.fn()
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].LabelValues, "\xff") < strings.Join(samples[j].LabelValues, "\xff")
+	})
+
+	f.header(w, f.kind)
+	for _, s := range samples {
+		if len(s.LabelValues) != len(f.labels) {
+			continue
+		}
+		fmt.Fprintf(w, "%s%s %s\n", f.name, f.formatLabels(s.LabelValues), formatValue(s.Value))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_requests_total", "Requests served.", "method", "status")
+
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(2.5, "POST", "201")
+	c.Add(-1, "POST", "201")
+
+	assert.Equal(t, float64(2), c.Value("GET", "200"))
+	assert.Equal(t, 2.5, c.Value("POST", "201"))
+	assert.Equal(t, float64(0), c.Value("PUT", "200"))
+
+	assert.Equal(t, `# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",status="200"} 2
+test_requests_total{method="POST",status="201"} 2.5
+`, scrape(t, r))
+}
+
+func TestCounterVec_WrongLabelCountPanics(t *testing.T) {
+	c := NewRegistry().NewCounterVec("test_total", "help", "a")
+	assert.Panics(t, func() { c.Inc("x", "y") })
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_duration_seconds", "Durations.", []float64{0.1, 1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(3, "/a")
+
+	assert.Equal(t, uint64(3), h.Count("/a"))
+	assert.Equal(t, uint64(0), h.Count("/b"))
+
+	assert.Equal(t, `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/a",le="0.1"} 1
+test_duration_seconds_bucket{route="/a",le="1"} 2
+test_duration_seconds_bucket{route="/a",le="+Inf"} 3
+test_duration_seconds_sum{route="/a"} 3.55
+test_duration_seconds_count{route="/a"} 3
+`, scrape(t, r))
+}
+
+func TestFuncCollectors(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("test_items", "Items.", []string{"type"}, func() []Sample {
+		return []Sample{
+			{LabelValues: []string{"b"}, Value: 2},
+			{LabelValues: []string{"a"}, Value: 1},
+			{LabelValues: []string{"x", "y"}, Value: 9},
+		}
+	})
+	r.NewCounterFunc("test_events_total", "Events.", nil, func() []Sample {
+		return []Sample{{Value: 7}}
+	})
+
+	assert.Equal(t, `# HELP test_items Items.
+# TYPE test_items gauge
+test_items{type="a"} 1
+test_items{type="b"} 2
+# HELP test_events_total Events.
+# TYPE test_events_total counter
+test_events_total 7
+`, scrape(t, r))
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "help", "path").Inc("a\"b\\c\nd")
+
+	assert.Contains(t, scrape(t, r), `test_total{path="a\"b\\c\nd"} 1`)
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "help").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, rec.Body.String(), "test_total 1\n")
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// DefaultTokenTTL is the lifetime of access tokens when none is configured
+const DefaultTokenTTL = time.Hour
+
+// OAuthConfig controls the access tokens issued for the client credentials grant
+type OAuthConfig struct {
+	// Key used to sign access tokens; tokens don't survive a change of key
+	SigningKey []byte
+
+	// Lifetime of issued access tokens
+	TokenTTL time.Duration
+}
+
+// SetOAuthConfig configures access token issuance. An empty signing key keeps the
+// current key and a zero TTL uses DefaultTokenTTL.
+func (s *Service) SetOAuthConfig(cfg OAuthConfig) {
+	if len(cfg.SigningKey) == 0 {
+		cfg.SigningKey = s.oauth.SigningKey
+	}
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = DefaultTokenTTL
+	}
+	s.oauth = cfg
+}
+
+// tokenHeader is the encoded JWT header of every access token
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// tokenClaims are the claims carried by an access token
+type tokenClaims struct {
+	Subject   string `json:"sub"` // API key ID
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// IssueAccessToken exchanges client credentials for a signed access token. The
+// client ID is an API key's ID and the client secret is the key itself; the token
+// carries the key's scopes and project restriction.
+func (s *Service) IssueAccessToken(clientID, clientSecret string) (*domain.AccessToken, error) {
+	return s.issueAccessToken(clientID, clientSecret, time.Now())
+}
+
+func (s *Service) issueAccessToken(clientID, clientSecret string, now time.Time) (*domain.AccessToken, error) {
+	key, err := s.AuthenticateAPIKey(clientSecret)
+	if err != nil {
+		if domain.IsNotFound(err) {
+			return nil, domain.UnauthorizedError("invalid client credentials")
+		}
+		return nil, err
+	}
+	if key.ID != clientID {
+		return nil, domain.UnauthorizedError("invalid client credentials")
+	}
+
+	claims, err := json.Marshal(tokenClaims{
+		Subject:   key.ID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.oauth.TokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, domain.InternalError("failed to encode access token")
+	}
+	payload := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	return &domain.AccessToken{
+		AccessToken: payload + "." + s.signToken(payload),
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.oauth.TokenTTL.Seconds()),
+		Scope:       strings.Join(key.Scopes, " "),
+	}, nil
+}
+
+// AuthenticateAccessToken returns the API key an access token was issued for
+// It returns a not found error for credentials that aren't access tokens and an
+// unauthorized error for tokens that are forged, expired or whose key was revoked.
+func (s *Service) AuthenticateAccessToken(token string) (*domain.APIKey, error) {
+	return s.authenticateAccessToken(token, time.Now())
+}
+
+func (s *Service) authenticateAccessToken(token string, now time.Time) (*domain.APIKey, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, domain.NotFoundError("access token", "")
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signToken(payload))) {
+		return nil, domain.UnauthorizedError("invalid access token")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, domain.UnauthorizedError("invalid access token")
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, domain.UnauthorizedError("invalid access token")
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, domain.UnauthorizedError("access token has expired")
+	}
+
+	key, err := s.apiKeyRepo.GetByID(claims.Subject)
+	if err != nil {
+		if domain.IsNotFound(err) {
+			return nil, domain.UnauthorizedError("invalid access token")
+		}
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, domain.UnauthorizedError("api key has been revoked")
+	}
+	return key, nil
+}
+
+// signToken returns the encoded HMAC-SHA256 signature of a token's header and claims
+func (s *Service) signToken(payload string) string {
+	mac := hmac.New(sha256.New, s.oauth.SigningKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
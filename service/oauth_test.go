@@ -0,0 +1,96 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth_IssueAndAuthenticate(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+	svc.SetOAuthConfig(OAuthConfig{TokenTTL: 5 * time.Minute})
+
+	created, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{
+		Name:      "provider",
+		Scopes:    []string{"projects:read", "instances:*"},
+		ProjectID: project.ID,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	token, err := svc.issueAccessToken(created.ID, created.Key, now)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, 300, token.ExpiresIn)
+	assert.Equal(t, "projects:read instances:*", token.Scope)
+	assert.Len(t, strings.Split(token.AccessToken, "."), 3)
+
+	key, err := svc.authenticateAccessToken(token.AccessToken, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+	assert.Equal(t, project.ID, key.ProjectID)
+
+	_, err = svc.authenticateAccessToken(token.AccessToken, now.Add(5*time.Minute))
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeUnauthorized, err.(*domain.NahError).Code)
+
+	// API keys and the server token aren't access tokens
+	_, err = svc.AuthenticateAccessToken(created.Key)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestOAuth_InvalidCredentials(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	created, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"*"}})
+	require.NoError(t, err)
+	other, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{Name: "other", Scopes: []string{"*"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		clientID string
+		secret   string
+	}{
+		{name: "unknown secret", clientID: created.ID, secret: "nah_not-a-key"},
+		{name: "mismatched id", clientID: other.ID, secret: created.Key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.IssueAccessToken(tt.clientID, tt.secret)
+			require.Error(t, err)
+			assert.Equal(t, domain.ErrorCodeUnauthorized, err.(*domain.NahError).Code)
+		})
+	}
+}
+
+func TestOAuth_RejectsForgedAndRevoked(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	created, err := svc.CreateAPIKey(domain.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"*"}})
+	require.NoError(t, err)
+	token, err := svc.IssueAccessToken(created.ID, created.Key)
+	require.NoError(t, err)
+
+	// Tokens signed with another key are rejected
+	svc.SetOAuthConfig(OAuthConfig{SigningKey: []byte("rotated")})
+	_, err = svc.AuthenticateAccessToken(token.AccessToken)
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeUnauthorized, err.(*domain.NahError).Code)
+
+	token, err = svc.IssueAccessToken(created.ID, created.Key)
+	require.NoError(t, err)
+	_, err = svc.AuthenticateAccessToken(token.AccessToken)
+	require.NoError(t, err)
+
+	// Revoking the key invalidates tokens already issued for it
+	_, err = svc.RevokeAPIKey(created.ID)
+	require.NoError(t, err)
+	_, err = svc.AuthenticateAccessToken(token.AccessToken)
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeUnauthorized, err.(*domain.NahError).Code)
+}
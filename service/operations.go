@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// operationSettleTimeout bounds how long an operation waits for its instance to leave a transitional state
+const operationSettleTimeout = 10 * time.Minute
+
+// GetOperation retrieves an operation by ID
+func (s *Service) GetOperation(id string) (*domain.Operation, error) {
+	return s.operationRepo.GetByID(id)
+}
+
+// ListOperations lists a page of operations with optional filtering and returns the next page token
+func (s *Service) ListOperations(opts domain.OperationListOptions) ([]*domain.Operation, string, error) {
+	return s.operationRepo.List(opts)
+}
+
+// newOperation records a new operation in the given status. projectID is the project
+// an instance target belongs to, which decides which API keys may fetch the operation.
+func (s *Service) newOperation(kind, targetType, targetID, projectID, status string) (*domain.Operation, error) {
+	id, err := generateID()
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+	op := &domain.Operation{
+		ID:         id,
+		Kind:       kind,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ProjectID:  projectID,
+		Status:     status,
+	}
+	if err := s.operationRepo.Create(op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// runOperation executes fn in the background and records its outcome on the operation.
+// fn returns the ID of the resource it acted on. Instance operations are only marked
+// done once the instance has left its transitional state.
+func (s *Service) runOperation(op *domain.Operation, deleting bool, fn func() (string, error)) {
+	s.goBackground(func(ctx context.Context) {
+		running := domain.OperationStatusRunning
+		if _, err := s.operationRepo.Update(op.ID, domain.UpdateOperationRequest{Status: &running}); err != nil {
+			slog.Error("operation update failed", "operation_id", op.ID, "error", err)
+			return
+		}
+
+		targetID, err := fn()
+		if err == nil && op.TargetType == "instance" {
+			err = s.waitForInstanceSettled(ctx, targetID, deleting)
+		}
+		s.finishOperation(op.ID, targetID, err)
+	})
+}
+
+// FailInterruptedOperations marks operations left pending or running by a previous
+// server process as failed, since nothing will finish them, and returns how many it marked
+func (s *Service) FailInterruptedOperations() (int, error) {
+	interrupted := domain.InternalError("operation was interrupted by a server restart")
+	failed := 0
+	for _, status := range []string{domain.OperationStatusPending, domain.OperationStatusRunning} {
+		for {
+			// Failed operations drop out of the listing, so the first page is always the next
+			ops, _, err := s.operationRepo.List(domain.OperationListOptions{
+				Status:      status,
+				PageOptions: domain.PageOptions{PageSize: domain.MaxPageSize},
+			})
+			if err != nil {
+				return failed, err
+			}
+			if len(ops) == 0 {
+				break
+			}
+			for _, op := range ops {
+				if _, err := s.finishOperation(op.ID, "", interrupted); err != nil {
+					return failed, err
+				}
+				failed++
+			}
+		}
+	}
+	return failed, nil
+}
+
+// finishOperation marks an operation done, or failed if err is set
+func (s *Service) finishOperation(id, targetID string, err error) (*domain.Operation, error) {
+	status := domain.OperationStatusDone
+	req := domain.UpdateOperationRequest{Status: &status}
+	if targetID != "" {
+		req.TargetID = &targetID
+	}
+	if err != nil {
+		status = domain.OperationStatusFailed
+		nahErr, ok := err.(*domain.NahError)
+		if !ok {
+			nahErr = domain.InternalError(err.Error())
+		}
+		req.Error = nahErr
+	}
+
+	op, updateErr := s.operationRepo.Update(id, req)
+	if updateErr != nil {
+		slog.Error("operation update failed", "operation_id", id, "error", updateErr)
+	}
+	return op, updateErr
+}
+
+// waitForInstanceSettled blocks until the instance is no longer in a transitional state,
+// or has been removed when deleting is set. It gives up when ctx is done.
+func (s *Service) waitForInstanceSettled(ctx context.Context, id string, deleting bool) error {
+	if !s.lifecycle.Enabled {
+		return nil
+	}
+
+	interval := s.lifecycle.TickInterval
+	if interval <= 0 {
+		interval = DefaultLifecycleTickInterval
+	}
+
+	deadline := time.Now().Add(operationSettleTimeout)
+	for {
+		instance, err := s.instanceRepo.GetByID(id)
+		if err != nil {
+			if deleting && domain.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if !deleting && !domain.IsTransitionalStatus(instance.Status) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return domain.InternalError("timed out waiting for instance to leave status " + instance.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return domain.InternalError("server shut down before the operation finished")
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Asynchronous variants of instance and bucket mutations.
+// Each returns a pending operation immediately and performs the mutation in the background.
+
+// CreateInstanceAsync creates an instance in the background
+func (s *Service) CreateInstanceAsync(req domain.CreateInstanceRequest) (*domain.Operation, error) {
+	op, err := s.newOperation("instance.create", "instance", "", req.ProjectID, domain.OperationStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	s.runOperation(op, false, func() (string, error) {
+		instance, err := s.CreateInstance(req)
+		if err != nil {
+			return "", err
+		}
+		return instance.ID, nil
+	})
+	return op, nil
+}
+
+// UpdateInstanceAsync updates an instance in the background
+func (s *Service) UpdateInstanceAsync(id string, req domain.UpdateInstanceRequest) (*domain.Operation, error) {
+	instance, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.newOperation("instance.update", "instance", id, instance.ProjectID, domain.OperationStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	s.runOperation(op, false, func() (string, error) {
+		_, err := s.UpdateInstance(id, req)
+		return id, err
+	})
+	return op, nil
+}
+
+// DeleteInstanceAsync deletes an instance in the background
+func (s *Service) DeleteInstanceAsync(id string) (*domain.Operation, error) {
+	instance, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.newOperation("instance.delete", "instance", id, instance.ProjectID, domain.OperationStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	s.runOperation(op, true, func() (string, error) {
+		return id, s.DeleteInstance(id)
+	})
+	return op, nil
+}
+
+// CreateBucketAsync creates a bucket in the background
+func (s *Service) CreateBucketAsync(req domain.CreateBucketRequest) (*domain.Operation, error) {
+	op, err := s.newOperation("bucket.create", "bucket", "", "", domain.OperationStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	s.runOperation(op, false, func() (string, error) {
+		bucket, err := s.CreateBucket(req)
+		if err != nil {
+			return "", err
+		}
+		return bucket.ID, nil
+	})
+	return op, nil
+}
+
+// UpdateBucketAsync updates a bucket in the background
+func (s *Service) UpdateBucketAsync(id string, req domain.UpdateBucketRequest) (*domain.Operation, error) {
+	if _, err := s.bucketRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+	op, err := s.newOperation("bucket.update", "bucket", id, "", domain.OperationStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	s.runOperation(op, false, func() (string, error) {
+		_, err := s.UpdateBucket(id, req)
+		return id, err
+	})
+	return op, nil
+}
+
+// DeleteBucketAsync deletes a bucket in the background
+func (s *Service) DeleteBucketAsync(id string) (*domain.Operation, error) {
+	if _, err := s.bucketRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+	op, err := s.newOperation("bucket.delete", "bucket", id, "", domain.OperationStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	s.runOperation(op, true, func() (string, error) {
+		return id, s.DeleteBucket(id)
+	})
+	return op, nil
+}
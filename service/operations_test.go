@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForOperation polls the service until the operation completes
+func waitForOperation(t *testing.T, svc *Service, id string) *domain.Operation {
+	t.Helper()
+
+	var op *domain.Operation
+	require.Eventually(t, func() bool {
+		var err error
+		op, err = svc.GetOperation(id)
+		require.NoError(t, err)
+		return op.IsComplete()
+	}, 5*time.Second, 10*time.Millisecond)
+	return op
+}
+
+func TestOperations_CreateBucketAsync(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	op, err := svc.CreateBucketAsync(domain.CreateBucketRequest{Name: "assets"})
+	require.NoError(t, err)
+	assert.Equal(t, "bucket.create", op.Kind)
+	assert.Equal(t, domain.OperationStatusPending, op.Status)
+
+	op = waitForOperation(t, svc, op.ID)
+	assert.Equal(t, domain.OperationStatusDone, op.Status)
+	assert.Equal(t, "assets", op.TargetID)
+	assert.Empty(t, op.ProjectID, "buckets don't belong to a project")
+	assert.Nil(t, op.Error)
+	require.NotNil(t, op.CompletedAt)
+
+	_, err = svc.GetBucket("assets")
+	require.NoError(t, err)
+}
+
+func TestOperations_FailedOperationRecordsError(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	op, err := svc.CreateInstanceAsync(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "bad",
+		Region:    "mars-north-1",
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+	})
+	require.NoError(t, err)
+
+	op = waitForOperation(t, svc, op.ID)
+	assert.Equal(t, domain.OperationStatusFailed, op.Status)
+	require.NotNil(t, op.Error)
+	assert.Equal(t, domain.ErrorCodeInvalidInput, op.Error.Code)
+	assert.Empty(t, op.TargetID)
+	assert.Equal(t, project.ID, op.ProjectID)
+}
+
+func TestOperations_InstanceActionIsRecorded(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+	svc.SetLifecycleConfig(LifecycleConfig{})
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "cache",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "redis:7",
+	})
+	require.NoError(t, err)
+
+	op, err := svc.InstanceAction(instance.ID, domain.InstanceActionStop, domain.InstanceActionRequest{})
+	require.NoError(t, err)
+
+	stored, err := svc.GetOperation(op.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationStatusDone, stored.Status)
+	assert.Equal(t, project.ID, stored.ProjectID)
+	assert.NotNil(t, stored.CompletedAt)
+
+	ops, _, err := svc.ListOperations(domain.OperationListOptions{TargetID: instance.ID})
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.Equal(t, "instance.stop", ops[0].Kind)
+
+	_, err = svc.GetOperation("missing")
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestOperations_ShutdownFailsWaitingOperations(t *testing.T) {
+	svc, project := setupLifecycleService(t)
+
+	// No lifecycle worker runs, so the instance stays pending and the operation waits on it
+	op, err := svc.CreateInstanceAsync(domain.CreateInstanceRequest{
+		ProjectID: project.ID,
+		Name:      "slow",
+		Region:    domain.RegionUSEast1,
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		current, err := svc.GetOperation(op.ID)
+		require.NoError(t, err)
+		return current.Status == domain.OperationStatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Shutdown(ctx))
+
+	op, err = svc.GetOperation(op.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationStatusFailed, op.Status)
+	require.NotNil(t, op.Error)
+	assert.Contains(t, op.Error.Message, "shut down")
+}
+
+func TestOperations_FailInterruptedOperations(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	pending, err := svc.newOperation("bucket.create", "bucket", "", "", domain.OperationStatusPending)
+	require.NoError(t, err)
+	running, err := svc.newOperation("instance.stop", "instance", "inst-1", "proj-1", domain.OperationStatusRunning)
+	require.NoError(t, err)
+	done, err := svc.newOperation("instance.start", "instance", "inst-1", "proj-1", domain.OperationStatusDone)
+	require.NoError(t, err)
+
+	n, err := svc.FailInterruptedOperations()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	for _, id := range []string{pending.ID, running.ID} {
+		op, err := svc.GetOperation(id)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OperationStatusFailed, op.Status)
+		require.NotNil(t, op.Error)
+		assert.Contains(t, op.Error.Message, "restart")
+	}
+	op, err := svc.GetOperation(done.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationStatusDone, op.Status)
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Middleware enforces the configured limits on API requests. Requests outside the
+// API, and admin requests, are never limited. subject names who a request is
+// authenticated as, or returns "" for anonymous and invalid credentials, which
+// then share one bucket.
+func (l *Limiter) Middleware(subject func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/v1/_admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			l.serve(w, r, subject(r), next)
+		})
+	}
+}
+
+func (l *Limiter) serve(w http.ResponseWriter, r *http.Request, subject string, next http.Handler) {
+	// Paths that name no known resource share one bucket, so they cannot grow the limiter
+	resource := domain.ResourceForPath(r.URL.Path)
+	if !slices.Contains(domain.ScopeResources, resource) {
+		resource = ""
+	}
+
+	d := l.take(subject, r.Method, resource)
+	if d == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(int(d.limit.capacity())))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(d.reset), 10))
+
+	if d.allowed {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	retryAfter := int(math.Ceil(d.retryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	h.Set("Retry-After", strconv.Itoa(retryAfter))
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(domain.NewError(domain.ErrorCodeTooManyRequests, "rate limit exceeded", map[string]interface{}{
+		"limit":    d.limit.Name,
+		"resource": resource,
+	}).WithRequestID(h.Get(domain.RequestIDHeader)))
+}
+
+// ceilSeconds returns a time as Unix seconds, rounded up
+func ceilSeconds(t time.Time) int64 {
+	seconds := t.Unix()
+	if t.After(time.Unix(seconds, 0)) {
+		seconds++
+	}
+	return seconds
+}
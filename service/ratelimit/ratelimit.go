@@ -0,0 +1,311 @@
+package ratelimit
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// WriteMethods are the HTTP methods that modify resources
+var WriteMethods = []string{"POST", "PUT", "PATCH", "DELETE", "LOCK", "UNLOCK"}
+
+// ReadMethods are the HTTP methods that only read resources
+var ReadMethods = []string{"GET", "HEAD"}
+
+// Config holds rate limiting configuration
+type Config struct {
+	Enabled bool    `json:"enabled"`
+	Limits  []Limit `json:"limits"`
+}
+
+// Limit is a token bucket quota. Every authenticated subject gets its own bucket
+// for each resource type the limit applies to.
+type Limit struct {
+	Name     string   `json:"name"`
+	Methods  []string `json:"methods,omitempty"`  // empty matches every method
+	Resource string   `json:"resource,omitempty"` // empty matches every resource type
+	Rate     float64  `json:"rate"`               // tokens added per second
+	Burst    int      `json:"burst,omitempty"`    // bucket capacity; 0 uses the rate rounded up
+}
+
+// capacity returns the number of tokens a full bucket holds
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// matches reports whether a request is subject to the limit
+func (l Limit) matches(method, resource string) bool {
+	if l.Resource != "" && l.Resource != resource {
+		return false
+	}
+	if len(l.Methods) == 0 {
+		return true
+	}
+	for _, m := range l.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that a configuration can be applied
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for _, limit := range c.Limits {
+		if limit.Name == "" {
+			return domain.InvalidInputError("rate limit name is required", nil)
+		}
+		if names[limit.Name] {
+			return domain.AlreadyExistsError("rate limit", "name", limit.Name)
+		}
+		names[limit.Name] = true
+
+		if limit.Rate <= 0 {
+			return domain.InvalidInputError("rate must be greater than 0", map[string]interface{}{"limit": limit.Name})
+		}
+		if limit.Burst < 0 {
+			return domain.InvalidInputError("burst must not be negative", map[string]interface{}{"limit": limit.Name})
+		}
+	}
+	return nil
+}
+
+// BucketStatus describes the state of a single token bucket
+type BucketStatus struct {
+	Limit     string    `json:"limit"`
+	Subject   string    `json:"subject"` // who the bucket belongs to, e.g. "api_key:<id>", or "anonymous"
+	Resource  string    `json:"resource"`
+	Capacity  int       `json:"capacity"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"` // when the bucket will be full again
+}
+
+// Status is a snapshot of the limiter's configuration and buckets
+type Status struct {
+	Config  Config         `json:"config"`
+	Buckets []BucketStatus `json:"buckets"`
+}
+
+// sweepInterval is how often buckets that have refilled are discarded. A full
+// bucket is the same as no bucket, so this only bounds memory.
+const sweepInterval = time.Minute
+
+type bucketKey struct {
+	limit    string
+	subject  string
+	resource string
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens accrued since the bucket was last updated
+func (b *bucket) refill(limit Limit, now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(limit.capacity(), b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+}
+
+// resetAt returns when the bucket will be full again
+func (b *bucket) resetAt(limit Limit) time.Time {
+	missing := limit.capacity() - b.tokens
+	return b.updated.Add(time.Duration(missing / limit.Rate * float64(time.Second)))
+}
+
+// Limiter enforces token bucket quotas per authenticated subject and resource type
+type Limiter struct {
+	mu      sync.Mutex
+	config  *Config
+	buckets map[bucketKey]*bucket
+	swept   time.Time // when full buckets were last discarded
+	now     func() time.Time
+}
+
+// NewLimiter creates a limiter with the provided config
+func NewLimiter(config *Config) *Limiter {
+	return &Limiter{
+		config:  config,
+		buckets: make(map[bucketKey]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Config returns a copy of the configuration in effect
+func (l *Limiter) Config() Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	config := *l.config
+	config.Limits = append([]Limit(nil), l.config.Limits...)
+	return config
+}
+
+// UpdateConfig replaces the configuration and discards every bucket
+func (l *Limiter) UpdateConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	config.Limits = append([]Limit(nil), config.Limits...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = &config
+	l.buckets = make(map[bucketKey]*bucket)
+	return nil
+}
+
+// Reset discards every bucket, so all tokens start again with full quotas
+func (l *Limiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets = make(map[bucketKey]*bucket)
+}
+
+// Status returns the configuration and the current state of every bucket
+func (l *Limiter) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limits := make(map[string]Limit, len(l.config.Limits))
+	for _, limit := range l.config.Limits {
+		limits[limit.Name] = limit
+	}
+
+	status := Status{Config: *l.config, Buckets: []BucketStatus{}}
+	status.Config.Limits = append([]Limit(nil), l.config.Limits...)
+	for key, b := range l.buckets {
+		limit := limits[key.limit]
+		b.refill(limit, now)
+		status.Buckets = append(status.Buckets, BucketStatus{
+			Limit:     key.limit,
+			Subject:   subjectName(key.subject),
+			Resource:  key.resource,
+			Capacity:  int(limit.capacity()),
+			Remaining: int(b.tokens),
+			Reset:     b.resetAt(limit).UTC(),
+		})
+	}
+	sort.Slice(status.Buckets, func(i, j int) bool {
+		a, b := status.Buckets[i], status.Buckets[j]
+		if a.Limit != b.Limit {
+			return a.Limit < b.Limit
+		}
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		return a.Resource < b.Resource
+	})
+	return status
+}
+
+// decision is the outcome of taking a token for a request
+type decision struct {
+	allowed    bool
+	limit      Limit // the most restrictive limit that applied
+	remaining  int
+	reset      time.Time
+	retryAfter time.Duration // how long until a token is available, when denied
+}
+
+// take removes a token from every bucket the request is subject to. The request is
+// only allowed if all of them have a token; otherwise none are consumed. It returns
+// nil when no limit applies.
+func (l *Limiter) take(subject, method, resource string) *decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.config.Enabled {
+		return nil
+	}
+
+	now := l.now()
+	if now.Sub(l.swept) >= sweepInterval {
+		l.sweep(now)
+	}
+	type applied struct {
+		limit  Limit
+		bucket *bucket
+	}
+	var matched []applied
+	for _, limit := range l.config.Limits {
+		if !limit.matches(method, resource) {
+			continue
+		}
+		key := bucketKey{limit: limit.Name, subject: subject, resource: resource}
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{tokens: limit.capacity(), updated: now}
+			l.buckets[key] = b
+		}
+		b.refill(limit, now)
+		matched = append(matched, applied{limit, b})
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	for _, m := range matched {
+		if m.bucket.tokens < 1 {
+			wait := (1 - m.bucket.tokens) / m.limit.Rate
+			return &decision{
+				limit:      m.limit,
+				remaining:  0,
+				reset:      m.bucket.resetAt(m.limit),
+				retryAfter: time.Duration(wait * float64(time.Second)),
+			}
+		}
+	}
+
+	var tightest *applied
+	for i := range matched {
+		m := &matched[i]
+		m.bucket.tokens--
+		if tightest == nil || m.bucket.tokens < tightest.bucket.tokens {
+			tightest = m
+		}
+	}
+	return &decision{
+		allowed:   true,
+		limit:     tightest.limit,
+		remaining: int(tightest.bucket.tokens),
+		reset:     tightest.bucket.resetAt(tightest.limit),
+	}
+}
+
+// sweep discards the buckets that have refilled since they were last used; the
+// caller holds the lock
+func (l *Limiter) sweep(now time.Time) {
+	limits := make(map[string]Limit, len(l.config.Limits))
+	for _, limit := range l.config.Limits {
+		limits[limit.Name] = limit
+	}
+	for key, b := range l.buckets {
+		limit := limits[key.limit]
+		b.refill(limit, now)
+		if b.tokens >= limit.capacity() {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// subjectName returns how a bucket's subject is shown in the status
+func subjectName(subject string) string {
+	if subject == "" {
+		return "anonymous"
+	}
+	return subject
+}
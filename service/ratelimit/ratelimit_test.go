@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// fakeClock is a manually advanced time source
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(limits ...Limit) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	limiter := NewLimiter(&Config{Enabled: true, Limits: limits})
+	limiter.now = clock.now
+	return limiter, clock
+}
+
+// subjects stands in for authentication: only these tokens are valid
+var subjects = map[string]string{"token-a": "api_key:a", "token-b": "api_key:b"}
+
+func subjectOf(r *http.Request) string {
+	return subjects[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+}
+
+func serve(limiter *Limiter, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	rec.Header().Set(domain.RequestIDHeader, "req-1")
+	limiter.Middleware(subjectOf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_BurstThenThrottle(t *testing.T) {
+	limiter, clock := newTestLimiter(Limit{Name: "writes", Methods: WriteMethods, Rate: 2, Burst: 3})
+
+	for i := 2; i >= 0; i-- {
+		rec := serve(limiter, "POST", "/v1/instances", "token-a")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(i), rec.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	rec := serve(limiter, "POST", "/v1/instances", "token-a")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	// An empty bucket refills 3 tokens at 2 per second in 1.5s
+	assert.Equal(t, "1792152002", rec.Header().Get("X-RateLimit-Reset"))
+
+	var nahErr domain.NahError
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &nahErr))
+	assert.Equal(t, domain.ErrorCodeTooManyRequests, nahErr.Code)
+	assert.Equal(t, "writes", nahErr.Details["limit"])
+	assert.Equal(t, "req-1", nahErr.Details["request_id"])
+
+	// Reads aren't subject to the write limit
+	assert.Equal(t, http.StatusOK, serve(limiter, "GET", "/v1/instances", "token-a").Code)
+
+	clock.advance(500 * time.Millisecond)
+	rec = serve(limiter, "POST", "/v1/instances", "token-a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestMiddleware_BucketsPerTokenAndResource(t *testing.T) {
+	limiter, _ := newTestLimiter(Limit{Name: "writes", Methods: WriteMethods, Rate: 1})
+
+	assert.Equal(t, http.StatusOK, serve(limiter, "POST", "/v1/instances", "token-a").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(limiter, "POST", "/v1/instances", "token-a").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(limiter, "PATCH", "/v1/instances/i-1", "token-a").Code)
+
+	assert.Equal(t, http.StatusOK, serve(limiter, "POST", "/v1/instances", "token-b").Code)
+	assert.Equal(t, http.StatusOK, serve(limiter, "POST", "/v1/projects", "token-a").Code)
+	assert.Equal(t, http.StatusOK, serve(limiter, "POST", "/v1/instances", "").Code)
+}
+
+func TestMiddleware_UnknownTokensShareAnonymousBucket(t *testing.T) {
+	limiter, _ := newTestLimiter(Limit{Name: "all", Rate: 1})
+
+	assert.Equal(t, http.StatusOK, serve(limiter, "GET", "/v1/projects", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(limiter, "GET", "/v1/projects", "made-up-1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(limiter, "GET", "/v1/projects", "made-up-2").Code)
+
+	// Paths that name no resource share a bucket too
+	assert.Equal(t, http.StatusOK, serve(limiter, "GET", "/v1/nothing-1", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(limiter, "GET", "/v1/nothing-2", "").Code)
+
+	assert.Len(t, limiter.Status().Buckets, 2)
+}
+
+func TestLimiter_SweepsRefilledBuckets(t *testing.T) {
+	limiter, clock := newTestLimiter(Limit{Name: "writes", Methods: WriteMethods, Rate: 0.02, Burst: 2})
+
+	serve(limiter, "POST", "/v1/instances", "token-a")
+	serve(limiter, "POST", "/v1/projects", "token-b")
+	serve(limiter, "POST", "/v1/projects", "token-b")
+	require.Len(t, limiter.Status().Buckets, 2)
+
+	// After a minute token-a's bucket has refilled, token-b's hasn't
+	clock.advance(sweepInterval)
+	serve(limiter, "GET", "/v1/projects", "token-a")
+
+	buckets := limiter.Status().Buckets
+	require.Len(t, buckets, 1)
+	assert.Equal(t, "api_key:b", buckets[0].Subject)
+	assert.Equal(t, 1, buckets[0].Remaining)
+}
+
+func TestMiddleware_AllMatchingLimitsMustAllow(t *testing.T) {
+	limiter, _ := newTestLimiter(
+		Limit{Name: "all", Rate: 10, Burst: 10},
+		Limit{Name: "tfstate-locks", Methods: []string{"LOCK"}, Resource: "tfstate", Rate: 1},
+	)
+
+	rec := serve(limiter, "LOCK", "/v1/tfstate/s1", "token-a")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"), "headers report the most restrictive limit")
+
+	rec = serve(limiter, "LOCK", "/v1/tfstate/s1", "token-a")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// The denied request didn't consume a token from the broader limit
+	rec = serve(limiter, "GET", "/v1/tfstate/s1", "token-a")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "8", rec.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestMiddleware_Exemptions(t *testing.T) {
+	limiter, _ := newTestLimiter(Limit{Name: "all", Rate: 1})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(limiter, "GET", "/v1/_admin/ratelimit", "").Code)
+		assert.Equal(t, http.StatusOK, serve(limiter, "GET", "/web/projects", "").Code)
+	}
+
+	require.NoError(t, limiter.UpdateConfig(Config{Enabled: false, Limits: []Limit{{Name: "all", Rate: 1}}}))
+	for i := 0; i < 3; i++ {
+		rec := serve(limiter, "GET", "/v1/projects", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestLimiter_StatusAndReset(t *testing.T) {
+	limiter, _ := newTestLimiter(Limit{Name: "writes", Methods: WriteMethods, Rate: 1, Burst: 5})
+
+	serve(limiter, "POST", "/v1/instances", "token-a")
+	serve(limiter, "POST", "/v1/instances", "token-a")
+	serve(limiter, "DELETE", "/v1/bucket/b/objects/o", "")
+
+	status := limiter.Status()
+	assert.Len(t, status.Config.Limits, 1)
+	require.Len(t, status.Buckets, 2)
+	assert.Equal(t, "anonymous", status.Buckets[0].Subject)
+	assert.Equal(t, "objects", status.Buckets[0].Resource)
+	assert.Equal(t, 4, status.Buckets[0].Remaining)
+	assert.Equal(t, "api_key:a", status.Buckets[1].Subject)
+	assert.Equal(t, "instances", status.Buckets[1].Resource)
+	assert.Equal(t, 3, status.Buckets[1].Remaining)
+	assert.Equal(t, 5, status.Buckets[1].Capacity)
+
+	limiter.Reset()
+	assert.Empty(t, limiter.Status().Buckets)
+	rec := serve(limiter, "POST", "/v1/instances", "token-a")
+	assert.Equal(t, "4", rec.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  []Limit
+		wantErr bool
+	}{
+		{name: "valid", limits: []Limit{{Name: "writes", Rate: 5, Burst: 10}, {Name: "reads", Rate: 0.5}}},
+		{name: "missing name", limits: []Limit{{Rate: 5}}, wantErr: true},
+		{name: "duplicate name", limits: []Limit{{Name: "a", Rate: 1}, {Name: "a", Rate: 2}}, wantErr: true},
+		{name: "zero rate", limits: []Limit{{Name: "a"}}, wantErr: true},
+		{name: "negative burst", limits: []Limit{{Name: "a", Rate: 1, Burst: -1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Enabled: true, Limits: tt.limits}
+			err := config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
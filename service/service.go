@@ -1,20 +1,38 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"regexp"
+	"sync"
 
 	"github.com/hypertf/nahcloud/domain"
 )
 
 // Service provides business logic for NahCloud operations
 type Service struct {
-	projectRepo  ProjectRepository
-	instanceRepo InstanceRepository
-	metadataRepo MetadataRepository
-	bucketRepo   BucketRepository
-	objectRepo   ObjectRepository
+	projectRepo   ProjectRepository
+	instanceRepo  InstanceRepository
+	metadataRepo  MetadataRepository
+	bucketRepo    BucketRepository
+	objectRepo    ObjectRepository
+	operationRepo OperationRepository
+	apiKeyRepo    APIKeyRepository
+	tfStateRepo   TFStateRepository
+
+	lifecycle LifecycleConfig
+	oauth     OAuthConfig
+	tfstate   TFStateConfig
+
+	// tfstateMu serializes Terraform state writes, so versions are numbered in order
+	tfstateMu sync.Mutex
+
+	// Background work, such as asynchronous operations, runs under ctx and is
+	// tracked by background so Shutdown can wait for it
+	ctx        context.Context
+	stop       context.CancelFunc
+	background sync.WaitGroup
 }
 
 // ProjectRepository defines the interface for project data operations
@@ -22,7 +40,7 @@ type ProjectRepository interface {
 	Create(project *domain.Project) error
 	GetByID(id string) (*domain.Project, error)
 	GetByName(name string) (*domain.Project, error)
-	List(opts domain.ProjectListOptions) ([]*domain.Project, error)
+	List(opts domain.ProjectListOptions) ([]*domain.Project, string, error)
 	Update(id string, req domain.UpdateProjectRequest) (*domain.Project, error)
 	Delete(id string) error
 }
@@ -31,9 +49,13 @@ type ProjectRepository interface {
 type InstanceRepository interface {
 	Create(instance *domain.Instance) error
 	GetByID(id string) (*domain.Instance, error)
-	List(opts domain.InstanceListOptions) ([]*domain.Instance, error)
+	List(opts domain.InstanceListOptions) ([]*domain.Instance, string, error)
 	Update(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error)
+	// UpdateIfStatus and DeleteIfStatus only apply while the instance is in the
+	// given status, and return a conflict error otherwise
+	UpdateIfStatus(id, status string, req domain.UpdateInstanceRequest) (*domain.Instance, error)
 	Delete(id string) error
+	DeleteIfStatus(id, status string) error
 }
 
 // MetadataRepository defines the interface for metadata data operations
@@ -42,7 +64,7 @@ type MetadataRepository interface {
 	GetByID(id string) (*domain.Metadata, error)
 	GetByPath(path string) (*domain.Metadata, error)
 	Update(id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error)
-	List(opts domain.MetadataListOptions) ([]*domain.Metadata, error)
+	List(opts domain.MetadataListOptions) ([]*domain.Metadata, string, error)
 	Delete(id string) error
 }
 
@@ -51,7 +73,7 @@ type BucketRepository interface {
 	Create(bucket *domain.Bucket) error
 	GetByID(id string) (*domain.Bucket, error)
 	GetByName(name string) (*domain.Bucket, error)
-	List(opts domain.BucketListOptions) ([]*domain.Bucket, error)
+	List(opts domain.BucketListOptions) ([]*domain.Bucket, string, error)
 	Update(id string, req domain.UpdateBucketRequest) (*domain.Bucket, error)
 	Delete(id string) error
 }
@@ -61,18 +83,87 @@ type ObjectRepository interface {
 	Create(req domain.CreateObjectRequest) (*domain.Object, error)
 	GetByID(id string) (*domain.Object, error)
 	Update(id string, req domain.UpdateObjectRequest) (*domain.Object, error)
-	List(opts domain.ObjectListOptions) ([]*domain.Object, error)
+	List(opts domain.ObjectListOptions) ([]*domain.Object, string, error)
+	Delete(id string) error
+}
+
+// OperationRepository defines the interface for operation data operations
+type OperationRepository interface {
+	Create(op *domain.Operation) error
+	GetByID(id string) (*domain.Operation, error)
+	List(opts domain.OperationListOptions) ([]*domain.Operation, string, error)
+	Update(id string, req domain.UpdateOperationRequest) (*domain.Operation, error)
+}
+
+// TFStateRepository defines the interface for Terraform state data operations.
+// Versions outlive the state they belong to, so a deleted state can be restored.
+type TFStateRepository interface {
+	Get(id string) (*domain.TFState, error)
+	List(opts domain.TFStateListOptions) ([]*domain.TFState, string, error)
+	// Write records state as the next version of a state, numbering the version and
+	// setting its creation time, and makes it the current state
+	Write(version *domain.TFStateVersion, state string) error
 	Delete(id string) error
+	// ListVersions returns a state's versions, newest first
+	ListVersions(id string) ([]*domain.TFStateVersion, error)
+	GetVersion(id string, version int) (*domain.TFStateVersion, string, error)
+	// Lock takes a state's lock unless it is held by a lock that hasn't expired,
+	// in one atomic step. It returns whether the lock was acquired and the lock
+	// that now holds the state.
+	Lock(lock *domain.TFStateLockRecord) (bool, *domain.TFStateLockRecord, error)
+	// GetLock and ListLocks return expired locks too, until they are taken over or released
+	GetLock(id string) (*domain.TFStateLockRecord, error)
+	ListLocks() ([]*domain.TFStateLockRecord, error)
+	// Unlock releases a state's lock if lockID holds it, or whichever lock holds
+	// it if lockID is empty
+	Unlock(id, lockID string) error
 }
 
 // NewService creates a new service instance
-func NewService(projectRepo ProjectRepository, instanceRepo InstanceRepository, metadataRepo MetadataRepository, bucketRepo BucketRepository, objectRepo ObjectRepository) *Service {
+func NewService(projectRepo ProjectRepository, instanceRepo InstanceRepository, metadataRepo MetadataRepository, bucketRepo BucketRepository, objectRepo ObjectRepository, operationRepo OperationRepository, apiKeyRepo APIKeyRepository, tfStateRepo TFStateRepository) *Service {
+	// Access tokens are signed with a random key unless one is configured
+	signingKey := make([]byte, 32)
+	rand.Read(signingKey)
+
+	ctx, stop := context.WithCancel(context.Background())
 	return &Service{
-		projectRepo:  projectRepo,
-		instanceRepo: instanceRepo,
-		metadataRepo: metadataRepo,
-		bucketRepo:   bucketRepo,
-		objectRepo:   objectRepo,
+		projectRepo:   projectRepo,
+		instanceRepo:  instanceRepo,
+		metadataRepo:  metadataRepo,
+		bucketRepo:    bucketRepo,
+		objectRepo:    objectRepo,
+		operationRepo: operationRepo,
+		apiKeyRepo:    apiKeyRepo,
+		tfStateRepo:   tfStateRepo,
+		oauth:         OAuthConfig{SigningKey: signingKey, TokenTTL: DefaultTokenTTL},
+		ctx:           ctx,
+		stop:          stop,
+	}
+}
+
+// goBackground runs fn in a goroutine that Shutdown cancels and waits for
+func (s *Service) goBackground(fn func(ctx context.Context)) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn(s.ctx)
+	}()
+}
+
+// Shutdown cancels background work and waits for it to finish, or for ctx to be
+// done. Call it before closing the repositories.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.stop()
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -173,13 +264,15 @@ func validateInstanceSpecs(cpu int, memoryMB int, image string) error {
 
 // validateInstanceStatus validates instance status
 func validateInstanceStatus(status string) error {
-	if status != domain.StatusRunning && status != domain.StatusStopped {
-		return domain.InvalidInputError("invalid status", map[string]interface{}{
-			"valid_statuses": []string{domain.StatusRunning, domain.StatusStopped},
-			"actual":         status,
-		})
+	for _, validStatus := range domain.ValidInstanceStatuses {
+		if status == validStatus {
+			return nil
+		}
 	}
-	return nil
+	return domain.InvalidInputError("invalid status", map[string]interface{}{
+		"valid_statuses": domain.ValidInstanceStatuses,
+		"actual":         status,
+	})
 }
 
 // validateInstanceRegion validates instance region
@@ -239,8 +332,8 @@ func (s *Service) GetProject(id string) (*domain.Project, error) {
 	return s.projectRepo.GetByID(id)
 }
 
-// ListProjects lists projects with optional filtering
-func (s *Service) ListProjects(opts domain.ProjectListOptions) ([]*domain.Project, error) {
+// ListProjects lists a page of projects with optional filtering and returns the next page token
+func (s *Service) ListProjects(opts domain.ProjectListOptions) ([]*domain.Project, string, error) {
 	return s.projectRepo.List(opts)
 }
 
@@ -281,6 +374,17 @@ func (s *Service) CreateInstance(req domain.CreateInstanceRequest) (*domain.Inst
 	if err := validateInstanceStatus(status); err != nil {
 		return nil, err
 	}
+	// Only the lifecycle moves instances through transitional states
+	if domain.IsTransitionalStatus(status) {
+		return nil, domain.InvalidInputError("transitional statuses cannot be set directly", map[string]interface{}{
+			"valid_statuses": []string{domain.StatusRunning, domain.StatusStopped},
+			"actual":         status,
+		})
+	}
+	// With the lifecycle engine enabled, new instances boot through pending/provisioning
+	if s.lifecycle.Enabled && status == domain.StatusRunning {
+		status = domain.StatusPending
+	}
 
 	// Verify project exists
 	_, err := s.projectRepo.GetByID(req.ProjectID)
@@ -319,8 +423,8 @@ func (s *Service) GetInstance(id string) (*domain.Instance, error) {
 	return s.instanceRepo.GetByID(id)
 }
 
-// ListInstances lists instances with optional filtering
-func (s *Service) ListInstances(opts domain.InstanceListOptions) ([]*domain.Instance, error) {
+// ListInstances lists a page of instances with optional filtering and returns the next page token
+func (s *Service) ListInstances(opts domain.InstanceListOptions) ([]*domain.Instance, string, error) {
 	return s.instanceRepo.List(opts)
 }
 
@@ -373,16 +477,56 @@ func (s *Service) UpdateInstance(id string, req domain.UpdateInstanceRequest) (*
 		if err := validateInstanceStatus(*req.Status); err != nil {
 			return nil, err
 		}
+		// Only the lifecycle moves instances through transitional states
+		if domain.IsTransitionalStatus(*req.Status) && *req.Status != current.Status {
+			return nil, domain.InvalidInputError("transitional statuses cannot be set directly", map[string]interface{}{
+				"valid_statuses": []string{domain.StatusRunning, domain.StatusStopped},
+				"actual":         *req.Status,
+			})
+		}
+		status, err := s.lifecycleStatus(current.Status, *req.Status)
+		if err != nil {
+			return nil, err
+		}
+		req.Status = &status
+		// The status was decided from the one just read, so it only applies if that still holds
+		return s.instanceRepo.UpdateIfStatus(id, current.Status, req)
 	}
 
 	return s.instanceRepo.Update(id, req)
 }
 
 // DeleteInstance deletes an instance
+// With the lifecycle engine enabled the instance is marked terminating and removed later by the worker.
 func (s *Service) DeleteInstance(id string) error {
-	return s.instanceRepo.Delete(id)
+	if !s.lifecycle.Enabled {
+		return s.instanceRepo.Delete(id)
+	}
+
+	// The lifecycle worker and power actions may change the status between the
+	// read and the write, in which case the instance is read again
+	var err error
+	for attempt := 0; attempt < deleteInstanceAttempts; attempt++ {
+		var current *domain.Instance
+		current, err = s.instanceRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if current.Status == domain.StatusTerminating {
+			return nil
+		}
+		status := domain.StatusTerminating
+		_, err = s.instanceRepo.UpdateIfStatus(id, current.Status, domain.UpdateInstanceRequest{Status: &status})
+		if !domain.IsConflict(err) {
+			return err
+		}
+	}
+	return err
 }
 
+// deleteInstanceAttempts bounds how often DeleteInstance retries a status that changed under it
+const deleteInstanceAttempts = 3
+
 // Metadata operations
 
 // CreateMetadata creates new metadata
@@ -421,8 +565,8 @@ func (s *Service) UpdateMetadata(id string, req domain.UpdateMetadataRequest) (*
 	return s.metadataRepo.Update(id, req)
 }
 
-// ListMetadata lists metadata with optional prefix filtering
-func (s *Service) ListMetadata(opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
+// ListMetadata lists a page of metadata with optional prefix filtering and returns the next page token
+func (s *Service) ListMetadata(opts domain.MetadataListOptions) ([]*domain.Metadata, string, error) {
 	return s.metadataRepo.List(opts)
 }
 
@@ -461,8 +605,8 @@ func (s *Service) GetBucketByName(name string) (*domain.Bucket, error) {
 	return s.bucketRepo.GetByName(name)
 }
 
-// ListBuckets lists buckets with optional filtering
-func (s *Service) ListBuckets(opts domain.BucketListOptions) ([]*domain.Bucket, error) {
+// ListBuckets lists a page of buckets with optional filtering and returns the next page token
+func (s *Service) ListBuckets(opts domain.BucketListOptions) ([]*domain.Bucket, string, error) {
 	return s.bucketRepo.List(opts)
 }
 
@@ -525,8 +669,8 @@ func (s *Service) GetObject(id string) (*domain.Object, error) {
 	return s.objectRepo.GetByID(id)
 }
 
-// ListObjects lists objects with optional filtering
-func (s *Service) ListObjects(opts domain.ObjectListOptions) ([]*domain.Object, error) {
+// ListObjects lists a page of objects with optional filtering and returns the next page token
+func (s *Service) ListObjects(opts domain.ObjectListOptions) ([]*domain.Object, string, error) {
 	return s.objectRepo.List(opts)
 }
 
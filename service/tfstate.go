@@ -1,113 +1,303 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"time"
 
 	"github.com/hypertf/nahcloud/domain"
 )
 
-func tfStatePath(stateID string) string      { return "tfstate/" + stateID }
-func tfStateLockPath(stateID string) string { return "tfstate/" + stateID + ".lock" }
+// TFStateConfig controls how Terraform state writes are checked and how long locks last
+type TFStateConfig struct {
+	// Strict rejects writes whose lineage differs from the current state's or
+	// whose serial goes backwards, as real state backends do
+	Strict bool
 
-// metadataByExactPath finds metadata by exact path using prefix listing
-func (s *Service) metadataByExactPath(path string) (*domain.Metadata, error) {
-	items, err := s.metadataRepo.List(domain.MetadataListOptions{Prefix: path})
-	if err != nil {
-		return nil, err
-	}
-	for _, m := range items {
-		if m.Path == path {
-			return m, nil
-		}
-	}
-	return nil, domain.NotFoundError("metadata", path)
+	// LockTTL is how long a lock is held before another LOCK may take it over;
+	// zero keeps locks until they are released
+	LockTTL time.Duration
+}
+
+// SetTFStateConfig configures Terraform state validation and locking
+func (s *Service) SetTFStateConfig(cfg TFStateConfig) {
+	s.tfstate = cfg
+}
+
+// tfStateMeta holds the fields that place a Terraform state in its history
+type tfStateMeta struct {
+	Serial  *int64  `json:"serial"`
+	Lineage *string `json:"lineage"`
 }
 
 // GetTFState returns the raw state JSON for a given state ID
 func (s *Service) GetTFState(stateID string) (string, error) {
-	m, err := s.metadataByExactPath(tfStatePath(stateID))
+	state, err := s.tfStateRepo.Get(stateID)
 	if err != nil {
 		return "", err
 	}
-	return m.Value, nil
+	return state.State, nil
 }
 
-// SetTFState creates or updates the state JSON for a given state ID
-func (s *Service) SetTFState(stateID string, stateJSON string) error {
-	path := tfStatePath(stateID)
-	m, err := s.metadataByExactPath(path)
+// ListTFStates retrieves a page of Terraform states, along with the lock held on each
+func (s *Service) ListTFStates(opts domain.TFStateListOptions) ([]*domain.TFState, string, error) {
+	states, next, err := s.tfStateRepo.List(opts)
 	if err != nil {
-		if domain.IsNotFound(err) {
-			_, err := s.metadataRepo.Create(domain.CreateMetadataRequest{Path: path, Value: stateJSON})
-			return err
+		return nil, "", err
+	}
+	locks, err := s.ListTFStateLocks()
+	if err != nil {
+		return nil, "", err
+	}
+	held := make(map[string]*domain.TFStateLockStatus, len(locks))
+	for _, lock := range locks {
+		held[lock.StateID] = lock
+	}
+	for _, state := range states {
+		state.Lock = held[state.ID]
+		state.Locked = state.Lock != nil
+	}
+	return states, next, nil
+}
+
+// SetTFState creates or updates the state JSON for a given state ID, recording
+// it as a new version. lockID is the lock the writer holds, if any.
+func (s *Service) SetTFState(stateID string, stateJSON string, lockID string) (*domain.TFStateVersion, error) {
+	s.tfstateMu.Lock()
+	defer s.tfstateMu.Unlock()
+
+	if s.tfstate.Strict {
+		if err := s.validateTFState(stateID, stateJSON); err != nil {
+			return nil, err
 		}
-		return err
 	}
-	// Update existing
-	return s.updateMetadataValue(m.ID, stateJSON)
+	return s.writeTFState(stateID, stateJSON, lockID, 0)
 }
 
-// DeleteTFState deletes the state entry if it exists
-func (s *Service) DeleteTFState(stateID string) error {
-	path := tfStatePath(stateID)
-	m, err := s.metadataByExactPath(path)
+// validateTFState checks that a state follows on from the current one: it must
+// have the same lineage and a serial that doesn't go backwards. Rewriting the
+// current serial is only allowed with identical content, so retried writes succeed.
+func (s *Service) validateTFState(stateID, stateJSON string) error {
+	var next tfStateMeta
+	if err := json.Unmarshal([]byte(stateJSON), &next); err != nil {
+		return domain.InvalidInputError("state is not valid JSON", map[string]interface{}{"state_id": stateID})
+	}
+	if next.Serial == nil || next.Lineage == nil || *next.Lineage == "" {
+		return domain.InvalidInputError("state must have a serial and a lineage", map[string]interface{}{"state_id": stateID})
+	}
+
+	current, err := s.GetTFState(stateID)
 	if err != nil {
 		if domain.IsNotFound(err) {
 			return nil
 		}
 		return err
 	}
-	return s.metadataRepo.Delete(m.ID)
+	var cur tfStateMeta
+	if err := json.Unmarshal([]byte(current), &cur); err != nil || cur.Serial == nil {
+		// The current state was written without validation; accept anything over it
+		return nil
+	}
+
+	details := map[string]interface{}{
+		"state_id":       stateID,
+		"serial":         *next.Serial,
+		"lineage":        *next.Lineage,
+		"current_serial": *cur.Serial,
+	}
+	if cur.Lineage != nil {
+		details["current_lineage"] = *cur.Lineage
+	}
+	switch {
+	case cur.Lineage != nil && *cur.Lineage != "" && *cur.Lineage != *next.Lineage:
+		return domain.ConflictError("state lineage does not match the current state", details)
+	case *next.Serial < *cur.Serial:
+		return domain.ConflictError("state serial is older than the current state", details)
+	case *next.Serial == *cur.Serial && stateJSON != current:
+		return domain.ConflictError("state serial matches the current state but its content differs", details)
+	}
+	return nil
+}
+
+// writeTFState records state JSON as the next version of a state and makes it current
+func (s *Service) writeTFState(stateID, stateJSON, lockID string, rollbackFrom int) (*domain.TFStateVersion, error) {
+	// Serial and lineage are recorded when the state has them; bodies aren't validated
+	var meta tfStateMeta
+	json.Unmarshal([]byte(stateJSON), &meta)
+
+	sum := sha256.Sum256([]byte(stateJSON))
+	version := &domain.TFStateVersion{
+		StateID:      stateID,
+		Size:         len(stateJSON),
+		Checksum:     hex.EncodeToString(sum[:]),
+		LockID:       lockID,
+		RollbackFrom: rollbackFrom,
+	}
+	if meta.Serial != nil {
+		version.Serial = *meta.Serial
+	}
+	if meta.Lineage != nil {
+		version.Lineage = *meta.Lineage
+	}
+
+	if err := s.tfStateRepo.Write(version, stateJSON); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// DeleteTFState deletes the state entry if it exists. Its versions are kept, so
+// a deleted state can be rolled back.
+func (s *Service) DeleteTFState(stateID string) error {
+	if err := s.tfStateRepo.Delete(stateID); err != nil && !domain.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// ListTFStateVersions returns the versions of a state, newest first
+func (s *Service) ListTFStateVersions(stateID string) ([]*domain.TFStateVersion, error) {
+	versions, err := s.tfStateRepo.ListVersions(stateID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		// States written before versioning have no history yet
+		if _, err := s.tfStateRepo.Get(stateID); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+// GetTFStateVersion returns a version of a state along with the state JSON it recorded
+func (s *Service) GetTFStateVersion(stateID string, version int) (*domain.TFStateVersion, string, error) {
+	return s.tfStateRepo.GetVersion(stateID, version)
+}
+
+// RollbackTFState makes an earlier version of a state current again. The rollback
+// is recorded as a new version, so history is never rewritten. Strict validation
+// doesn't apply, since a rollback deliberately takes the serial backwards.
+func (s *Service) RollbackTFState(stateID string, version int, lockID string) (*domain.TFStateVersion, error) {
+	s.tfstateMu.Lock()
+	defer s.tfstateMu.Unlock()
+
+	_, stateJSON, err := s.GetTFStateVersion(stateID, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.writeTFState(stateID, stateJSON, lockID, version)
+}
+
+// heldTFStateLock returns the lock held on a state, treating an expired lock as released
+func (s *Service) heldTFStateLock(stateID string) (*domain.TFStateLockRecord, error) {
+	lock, err := s.tfStateRepo.GetLock(stateID)
+	if err != nil {
+		return nil, err
+	}
+	if tfStateLockExpired(lock, time.Now()) {
+		return nil, domain.NotFoundError("tfstate lock", stateID)
+	}
+	return lock, nil
+}
+
+// tfStateLockExpired reports whether a lock has expired by now
+func tfStateLockExpired(lock *domain.TFStateLockRecord, now time.Time) bool {
+	return lock.ExpiresAt != nil && !lock.ExpiresAt.After(now)
+}
+
+// tfStateLockStatus describes a held lock, falling back to just its ID when the
+// stored payload isn't valid lock JSON
+func tfStateLockStatus(lock *domain.TFStateLockRecord) *domain.TFStateLockStatus {
+	status := &domain.TFStateLockStatus{
+		StateID:    lock.StateID,
+		AcquiredAt: lock.CreatedAt,
+		ExpiresAt:  lock.ExpiresAt,
+	}
+	if err := json.Unmarshal([]byte(lock.Info), &status.Lock); err != nil || status.Lock.ID == "" {
+		status.Lock = domain.TFStateLock{ID: lock.LockID}
+	}
+	return status
 }
 
 // GetTFStateLock returns the current lock JSON and parsed lock info if present
 func (s *Service) GetTFStateLock(stateID string) (string, *domain.TFStateLock, error) {
-	m, err := s.metadataByExactPath(tfStateLockPath(stateID))
+	lock, err := s.heldTFStateLock(stateID)
 	if err != nil {
 		return "", nil, err
 	}
 	var li domain.TFStateLock
-	if err := json.Unmarshal([]byte(m.Value), &li); err != nil {
+	if err := json.Unmarshal([]byte(lock.Info), &li); err != nil {
 		// If stored value isn't valid JSON, still return raw
-		return m.Value, nil, nil
+		return lock.Info, nil, nil
 	}
-	return m.Value, &li, nil
+	return lock.Info, &li, nil
 }
 
-// TryLockTFState attempts to acquire a lock; returns existing lock JSON if already locked
-func (s *Service) TryLockTFState(stateID string, lockJSON string) (alreadyLocked bool, existingLockJSON string, err error) {
-	path := tfStateLockPath(stateID)
-	m, err := s.metadataByExactPath(path)
+// GetTFStateLockStatus describes the lock held on a state
+func (s *Service) GetTFStateLockStatus(stateID string) (*domain.TFStateLockStatus, error) {
+	lock, err := s.heldTFStateLock(stateID)
 	if err != nil {
-		if domain.IsNotFound(err) {
-			_, err := s.metadataRepo.Create(domain.CreateMetadataRequest{Path: path, Value: lockJSON})
-			return false, "", err
-		}
-		return false, "", err
+		return nil, err
 	}
-	// Already locked
-	return true, m.Value, nil
+	return tfStateLockStatus(lock), nil
 }
 
-// UnlockTFState removes the lock if present
-func (s *Service) UnlockTFState(stateID string) (existed bool, lockJSON string, err error) {
-	path := tfStateLockPath(stateID)
-	m, err := s.metadataByExactPath(path)
+// ListTFStateLocks describes every lock currently held, ordered by state ID
+func (s *Service) ListTFStateLocks() ([]*domain.TFStateLockStatus, error) {
+	locks, err := s.tfStateRepo.ListLocks()
 	if err != nil {
-		if domain.IsNotFound(err) {
-			return false, "", nil
+		return nil, err
+	}
+	now := time.Now()
+	var statuses []*domain.TFStateLockStatus
+	for _, lock := range locks {
+		if !tfStateLockExpired(lock, now) {
+			statuses = append(statuses, tfStateLockStatus(lock))
 		}
-		return false, "", err
 	}
-	if err := s.metadataRepo.Delete(m.ID); err != nil {
+	return statuses, nil
+}
+
+// TryLockTFState attempts to acquire a lock; returns existing lock JSON if already locked
+func (s *Service) TryLockTFState(stateID string, lockJSON string) (alreadyLocked bool, existingLockJSON string, err error) {
+	var li domain.TFStateLock
+	if err := json.Unmarshal([]byte(lockJSON), &li); err != nil || li.ID == "" {
+		return false, "", domain.InvalidInputError("invalid lock payload: missing or invalid ID", nil)
+	}
+
+	lock := &domain.TFStateLockRecord{StateID: stateID, LockID: li.ID, Info: lockJSON}
+	if s.tfstate.LockTTL > 0 {
+		expiresAt := time.Now().Add(s.tfstate.LockTTL)
+		lock.ExpiresAt = &expiresAt
+	}
+	acquired, holder, err := s.tfStateRepo.Lock(lock)
+	if err != nil {
 		return false, "", err
 	}
-	return true, m.Value, nil
+	if !acquired {
+		return true, holder.Info, nil
+	}
+	return false, "", nil
+}
+
+// UnlockTFState releases a state's lock if lockID holds it, or whichever lock
+// holds it if lockID is empty. It returns a not found error if no such lock is held.
+func (s *Service) UnlockTFState(stateID string, lockID string) error {
+	return s.tfStateRepo.Unlock(stateID, lockID)
 }
 
-// updateMetadataValue is a tiny helper to update only value by ID
-func (s *Service) updateMetadataValue(id string, value string) error {
-	req := domain.UpdateMetadataRequest{Value: &value}
-	_, err := s.metadataRepo.Update(id, req)
-	return err
+// ForceUnlockTFState releases whichever lock is held on a state, for locks left
+// behind by clients that died, and returns the lock it released
+func (s *Service) ForceUnlockTFState(stateID string) (*domain.TFStateLockStatus, error) {
+	lock, err := s.heldTFStateLock(stateID)
+	if err != nil {
+		return nil, err
+	}
+	// Only release the lock that was looked up, never one taken since
+	if err := s.tfStateRepo.Unlock(stateID, lock.LockID); err != nil {
+		return nil, err
+	}
+	return tfStateLockStatus(lock), nil
 }
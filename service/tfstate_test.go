@@ -0,0 +1,240 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTFState_VersionsEveryWrite(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	v1, err := svc.SetTFState("network", `{"version":4,"serial":1,"lineage":"abc"}`, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+	assert.Equal(t, int64(1), v1.Serial)
+	assert.Equal(t, "abc", v1.Lineage)
+
+	state := `{"version":4,"serial":2,"lineage":"abc"}`
+	v2, err := svc.SetTFState("network", state, "lock-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+	assert.Equal(t, "lock-1", v2.LockID)
+	assert.Equal(t, len(state), v2.Size)
+	sum := sha256.Sum256([]byte(state))
+	assert.Equal(t, hex.EncodeToString(sum[:]), v2.Checksum)
+
+	// Other states keep their own history
+	_, err = svc.SetTFState("network-prod", `{"serial":9}`, "")
+	require.NoError(t, err)
+
+	versions, err := svc.ListTFStateVersions("network")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version, "newest first")
+	assert.Equal(t, 1, versions[1].Version)
+
+	got, body, err := svc.GetTFStateVersion("network", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.Serial)
+	assert.Equal(t, `{"version":4,"serial":1,"lineage":"abc"}`, body)
+
+	_, _, err = svc.GetTFStateVersion("network", 3)
+	assert.True(t, domain.IsNotFound(err))
+
+	states, _, err := svc.ListTFStates(domain.TFStateListOptions{})
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, "network", states[0].ID)
+	assert.Equal(t, 2, states[0].Version)
+	assert.Equal(t, int64(2), states[0].Serial)
+	assert.Equal(t, "network-prod", states[1].ID)
+
+	_, _, err = svc.TryLockTFState("network-prod", `{"ID":"lock-2"}`)
+	require.NoError(t, err)
+	states, _, err = svc.ListTFStates(domain.TFStateListOptions{Prefix: "network-"})
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, "network-prod", states[0].ID)
+	assert.True(t, states[0].Locked)
+	assert.Equal(t, "lock-2", states[0].Lock.Lock.ID)
+}
+
+func TestTFState_Rollback(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	_, err := svc.SetTFState("app", `{"serial":1}`, "")
+	require.NoError(t, err)
+	_, err = svc.SetTFState("app", `{"serial":2,"broken":true}`, "")
+	require.NoError(t, err)
+
+	restored, err := svc.RollbackTFState("app", 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, restored.Version, "rollbacks append rather than rewrite history")
+	assert.Equal(t, 1, restored.RollbackFrom)
+
+	current, err := svc.GetTFState("app")
+	require.NoError(t, err)
+	assert.Equal(t, `{"serial":1}`, current)
+
+	versions, err := svc.ListTFStateVersions("app")
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+
+	_, err = svc.RollbackTFState("app", 7, "")
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestTFState_DeleteKeepsHistory(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	_, err := svc.SetTFState("app", `{"serial":1}`, "")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteTFState("app"))
+
+	_, err = svc.GetTFState("app")
+	assert.True(t, domain.IsNotFound(err))
+
+	// A deleted state can be brought back
+	_, err = svc.RollbackTFState("app", 1, "")
+	require.NoError(t, err)
+	current, err := svc.GetTFState("app")
+	require.NoError(t, err)
+	assert.Equal(t, `{"serial":1}`, current)
+
+	_, err = svc.ListTFStateVersions("missing")
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestTFState_StrictValidation(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+	svc.SetTFStateConfig(TFStateConfig{Strict: true})
+
+	conflict := func(t *testing.T, err error) *domain.NahError {
+		t.Helper()
+		require.True(t, domain.IsConflict(err), "expected a conflict, got %v", err)
+		return err.(*domain.NahError)
+	}
+
+	_, err := svc.SetTFState("app", `{"serial":1,"lineage":"aaa"}`, "")
+	require.NoError(t, err)
+	_, err = svc.SetTFState("app", `{"serial":2,"lineage":"aaa"}`, "")
+	require.NoError(t, err)
+
+	// Retrying the current write is allowed
+	_, err = svc.SetTFState("app", `{"serial":2,"lineage":"aaa"}`, "")
+	require.NoError(t, err)
+
+	_, err = svc.SetTFState("app", `{"serial":1,"lineage":"aaa"}`, "")
+	nahErr := conflict(t, err)
+	assert.Equal(t, int64(1), nahErr.Details["serial"])
+	assert.Equal(t, int64(2), nahErr.Details["current_serial"])
+
+	_, err = svc.SetTFState("app", `{"serial":2,"lineage":"aaa","changed":true}`, "")
+	conflict(t, err)
+
+	_, err = svc.SetTFState("app", `{"serial":3,"lineage":"bbb"}`, "")
+	nahErr = conflict(t, err)
+	assert.Equal(t, "bbb", nahErr.Details["lineage"])
+	assert.Equal(t, "aaa", nahErr.Details["current_lineage"])
+
+	for _, body := range []string{`not json`, `{"serial":3}`, `{"lineage":"aaa"}`} {
+		_, err = svc.SetTFState("app", body, "")
+		assert.True(t, domain.IsInvalidInput(err), body)
+	}
+
+	// Rejected writes leave no version behind
+	versions, err := svc.ListTFStateVersions("app")
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+
+	// Rollbacks deliberately go backwards, and later writes follow on from them
+	_, err = svc.RollbackTFState("app", 1, "")
+	require.NoError(t, err)
+	_, err = svc.SetTFState("app", `{"serial":2,"lineage":"aaa","changed":true}`, "")
+	require.NoError(t, err)
+
+	// A new state may start any lineage
+	_, err = svc.SetTFState("other", `{"serial":5,"lineage":"ccc"}`, "")
+	require.NoError(t, err)
+}
+
+func TestTFState_LenientByDefault(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	_, err := svc.SetTFState("app", `{"serial":5,"lineage":"aaa"}`, "")
+	require.NoError(t, err)
+	_, err = svc.SetTFState("app", `{"serial":1,"lineage":"bbb"}`, "")
+	require.NoError(t, err)
+	_, err = svc.SetTFState("app", `not json`, "")
+	require.NoError(t, err)
+}
+
+func TestTFState_LockTTL(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+	svc.SetTFStateConfig(TFStateConfig{LockTTL: 50 * time.Millisecond})
+
+	locked, _, err := svc.TryLockTFState("app", `{"ID":"ci-job","Who":"ci@runner","Operation":"OperationTypeApply"}`)
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	locked, existing, err := svc.TryLockTFState("app", `{"ID":"laptop"}`)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	assert.Contains(t, existing, "ci-job")
+
+	status, err := svc.GetTFStateLockStatus("app")
+	require.NoError(t, err)
+	assert.Equal(t, "ci@runner", status.Lock.Who)
+	require.NotNil(t, status.ExpiresAt)
+	assert.WithinDuration(t, status.AcquiredAt.Add(50*time.Millisecond), *status.ExpiresAt, time.Millisecond)
+
+	// Once it expires the lock no longer shows as held, and a new LOCK takes it over
+	time.Sleep(60 * time.Millisecond)
+	_, err = svc.GetTFStateLockStatus("app")
+	assert.True(t, domain.IsNotFound(err))
+	locks, err := svc.ListTFStateLocks()
+	require.NoError(t, err)
+	assert.Empty(t, locks)
+
+	locked, _, err = svc.TryLockTFState("app", `{"ID":"laptop"}`)
+	require.NoError(t, err)
+	assert.False(t, locked)
+	_, li, err := svc.GetTFStateLock("app")
+	require.NoError(t, err)
+	assert.Equal(t, "laptop", li.ID)
+}
+
+func TestTFState_ForceUnlock(t *testing.T) {
+	svc, _ := setupLifecycleService(t)
+
+	_, _, err := svc.TryLockTFState("network", `{"ID":"one","Who":"alice@ci","Operation":"OperationTypePlan"}`)
+	require.NoError(t, err)
+	_, _, err = svc.TryLockTFState("app", `{"ID":"two"}`)
+	require.NoError(t, err)
+
+	locks, err := svc.ListTFStateLocks()
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	assert.Equal(t, "app", locks[0].StateID)
+	assert.Nil(t, locks[0].ExpiresAt, "locks never expire without a TTL")
+	assert.Equal(t, "network", locks[1].StateID)
+	assert.Equal(t, "alice@ci", locks[1].Lock.Who)
+	assert.Equal(t, "OperationTypePlan", locks[1].Lock.Operation)
+
+	released, err := svc.ForceUnlockTFState("network")
+	require.NoError(t, err)
+	assert.Equal(t, "one", released.Lock.ID)
+
+	_, err = svc.ForceUnlockTFState("network")
+	assert.True(t, domain.IsNotFound(err))
+	locks, err = svc.ListTFStateLocks()
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	assert.Equal(t, "app", locks[0].StateID)
+}
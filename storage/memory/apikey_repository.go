@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// apiKeyRecord is a stored API key along with the hash of its secret
+type apiKeyRecord struct {
+	key  domain.APIKey
+	hash string
+}
+
+// APIKeyRepository handles API key data operations
+type APIKeyRepository struct {
+	store *Store
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(store *Store) *APIKeyRepository {
+	return &APIKeyRepository{store: store}
+}
+
+// Create stores a new API key along with the hash of its secret
+func (r *APIKeyRepository) Create(key *domain.APIKey, keyHash string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.apiKeys[key.ID]; ok {
+		return domain.AlreadyExistsError("api key", "id", key.ID)
+	}
+	for _, record := range r.store.apiKeys {
+		if record.hash == keyHash {
+			return domain.AlreadyExistsError("api key", "prefix", key.Prefix)
+		}
+	}
+
+	if key.ProjectID != "" {
+		if _, ok := r.store.projects[key.ProjectID]; !ok {
+			return domain.ForeignKeyViolationError("project", "id", key.ProjectID)
+		}
+	}
+
+	key.CreatedAt = time.Now()
+	r.store.apiKeys[key.ID] = apiKeyRecord{key: copyAPIKey(*key), hash: keyHash}
+	return nil
+}
+
+// GetByID retrieves an API key by ID
+func (r *APIKeyRepository) GetByID(id string) (*domain.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	record, ok := r.store.apiKeys[id]
+	if !ok {
+		return nil, domain.NotFoundError("api key", id)
+	}
+	key := copyAPIKey(record.key)
+	return &key, nil
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *APIKeyRepository) GetByHash(keyHash string) (*domain.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, record := range r.store.apiKeys {
+		if record.hash == keyHash {
+			key := copyAPIKey(record.key)
+			return &key, nil
+		}
+	}
+	return nil, domain.NotFoundError("api key", "")
+}
+
+// List retrieves a page of API keys ordered by name
+func (r *APIKeyRepository) List(opts domain.APIKeyListOptions) ([]*domain.APIKey, string, error) {
+	key, id, resume, err := pageCursor("api_key", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var keys []*domain.APIKey
+	for _, record := range r.store.apiKeys {
+		if !opts.IncludeRevoked && record.key.IsRevoked() {
+			continue
+		}
+		if resume && !after(record.key.Name, record.key.ID, key, id) {
+			continue
+		}
+		k := copyAPIKey(record.key)
+		keys = append(keys, &k)
+	}
+
+	cursor := func(k *domain.APIKey) (string, string) { return k.Name, k.ID }
+	sortByKey(keys, cursor)
+	keys, next := trimPage("api_key", keys, opts.PageOptions, cursor)
+	return keys, next, nil
+}
+
+// Revoke marks an API key as revoked; revoking an already revoked key keeps its original time
+func (r *APIKeyRepository) Revoke(id string) (*domain.APIKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	record, ok := r.store.apiKeys[id]
+	if !ok {
+		return nil, domain.NotFoundError("api key", id)
+	}
+	if !record.key.IsRevoked() {
+		now := time.Now()
+		record.key.RevokedAt = &now
+		r.store.apiKeys[id] = record
+	}
+	key := copyAPIKey(record.key)
+	return &key, nil
+}
+
+// copyAPIKey copies an API key so the store never shares memory with callers
+func copyAPIKey(key domain.APIKey) domain.APIKey {
+	key.Scopes = append([]string{}, key.Scopes...)
+	if key.RevokedAt != nil {
+		revoked := *key.RevokedAt
+		key.RevokedAt = &revoked
+	}
+	return key
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// BucketRepository handles bucket data operations
+type BucketRepository struct {
+	store *Store
+}
+
+// NewBucketRepository creates a new bucket repository
+func NewBucketRepository(store *Store) *BucketRepository {
+	return &BucketRepository{store: store}
+}
+
+// Create creates a new bucket
+func (r *BucketRepository) Create(bucket *domain.Bucket) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.nameTaken(bucket.Name, "") {
+		return domain.AlreadyExistsError("bucket", "name", bucket.Name)
+	}
+	if _, ok := r.store.buckets[bucket.ID]; ok {
+		return domain.AlreadyExistsError("bucket", "id", bucket.ID)
+	}
+
+	now := time.Now()
+	bucket.CreatedAt = now
+	bucket.UpdatedAt = now
+	r.store.buckets[bucket.ID] = *bucket
+	return nil
+}
+
+// GetByID retrieves a bucket by ID
+func (r *BucketRepository) GetByID(id string) (*domain.Bucket, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	bucket, ok := r.store.buckets[id]
+	if !ok {
+		return nil, domain.NotFoundError("bucket", id)
+	}
+	return &bucket, nil
+}
+
+// GetByName retrieves a bucket by name
+func (r *BucketRepository) GetByName(name string) (*domain.Bucket, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, bucket := range r.store.buckets {
+		if bucket.Name == name {
+			return &bucket, nil
+		}
+	}
+	return nil, domain.NotFoundError("bucket", name)
+}
+
+// List retrieves a page of buckets with optional filtering
+func (r *BucketRepository) List(opts domain.BucketListOptions) ([]*domain.Bucket, string, error) {
+	key, id, resume, err := pageCursor("bucket", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var buckets []*domain.Bucket
+	for _, bucket := range r.store.buckets {
+		if opts.Name != "" && bucket.Name != opts.Name {
+			continue
+		}
+		if resume && !after(bucket.Name, bucket.ID, key, id) {
+			continue
+		}
+		bucket := bucket
+		buckets = append(buckets, &bucket)
+	}
+
+	cursor := func(b *domain.Bucket) (string, string) { return b.Name, b.ID }
+	sortByKey(buckets, cursor)
+	buckets, next := trimPage("bucket", buckets, opts.PageOptions, cursor)
+	return buckets, next, nil
+}
+
+// Update updates an existing bucket
+func (r *BucketRepository) Update(id string, req domain.UpdateBucketRequest) (*domain.Bucket, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	bucket, ok := r.store.buckets[id]
+	if !ok {
+		return nil, domain.NotFoundError("bucket", id)
+	}
+	if r.nameTaken(req.Name, id) {
+		return nil, domain.AlreadyExistsError("bucket", "name", req.Name)
+	}
+
+	bucket.Name = req.Name
+	bucket.UpdatedAt = time.Now()
+	r.store.buckets[id] = bucket
+	return &bucket, nil
+}
+
+// Delete deletes a bucket by ID (and cascades to delete its objects)
+func (r *BucketRepository) Delete(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.buckets[id]; !ok {
+		return domain.NotFoundError("bucket", id)
+	}
+	delete(r.store.buckets, id)
+	for objectID, object := range r.store.objects {
+		if object.BucketID == id {
+			delete(r.store.objects, objectID)
+		}
+	}
+	return nil
+}
+
+// nameTaken reports whether a bucket other than exceptID has the name; the caller holds the lock
+func (r *BucketRepository) nameTaken(name, exceptID string) bool {
+	for _, bucket := range r.store.buckets {
+		if bucket.Name == name && bucket.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/hypertf/nahcloud/storage"
+	"github.com/hypertf/nahcloud/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repositories {
+		backend, err := storage.Open(storage.Memory, "")
+		require.NoError(t, err)
+		return backend.Repositories
+	})
+}
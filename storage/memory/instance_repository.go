@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// InstanceRepository handles instance data operations
+type InstanceRepository struct {
+	store *Store
+}
+
+// NewInstanceRepository creates a new instance repository
+func NewInstanceRepository(store *Store) *InstanceRepository {
+	return &InstanceRepository{store: store}
+}
+
+// Create creates a new instance
+func (r *InstanceRepository) Create(instance *domain.Instance) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.instances[instance.ID]; ok {
+		return domain.AlreadyExistsError("instance", "id", instance.ID)
+	}
+	if r.nameTaken(instance.ProjectID, instance.Name, "") {
+		return domain.AlreadyExistsError("instance", "name", instance.Name)
+	}
+	if _, ok := r.store.projects[instance.ProjectID]; !ok {
+		return domain.ForeignKeyViolationError("project", "id", instance.ProjectID)
+	}
+
+	now := time.Now()
+	instance.StatusChangedAt = now
+	instance.CreatedAt = now
+	instance.UpdatedAt = now
+	r.store.instances[instance.ID] = *instance
+	return nil
+}
+
+// GetByID retrieves an instance by ID
+func (r *InstanceRepository) GetByID(id string) (*domain.Instance, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	instance, ok := r.store.instances[id]
+	if !ok {
+		return nil, domain.NotFoundError("instance", id)
+	}
+	return &instance, nil
+}
+
+// List retrieves a page of instances with optional filtering
+func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.Instance, string, error) {
+	key, id, resume, err := pageCursor("instance", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var instances []*domain.Instance
+	for _, instance := range r.store.instances {
+		if opts.ProjectID != "" && instance.ProjectID != opts.ProjectID {
+			continue
+		}
+		if opts.Name != "" && instance.Name != opts.Name {
+			continue
+		}
+		if opts.Region != "" && instance.Region != opts.Region {
+			continue
+		}
+		if opts.Status != "" && instance.Status != opts.Status {
+			continue
+		}
+		if resume && !after(instance.Name, instance.ID, key, id) {
+			continue
+		}
+		instance := instance
+		instances = append(instances, &instance)
+	}
+
+	// Names are only unique within a project, so the ID breaks ties
+	cursor := func(i *domain.Instance) (string, string) { return i.Name, i.ID }
+	sortByKey(instances, cursor)
+	instances, next := trimPage("instance", instances, opts.PageOptions, cursor)
+	return instances, next, nil
+}
+
+// Update updates an existing instance
+func (r *InstanceRepository) Update(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	return r.update(id, "", req)
+}
+
+// UpdateIfStatus updates an instance only while it is in the given status, and
+// returns a conflict error otherwise
+func (r *InstanceRepository) UpdateIfStatus(id, status string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	return r.update(id, status, req)
+}
+
+// update updates an instance, only while it is in expectedStatus unless that is empty
+func (r *InstanceRepository) update(id, expectedStatus string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	instance, ok := r.store.instances[id]
+	if !ok {
+		return nil, domain.NotFoundError("instance", id)
+	}
+	if expectedStatus != "" && instance.Status != expectedStatus {
+		return nil, instanceStatusConflict(id, expectedStatus, instance.Status)
+	}
+
+	// Update fields that are provided
+	if req.Name != nil {
+		if r.nameTaken(instance.ProjectID, *req.Name, id) {
+			return nil, domain.AlreadyExistsError("instance", "name", *req.Name)
+		}
+		instance.Name = *req.Name
+	}
+	if req.CPU != nil {
+		instance.CPU = *req.CPU
+	}
+	if req.MemoryMB != nil {
+		instance.MemoryMB = *req.MemoryMB
+	}
+	if req.Image != nil {
+		instance.Image = *req.Image
+	}
+	instance.UpdatedAt = time.Now()
+	if req.Status != nil && *req.Status != instance.Status {
+		instance.Status = *req.Status
+		instance.StatusChangedAt = instance.UpdatedAt
+	}
+
+	r.store.instances[id] = instance
+	return &instance, nil
+}
+
+// Delete deletes an instance by ID
+func (r *InstanceRepository) Delete(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.instances[id]; !ok {
+		return domain.NotFoundError("instance", id)
+	}
+	delete(r.store.instances, id)
+	return nil
+}
+
+// DeleteIfStatus deletes an instance only while it is in the given status, and
+// returns a conflict error otherwise
+func (r *InstanceRepository) DeleteIfStatus(id, status string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	instance, ok := r.store.instances[id]
+	if !ok {
+		return domain.NotFoundError("instance", id)
+	}
+	if instance.Status != status {
+		return instanceStatusConflict(id, status, instance.Status)
+	}
+	delete(r.store.instances, id)
+	return nil
+}
+
+// instanceStatusConflict reports that an instance left the status a write expected
+func instanceStatusConflict(id, expected, actual string) error {
+	return domain.ConflictError("instance status changed", map[string]interface{}{
+		"id":              id,
+		"expected_status": expected,
+		"current_status":  actual,
+	})
+}
+
+// nameTaken reports whether an instance other than exceptID in the project has the
+// name; the caller holds the lock
+func (r *InstanceRepository) nameTaken(projectID, name, exceptID string) bool {
+	for _, instance := range r.store.instances {
+		if instance.ProjectID == projectID && instance.Name == name && instance.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// MetadataRepository handles metadata data operations
+type MetadataRepository struct {
+	store *Store
+}
+
+// NewMetadataRepository creates a new metadata repository
+func NewMetadataRepository(store *Store) *MetadataRepository {
+	return &MetadataRepository{store: store}
+}
+
+// Create creates new metadata
+func (r *MetadataRepository) Create(req domain.CreateMetadataRequest) (*domain.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.pathTaken(req.Path, "") {
+		return nil, domain.AlreadyExistsError("metadata", "path", req.Path)
+	}
+
+	now := time.Now()
+	metadata := domain.Metadata{
+		ID:        uuid.New().String(),
+		Path:      req.Path,
+		Value:     req.Value,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.store.metadata[metadata.ID] = metadata
+	return &metadata, nil
+}
+
+// GetByID retrieves metadata by ID
+func (r *MetadataRepository) GetByID(id string) (*domain.Metadata, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	metadata, ok := r.store.metadata[id]
+	if !ok {
+		return nil, domain.NotFoundError("metadata", id)
+	}
+	return &metadata, nil
+}
+
+// GetByPath retrieves metadata by path
+func (r *MetadataRepository) GetByPath(path string) (*domain.Metadata, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, metadata := range r.store.metadata {
+		if metadata.Path == path {
+			return &metadata, nil
+		}
+	}
+	return nil, domain.NotFoundError("metadata", path)
+}
+
+// Update updates existing metadata
+func (r *MetadataRepository) Update(id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	metadata, ok := r.store.metadata[id]
+	if !ok {
+		return nil, domain.NotFoundError("metadata", id)
+	}
+
+	if req.Path != nil {
+		if r.pathTaken(*req.Path, id) {
+			return nil, domain.AlreadyExistsError("metadata", "path", *req.Path)
+		}
+		metadata.Path = *req.Path
+	}
+	if req.Value != nil {
+		metadata.Value = *req.Value
+	}
+	metadata.UpdatedAt = time.Now()
+
+	r.store.metadata[id] = metadata
+	return &metadata, nil
+}
+
+// List retrieves a page of metadata entries with optional prefix filtering
+func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Metadata, string, error) {
+	key, id, resume, err := pageCursor("metadata", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var metadata []*domain.Metadata
+	for _, m := range r.store.metadata {
+		if !strings.HasPrefix(m.Path, opts.Prefix) {
+			continue
+		}
+		if resume && !after(m.Path, m.ID, key, id) {
+			continue
+		}
+		m := m
+		metadata = append(metadata, &m)
+	}
+
+	cursor := func(m *domain.Metadata) (string, string) { return m.Path, m.ID }
+	sortByKey(metadata, cursor)
+	metadata, next := trimPage("metadata", metadata, opts.PageOptions, cursor)
+	return metadata, next, nil
+}
+
+// Delete deletes metadata by ID
+func (r *MetadataRepository) Delete(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.metadata[id]; !ok {
+		return domain.NotFoundError("metadata", id)
+	}
+	delete(r.store.metadata, id)
+	return nil
+}
+
+// pathTaken reports whether metadata other than exceptID has the path; the caller holds the lock
+func (r *MetadataRepository) pathTaken(path, exceptID string) bool {
+	for _, m := range r.store.metadata {
+		if m.Path == path && m.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
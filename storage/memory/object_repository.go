@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// ObjectRepository handles object data operations
+type ObjectRepository struct {
+	store *Store
+}
+
+// NewObjectRepository creates a new object repository
+func NewObjectRepository(store *Store) *ObjectRepository {
+	return &ObjectRepository{store: store}
+}
+
+// Create creates a new object
+func (r *ObjectRepository) Create(req domain.CreateObjectRequest) (*domain.Object, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.pathTaken(req.BucketID, req.Path, "") {
+		return nil, domain.AlreadyExistsError("object", "path", req.Path)
+	}
+	if _, ok := r.store.buckets[req.BucketID]; !ok {
+		return nil, domain.ForeignKeyViolationError("bucket", "id", req.BucketID)
+	}
+
+	now := time.Now()
+	obj := domain.Object{
+		ID:        uuid.New().String(),
+		BucketID:  req.BucketID,
+		Path:      req.Path,
+		Content:   req.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.store.objects[obj.ID] = obj
+	return &obj, nil
+}
+
+// GetByID retrieves an object by ID
+func (r *ObjectRepository) GetByID(id string) (*domain.Object, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	obj, ok := r.store.objects[id]
+	if !ok {
+		return nil, domain.NotFoundError("object", id)
+	}
+	return &obj, nil
+}
+
+// Update updates an existing object
+func (r *ObjectRepository) Update(id string, req domain.UpdateObjectRequest) (*domain.Object, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	obj, ok := r.store.objects[id]
+	if !ok {
+		return nil, domain.NotFoundError("object", id)
+	}
+	if req.Path != nil {
+		if r.pathTaken(obj.BucketID, *req.Path, id) {
+			return nil, domain.AlreadyExistsError("object", "path", *req.Path)
+		}
+		obj.Path = *req.Path
+	}
+	if req.Content != nil {
+		obj.Content = *req.Content
+	}
+	obj.UpdatedAt = time.Now()
+
+	r.store.objects[id] = obj
+	return &obj, nil
+}
+
+// List retrieves a page of objects with optional filtering
+func (r *ObjectRepository) List(opts domain.ObjectListOptions) ([]*domain.Object, string, error) {
+	key, id, resume, err := pageCursor("object", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var objects []*domain.Object
+	for _, obj := range r.store.objects {
+		if opts.BucketID != "" && obj.BucketID != opts.BucketID {
+			continue
+		}
+		if !strings.HasPrefix(obj.Path, opts.Prefix) {
+			continue
+		}
+		if resume && !after(obj.Path, obj.ID, key, id) {
+			continue
+		}
+		obj := obj
+		objects = append(objects, &obj)
+	}
+
+	cursor := func(o *domain.Object) (string, string) { return o.Path, o.ID }
+	sortByKey(objects, cursor)
+	objects, next := trimPage("object", objects, opts.PageOptions, cursor)
+	return objects, next, nil
+}
+
+// Delete deletes an object by ID
+func (r *ObjectRepository) Delete(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.objects[id]; !ok {
+		return domain.NotFoundError("object", id)
+	}
+	delete(r.store.objects, id)
+	return nil
+}
+
+// pathTaken reports whether an object other than exceptID in the bucket has the
+// path; the caller holds the lock
+func (r *ObjectRepository) pathTaken(bucketID, path, exceptID string) bool {
+	for _, obj := range r.store.objects {
+		if obj.BucketID == bucketID && obj.Path == path && obj.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// OperationRepository handles operation data operations
+type OperationRepository struct {
+	store *Store
+}
+
+// NewOperationRepository creates a new operation repository
+func NewOperationRepository(store *Store) *OperationRepository {
+	return &OperationRepository{store: store}
+}
+
+// Create creates a new operation
+// CompletedAt is set automatically when the operation is created in a terminal status.
+func (r *OperationRepository) Create(op *domain.Operation) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.operations[op.ID]; ok {
+		return domain.AlreadyExistsError("operation", "id", op.ID)
+	}
+
+	now := time.Now()
+	op.CreatedAt = now
+	op.UpdatedAt = now
+	if op.IsComplete() && op.CompletedAt == nil {
+		op.CompletedAt = &now
+	}
+	r.store.operations[op.ID] = copyOperation(*op)
+	return nil
+}
+
+// GetByID retrieves an operation by ID
+func (r *OperationRepository) GetByID(id string) (*domain.Operation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	op, ok := r.store.operations[id]
+	if !ok {
+		return nil, domain.NotFoundError("operation", id)
+	}
+	op = copyOperation(op)
+	return &op, nil
+}
+
+// List retrieves a page of operations with optional filtering, newest first
+func (r *OperationRepository) List(opts domain.OperationListOptions) ([]*domain.Operation, string, error) {
+	var createdBefore time.Time
+	key, id, resume, err := pageCursor("operation", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if resume {
+		// Newest first, so the cursor walks backwards in time
+		createdBefore, err = time.Parse(time.RFC3339Nano, key)
+		if err != nil {
+			return nil, "", domain.InvalidPageTokenError(opts.PageToken)
+		}
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var operations []*domain.Operation
+	for _, op := range r.store.operations {
+		if opts.TargetType != "" && op.TargetType != opts.TargetType {
+			continue
+		}
+		if opts.TargetID != "" && op.TargetID != opts.TargetID {
+			continue
+		}
+		if opts.Status != "" && op.Status != opts.Status {
+			continue
+		}
+		if resume && !(op.CreatedAt.Before(createdBefore) || (op.CreatedAt.Equal(createdBefore) && op.ID > id)) {
+			continue
+		}
+		op := copyOperation(op)
+		operations = append(operations, &op)
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		if !operations[i].CreatedAt.Equal(operations[j].CreatedAt) {
+			return operations[i].CreatedAt.After(operations[j].CreatedAt)
+		}
+		return operations[i].ID < operations[j].ID
+	})
+	operations, next := trimPage("operation", operations, opts.PageOptions, func(op *domain.Operation) (string, string) {
+		return op.CreatedAt.Format(time.RFC3339Nano), op.ID
+	})
+	return operations, next, nil
+}
+
+// Update records progress on an existing operation
+// CompletedAt is set automatically once the operation reaches a terminal status.
+func (r *OperationRepository) Update(id string, req domain.UpdateOperationRequest) (*domain.Operation, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	op, ok := r.store.operations[id]
+	if !ok {
+		return nil, domain.NotFoundError("operation", id)
+	}
+	if req.Status != nil {
+		op.Status = *req.Status
+	}
+	if req.TargetID != nil {
+		op.TargetID = *req.TargetID
+	}
+	if req.Error != nil {
+		op.Error = req.Error
+	}
+	op.UpdatedAt = time.Now()
+	if op.IsComplete() && op.CompletedAt == nil {
+		completed := op.UpdatedAt
+		op.CompletedAt = &completed
+	}
+
+	op = copyOperation(op)
+	r.store.operations[id] = op
+	op = copyOperation(op)
+	return &op, nil
+}
+
+// copyOperation copies an operation so the store never shares memory with callers
+func copyOperation(op domain.Operation) domain.Operation {
+	if op.Error != nil {
+		nahErr := *op.Error
+		op.Error = &nahErr
+	}
+	if op.CompletedAt != nil {
+		completed := *op.CompletedAt
+		op.CompletedAt = &completed
+	}
+	return op
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// ProjectRepository handles project data operations
+type ProjectRepository struct {
+	store *Store
+}
+
+// NewProjectRepository creates a new project repository
+func NewProjectRepository(store *Store) *ProjectRepository {
+	return &ProjectRepository{store: store}
+}
+
+// Create creates a new project
+func (r *ProjectRepository) Create(project *domain.Project) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.projects[project.ID]; ok {
+		return domain.AlreadyExistsError("project", "id", project.ID)
+	}
+	if r.nameTaken(project.Name, "") {
+		return domain.AlreadyExistsError("project", "name", project.Name)
+	}
+
+	now := time.Now()
+	project.CreatedAt = now
+	project.UpdatedAt = now
+	r.store.projects[project.ID] = *project
+	return nil
+}
+
+// GetByID retrieves a project by ID
+func (r *ProjectRepository) GetByID(id string) (*domain.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	project, ok := r.store.projects[id]
+	if !ok {
+		return nil, domain.NotFoundError("project", id)
+	}
+	return &project, nil
+}
+
+// GetByName retrieves a project by name
+func (r *ProjectRepository) GetByName(name string) (*domain.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, project := range r.store.projects {
+		if project.Name == name {
+			return &project, nil
+		}
+	}
+	return nil, domain.NotFoundError("project", name)
+}
+
+// List retrieves a page of projects with optional filtering
+func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Project, string, error) {
+	key, id, resume, err := pageCursor("project", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var projects []*domain.Project
+	for _, project := range r.store.projects {
+		if opts.Name != "" && project.Name != opts.Name {
+			continue
+		}
+		if resume && !after(project.Name, project.ID, key, id) {
+			continue
+		}
+		project := project
+		projects = append(projects, &project)
+	}
+
+	cursor := func(p *domain.Project) (string, string) { return p.Name, p.ID }
+	sortByKey(projects, cursor)
+	projects, next := trimPage("project", projects, opts.PageOptions, cursor)
+	return projects, next, nil
+}
+
+// Update updates an existing project
+func (r *ProjectRepository) Update(id string, req domain.UpdateProjectRequest) (*domain.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	project, ok := r.store.projects[id]
+	if !ok {
+		return nil, domain.NotFoundError("project", id)
+	}
+	if r.nameTaken(req.Name, id) {
+		return nil, domain.AlreadyExistsError("project", "name", req.Name)
+	}
+
+	project.Name = req.Name
+	project.UpdatedAt = time.Now()
+	r.store.projects[id] = project
+	return &project, nil
+}
+
+// Delete deletes a project by ID
+// Projects with instances cannot be deleted.
+func (r *ProjectRepository) Delete(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.projects[id]; !ok {
+		return domain.NotFoundError("project", id)
+	}
+
+	instanceCount := 0
+	for _, instance := range r.store.instances {
+		if instance.ProjectID == id {
+			instanceCount++
+		}
+	}
+	if instanceCount > 0 {
+		return domain.InvalidInputError("cannot delete project with existing instances", map[string]interface{}{
+			"project_id":     id,
+			"instance_count": instanceCount,
+		})
+	}
+
+	delete(r.store.projects, id)
+	// API keys restricted to the project go with it
+	for keyID, record := range r.store.apiKeys {
+		if record.key.ProjectID == id {
+			delete(r.store.apiKeys, keyID)
+		}
+	}
+	return nil
+}
+
+// nameTaken reports whether a project other than exceptID has the name; the caller holds the lock
+func (r *ProjectRepository) nameTaken(name, exceptID string) bool {
+	for _, project := range r.store.projects {
+		if project.Name == name && project.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
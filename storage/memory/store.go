@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// Store holds every resource of the in-memory backend, for tests and servers that
+// shouldn't touch disk. Repositories created from the same store share its data and
+// enforce the same uniqueness, reference and cascade rules as the SQLite schema.
+type Store struct {
+	mu         sync.RWMutex
+	projects   map[string]domain.Project
+	instances  map[string]domain.Instance
+	metadata   map[string]domain.Metadata
+	buckets    map[string]domain.Bucket
+	objects    map[string]domain.Object
+	operations map[string]domain.Operation
+	apiKeys    map[string]apiKeyRecord
+
+	tfStates        map[string]domain.TFState
+	tfStateVersions map[string][]tfStateVersionRecord // oldest first
+	tfStateLocks    map[string]domain.TFStateLockRecord
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		projects:   make(map[string]domain.Project),
+		instances:  make(map[string]domain.Instance),
+		metadata:   make(map[string]domain.Metadata),
+		buckets:    make(map[string]domain.Bucket),
+		objects:    make(map[string]domain.Object),
+		operations: make(map[string]domain.Operation),
+		apiKeys:    make(map[string]apiKeyRecord),
+
+		tfStates:        make(map[string]domain.TFState),
+		tfStateVersions: make(map[string][]tfStateVersionRecord),
+		tfStateLocks:    make(map[string]domain.TFStateLockRecord),
+	}
+}
+
+// CountResources returns the number of stored resources of each type
+func (s *Store) CountResources() (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]int64{
+		"projects":   int64(len(s.projects)),
+		"instances":  int64(len(s.instances)),
+		"metadata":   int64(len(s.metadata)),
+		"buckets":    int64(len(s.buckets)),
+		"objects":    int64(len(s.objects)),
+		"operations": int64(len(s.operations)),
+		"api_keys":   int64(len(s.apiKeys)),
+		"tfstates":   int64(len(s.tfStates)),
+	}, nil
+}
+
+// pageCursor returns the position a page token resumes after, or ok false for the first page
+func pageCursor(resource string, page domain.PageOptions) (key string, id string, ok bool, err error) {
+	if page.PageToken == "" {
+		return "", "", false, nil
+	}
+	key, id, err = domain.DecodePageToken(resource, page.PageToken)
+	if err != nil {
+		return "", "", false, err
+	}
+	return key, id, true, nil
+}
+
+// after reports whether an item sorted by (key, id) comes after a cursor
+func after(itemKey, itemID, key, id string) bool {
+	return itemKey > key || (itemKey == key && itemID > id)
+}
+
+// sortByKey orders items by a sort key, breaking ties by ID
+func sortByKey[T any](items []T, cursor func(T) (key string, id string)) {
+	sort.Slice(items, func(i, j int) bool {
+		ki, idi := cursor(items[i])
+		kj, idj := cursor(items[j])
+		return after(kj, idj, ki, idi)
+	})
+}
+
+// trimPage cuts a sorted listing down to the page size and returns the token for
+// the next page, if there is one
+func trimPage[T any](resource string, items []T, page domain.PageOptions, cursor func(T) (key string, id string)) ([]T, string) {
+	if page.PageSize <= 0 || len(items) <= page.PageSize {
+		return items, ""
+	}
+	items = items[:page.PageSize]
+	key, id := cursor(items[len(items)-1])
+	return items, domain.EncodePageToken(resource, key, id)
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// tfStateVersionRecord is a stored state version along with the state it recorded
+type tfStateVersionRecord struct {
+	version domain.TFStateVersion
+	state   string
+}
+
+// TFStateRepository handles Terraform state data operations
+type TFStateRepository struct {
+	store *Store
+}
+
+// NewTFStateRepository creates a new Terraform state repository
+func NewTFStateRepository(store *Store) *TFStateRepository {
+	return &TFStateRepository{store: store}
+}
+
+// Get retrieves the current state by ID
+func (r *TFStateRepository) Get(id string) (*domain.TFState, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	state, ok := r.store.tfStates[id]
+	if !ok {
+		return nil, domain.NotFoundError("tfstate", id)
+	}
+	return &state, nil
+}
+
+// List retrieves a page of states ordered by ID, without their state JSON
+func (r *TFStateRepository) List(opts domain.TFStateListOptions) ([]*domain.TFState, string, error) {
+	key, _, resume, err := pageCursor("tfstate", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var states []*domain.TFState
+	for _, s := range r.store.tfStates {
+		if !strings.HasPrefix(s.ID, opts.Prefix) {
+			continue
+		}
+		if resume && s.ID <= key {
+			continue
+		}
+		s := s
+		s.State = ""
+		states = append(states, &s)
+	}
+
+	cursor := func(s *domain.TFState) (string, string) { return s.ID, s.ID }
+	sortByKey(states, cursor)
+	states, next := trimPage("tfstate", states, opts.PageOptions, cursor)
+	return states, next, nil
+}
+
+// Write records state as the next version of a state and makes it current
+func (r *TFStateRepository) Write(version *domain.TFStateVersion, state string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	version.Version = len(r.store.tfStateVersions[version.StateID]) + 1
+	version.CreatedAt = now
+	r.store.tfStateVersions[version.StateID] = append(r.store.tfStateVersions[version.StateID], tfStateVersionRecord{version: *version, state: state})
+
+	current, ok := r.store.tfStates[version.StateID]
+	if !ok {
+		current = domain.TFState{ID: version.StateID, CreatedAt: now}
+	}
+	current.State = state
+	current.Serial = version.Serial
+	current.Lineage = version.Lineage
+	current.Version = version.Version
+	current.Size = version.Size
+	current.UpdatedAt = now
+	r.store.tfStates[version.StateID] = current
+	return nil
+}
+
+// Delete deletes the current state, keeping its versions
+func (r *TFStateRepository) Delete(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.tfStates[id]; !ok {
+		return domain.NotFoundError("tfstate", id)
+	}
+	delete(r.store.tfStates, id)
+	return nil
+}
+
+// ListVersions returns a state's versions, newest first
+func (r *TFStateRepository) ListVersions(id string) ([]*domain.TFStateVersion, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	records := r.store.tfStateVersions[id]
+	versions := make([]*domain.TFStateVersion, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		v := records[i].version
+		versions = append(versions, &v)
+	}
+	return versions, nil
+}
+
+// GetVersion retrieves a version of a state along with the state JSON it recorded
+func (r *TFStateRepository) GetVersion(id string, version int) (*domain.TFStateVersion, string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	records := r.store.tfStateVersions[id]
+	if version < 1 || version > len(records) {
+		return nil, "", domain.NotFoundError("tfstate version", id+"/"+strconv.Itoa(version))
+	}
+	record := records[version-1]
+	return &record.version, record.state, nil
+}
+
+// Lock takes a state's lock unless it is held by a lock that hasn't expired
+func (r *TFStateRepository) Lock(lock *domain.TFStateLockRecord) (bool, *domain.TFStateLockRecord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	if holder, ok := r.store.tfStateLocks[lock.StateID]; ok && (holder.ExpiresAt == nil || holder.ExpiresAt.After(now)) {
+		return false, &holder, nil
+	}
+	lock.CreatedAt = now
+	r.store.tfStateLocks[lock.StateID] = *lock
+	return true, lock, nil
+}
+
+// GetLock retrieves the lock held on a state
+func (r *TFStateRepository) GetLock(id string) (*domain.TFStateLockRecord, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	lock, ok := r.store.tfStateLocks[id]
+	if !ok {
+		return nil, domain.NotFoundError("tfstate lock", id)
+	}
+	return &lock, nil
+}
+
+// ListLocks retrieves every held lock, ordered by state ID
+func (r *TFStateRepository) ListLocks() ([]*domain.TFStateLockRecord, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	locks := make([]*domain.TFStateLockRecord, 0, len(r.store.tfStateLocks))
+	for _, lock := range r.store.tfStateLocks {
+		lock := lock
+		locks = append(locks, &lock)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].StateID < locks[j].StateID })
+	return locks, nil
+}
+
+// Unlock releases a state's lock if lockID holds it, or whichever lock holds it
+// if lockID is empty
+func (r *TFStateRepository) Unlock(id, lockID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	lock, ok := r.store.tfStateLocks[id]
+	if !ok || (lockID != "" && lock.LockID != lockID) {
+		return domain.NotFoundError("tfstate lock", id)
+	}
+	delete(r.store.tfStateLocks, id)
+	return nil
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// APIKeyRepository handles API key data operations
+type APIKeyRepository struct {
+	db *DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, COALESCE(project_id, ''), created_at, revoked_at`
+
+// Create stores a new API key along with the hash of its secret
+func (r *APIKeyRepository) Create(key *domain.APIKey, keyHash string) error {
+	key.CreatedAt = time.Now()
+
+	// Unrestricted keys have no project to reference
+	query := `INSERT INTO api_keys (id, name, key_hash, prefix, scopes, project_id, created_at) VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?)`
+	_, err := r.db.Exec(query, key.ID, key.Name, keyHash, key.Prefix, strings.Join(key.Scopes, ","), key.ProjectID, key.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: api_keys.id") {
+			return domain.AlreadyExistsError("api key", "id", key.ID)
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: api_keys.key_hash") {
+			return domain.AlreadyExistsError("api key", "prefix", key.Prefix)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("project", "id", key.ProjectID)
+		}
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an API key by ID
+func (r *APIKeyRepository) GetByID(id string) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ?`
+	key, err := scanAPIKey(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("api key", id)
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *APIKeyRepository) GetByHash(keyHash string) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = ?`
+	key, err := scanAPIKey(r.db.QueryRow(query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("api key", "")
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// List retrieves a page of API keys ordered by name
+func (r *APIKeyRepository) List(opts domain.APIKeyListOptions) ([]*domain.APIKey, string, error) {
+	var (
+		keys       []*domain.APIKey
+		args       []interface{}
+		conditions []string
+	)
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+	if !opts.IncludeRevoked {
+		conditions = append(conditions, "revoked_at IS NULL")
+	}
+	cond, pageArgs, err := pageCondition("api_key", "name", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY name, id" + pageLimit(opts.PageOptions)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating api keys: %w", err)
+	}
+	keys, next := trimPage("api_key", keys, opts.PageOptions, func(k *domain.APIKey) (string, string) {
+		return k.Name, k.ID
+	})
+	return keys, next, nil
+}
+
+// Revoke marks an API key as revoked; revoking an already revoked key keeps its original time
+func (r *APIKeyRepository) Revoke(id string) (*domain.APIKey, error) {
+	key, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return key, nil
+	}
+
+	now := time.Now()
+	if _, err := r.db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ?`, now, id); err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	key.RevokedAt = &now
+	return key, nil
+}
+
+// scanAPIKey reads a single API key row
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	var scopes string
+	var revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.ProjectID, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = []string{}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
@@ -67,8 +67,8 @@ func (r *BucketRepository) GetByName(name string) (*domain.Bucket, error) {
 	return bucket, nil
 }
 
-// List retrieves buckets with optional filtering
-func (r *BucketRepository) List(opts domain.BucketListOptions) ([]*domain.Bucket, error) {
+// List retrieves a page of buckets with optional filtering
+func (r *BucketRepository) List(opts domain.BucketListOptions) ([]*domain.Bucket, string, error) {
 	var buckets []*domain.Bucket
 	var args []interface{}
 	query := `SELECT id, name, created_at, updated_at FROM buckets`
@@ -77,26 +77,37 @@ func (r *BucketRepository) List(opts domain.BucketListOptions) ([]*domain.Bucket
 		conditions = append(conditions, "name = ?")
 		args = append(args, opts.Name)
 	}
+	cond, pageArgs, err := pageCondition("bucket", "name", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
-	query += " ORDER BY name"
+	query += " ORDER BY name, id" + pageLimit(opts.PageOptions)
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list buckets: %w", err)
+		return nil, "", fmt.Errorf("failed to list buckets: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		b := &domain.Bucket{}
 		if err := rows.Scan(&b.ID, &b.Name, &b.CreatedAt, &b.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan bucket: %w", err)
+			return nil, "", fmt.Errorf("failed to scan bucket: %w", err)
 		}
 		buckets = append(buckets, b)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating buckets: %w", err)
+		return nil, "", fmt.Errorf("error iterating buckets: %w", err)
 	}
-	return buckets, nil
+	buckets, next := trimPage("bucket", buckets, opts.PageOptions, func(b *domain.Bucket) (string, string) {
+		return b.Name, b.ID
+	})
+	return buckets, next, nil
 }
 
 // Update updates an existing bucket
@@ -0,0 +1,21 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hypertf/nahcloud/storage"
+	"github.com/hypertf/nahcloud/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repositories {
+		// A file rather than :memory:, whose connections each see a separate
+		// database, so the race tests run across real concurrent connections
+		backend, err := storage.Open(storage.SQLite, "file:"+filepath.Join(t.TempDir(), "nah.db")+"?_busy_timeout=5000&_fk=1")
+		require.NoError(t, err)
+		t.Cleanup(func() { backend.Close() })
+		return backend.Repositories
+	})
+}
@@ -1,8 +1,12 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -14,10 +18,196 @@ const (
 // DB wraps the SQLite database connection
 type DB struct {
 	*sql.DB
+	observer QueryObserver
 }
 
-// NewDB creates a new SQLite database connection and initializes the schema
+// QueryObserver is told how long each query took, along with its statement type
+// (select, insert, update, delete or other). Queries are timed until their rows
+// have been read: until Scan for a single row and Close for a result set. Schema
+// migrations run before the observer is set and are not observed.
+type QueryObserver func(statement string, duration time.Duration)
+
+// SetQueryObserver sets the function told about every query; set it before use
+func (db *DB) SetQueryObserver(observer QueryObserver) {
+	db.observer = observer
+}
+
+// Exec executes a query without returning rows
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning rows
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer db.observe(query, time.Now())
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// Query executes a query that returns rows
+func (db *DB) Query(query string, args ...any) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		db.observe(query, start)
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: func() { db.observe(query, start) }}, nil
+}
+
+// QueryRow executes a query that returns at most one row
+func (db *DB) QueryRow(query string, args ...any) *Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that returns at most one row
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	start := time.Now()
+	return &Row{Row: db.DB.QueryRowContext(ctx, query, args...), done: func() { db.observe(query, start) }}
+}
+
+// Begin starts a transaction whose queries are observed like the database's
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction whose queries are observed like the database's
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+func (db *DB) observe(query string, start time.Time) {
+	if db.observer != nil {
+		db.observer(statementType(query), time.Since(start))
+	}
+}
+
+// Tx is a transaction whose queries are reported to the database's observer.
+// Committing is reported as an "other" statement, since that is when SQLite
+// writes the transaction out.
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+// Exec executes a query without returning rows
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	defer tx.db.observe(query, time.Now())
+	return tx.Tx.Exec(query, args...)
+}
+
+// Query executes a query that returns rows
+func (tx *Tx) Query(query string, args ...any) (*Rows, error) {
+	start := time.Now()
+	rows, err := tx.Tx.Query(query, args...)
+	if err != nil {
+		tx.db.observe(query, start)
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: func() { tx.db.observe(query, start) }}, nil
+}
+
+// QueryRow executes a query that returns at most one row
+func (tx *Tx) QueryRow(query string, args ...any) *Row {
+	start := time.Now()
+	return &Row{Row: tx.Tx.QueryRow(query, args...), done: func() { tx.db.observe(query, start) }}
+}
+
+// Commit commits the transaction
+func (tx *Tx) Commit() error {
+	defer tx.db.observe("COMMIT", time.Now())
+	return tx.Tx.Commit()
+}
+
+// Row is the result of QueryRow; the query is observed once it is scanned
+type Row struct {
+	*sql.Row
+	done func()
+}
+
+// Scan copies the row's columns into dest and observes the query
+func (r *Row) Scan(dest ...any) error {
+	defer r.done()
+	return r.Row.Scan(dest...)
+}
+
+// Rows is the result of Query; the query is observed once the rows are closed
+type Rows struct {
+	*sql.Rows
+	done   func()
+	closed sync.Once
+}
+
+// Close closes the rows and observes the query
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.closed.Do(r.done)
+	return err
+}
+
+// statementType returns the lowercased leading keyword of a recognised query
+func statementType(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch keyword := strings.ToLower(fields[0]); keyword {
+	case "select", "insert", "update", "delete":
+		return keyword
+	}
+	return "other"
+}
+
+// resourceTables maps resource types to the tables that hold them
+var resourceTables = map[string]string{
+	"projects":   "projects",
+	"instances":  "instances",
+	"metadata":   "metadata",
+	"buckets":    "buckets",
+	"objects":    "objects",
+	"operations": "operations",
+	"api_keys":   "api_keys",
+	"tfstates":   "tfstates",
+}
+
+// CountResources returns the number of stored resources of each type
+func (db *DB) CountResources() (map[string]int64, error) {
+	counts := make(map[string]int64, len(resourceTables))
+	for resource, table := range resourceTables {
+		var n int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", resource, err)
+		}
+		counts[resource] = n
+	}
+	return counts, nil
+}
+
+// NewDB opens a SQLite database and applies any pending schema migrations
 func NewDB(dsn string) (*DB, error) {
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.MigrateUp(context.Background(), 0); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return db, nil
+}
+
+// OpenDB opens a SQLite database without migrating its schema
+func OpenDB(dsn string) (*DB, error) {
 	if dsn == "" {
 		dsn = defaultDSN
 	}
@@ -46,88 +236,17 @@ func NewDB(dsn string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	sqliteDB := &DB{DB: db}
-
-	// Initialize schema
-	if err := sqliteDB.initSchema(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	return sqliteDB, nil
-}
-
-// initSchema creates the necessary tables if they don't exist
-func (db *DB) initSchema() error {
-	schemas := []string{
-	`CREATE TABLE IF NOT EXISTS projects (
-	id TEXT PRIMARY KEY,
-	name TEXT UNIQUE NOT NULL,
-	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	)`,
-	`CREATE TABLE IF NOT EXISTS instances (
-	id TEXT PRIMARY KEY,
-	project_id TEXT NOT NULL,
-	name TEXT NOT NULL,
-	region TEXT NOT NULL DEFAULT 'us-east-1',
-	cpu INTEGER NOT NULL,
-	memory_mb INTEGER NOT NULL,
-	image TEXT NOT NULL,
-	status TEXT NOT NULL DEFAULT 'running',
-	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
-	UNIQUE(project_id, name)
-	)`,
-	`CREATE TABLE IF NOT EXISTS metadata (
-	id TEXT PRIMARY KEY,
-	path TEXT NOT NULL UNIQUE,
-	value TEXT NOT NULL,
-	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	)`,
-	 `CREATE TABLE IF NOT EXISTS buckets (
-				id TEXT PRIMARY KEY,
-				name TEXT UNIQUE NOT NULL,
-				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-				updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-			)`,
-			`CREATE TABLE IF NOT EXISTS objects (
-				id TEXT PRIMARY KEY,
-				bucket_id TEXT NOT NULL,
-				path TEXT NOT NULL,
-				content TEXT NOT NULL,
-				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-				updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-				FOREIGN KEY (bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
-				UNIQUE(bucket_id, path)
-			)`,
-		}
-
-	for _, schema := range schemas {
-		if _, err := db.Exec(schema); err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
-	}
-
-	// Run migrations for existing databases
-	if err := db.runMigrations(); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	return nil
+	return &DB{DB: db}, nil
 }
 
-// runMigrations applies schema migrations for existing databases
-func (db *DB) runMigrations() error {
-	// Add region column to instances table if it doesn't exist
-	_, err := db.Exec(`ALTER TABLE instances ADD COLUMN region TEXT NOT NULL DEFAULT 'us-east-1'`)
+// CheckSchema verifies that every schema migration has been applied
+func (db *DB) CheckSchema(ctx context.Context) error {
+	pending, err := db.pendingMigrations(ctx)
 	if err != nil {
-		// Ignore error if column already exists
-		if err.Error() != "duplicate column name: region" {
-			// Column might already exist, which is fine
-		}
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d schema migrations pending, starting with version %d", len(pending), pending[0])
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementType(t *testing.T) {
+	assert.Equal(t, "select", statementType("\n\t\tSELECT id FROM projects"))
+	assert.Equal(t, "insert", statementType("insert into projects VALUES (?)"))
+	assert.Equal(t, "update", statementType("UPDATE projects SET name = ?"))
+	assert.Equal(t, "delete", statementType("DELETE FROM projects"))
+	assert.Equal(t, "other", statementType("PRAGMA foreign_keys = ON"))
+	assert.Equal(t, "other", statementType(""))
+}
+
+func TestQueryObserver(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	observed := map[string]int{}
+	db.SetQueryObserver(func(statement string, duration time.Duration) {
+		assert.GreaterOrEqual(t, duration, time.Duration(0))
+		observed[statement]++
+	})
+
+	repo := NewProjectRepository(db)
+	require.NoError(t, repo.Create(&domain.Project{ID: "proj-observed", Name: "observed"}))
+	_, err := repo.GetByID("proj-observed")
+	require.NoError(t, err)
+
+	assert.Positive(t, observed["insert"])
+	assert.Positive(t, observed["select"])
+}
+
+func TestQueryObserver_TransactionsAndResultSets(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	observed := map[string]int{}
+	db.SetQueryObserver(func(statement string, duration time.Duration) {
+		observed[statement]++
+	})
+
+	// A tfstate write is two inserts and a commit in one transaction
+	repo := NewTFStateRepository(db)
+	require.NoError(t, repo.Write(&domain.TFStateVersion{StateID: "s1", Serial: 1, Lineage: "l"}, "{}"))
+	assert.Equal(t, map[string]int{"insert": 2, "other": 1}, observed)
+
+	// A result set is observed once, when it is closed
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM tfstates")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	assert.Zero(t, observed["select"])
+	require.NoError(t, rows.Close())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 1, observed["select"])
+
+	// A single row is observed once it is scanned
+	row := db.QueryRow("SELECT COUNT(*) FROM tfstates")
+	assert.Equal(t, 1, observed["select"])
+	var n int
+	require.NoError(t, row.Scan(&n))
+	assert.Equal(t, 2, observed["select"])
+}
+
+func TestCountResources(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewProjectRepository(db)
+	for _, name := range []string{"one", "two"} {
+		require.NoError(t, repo.Create(&domain.Project{ID: "proj-" + name, Name: name}))
+	}
+
+	counts, err := db.CountResources()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counts["projects"])
+	assert.Equal(t, int64(0), counts["instances"])
+	assert.Len(t, counts, len(resourceTables))
+}
+
+func TestCheckSchema(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	require.NoError(t, db.CheckSchema(context.Background()))
+
+	_, err := db.MigrateDown(context.Background(), 1)
+	require.NoError(t, err)
+	assert.ErrorContains(t, db.CheckSchema(context.Background()), "pending")
+}
@@ -22,12 +22,13 @@ func NewInstanceRepository(db *DB) *InstanceRepository {
 // Create creates a new instance
 func (r *InstanceRepository) Create(instance *domain.Instance) error {
 	now := time.Now()
+	instance.StatusChangedAt = now
 	instance.CreatedAt = now
 	instance.UpdatedAt = now
 
-	query := `INSERT INTO instances (id, project_id, name, region, cpu, memory_mb, image, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO instances (id, project_id, name, region, cpu, memory_mb, image, status, status_changed_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query, instance.ID, instance.ProjectID, instance.Name, instance.Region, instance.CPU, instance.MemoryMB, instance.Image, instance.Status, instance.CreatedAt, instance.UpdatedAt)
+	_, err := r.db.Exec(query, instance.ID, instance.ProjectID, instance.Name, instance.Region, instance.CPU, instance.MemoryMB, instance.Image, instance.Status, instance.StatusChangedAt, instance.CreatedAt, instance.UpdatedAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: instances.project_id, instances.name") {
 			return domain.AlreadyExistsError("instance", "name", instance.Name)
@@ -44,7 +45,7 @@ func (r *InstanceRepository) Create(instance *domain.Instance) error {
 // GetByID retrieves an instance by ID
 func (r *InstanceRepository) GetByID(id string) (*domain.Instance, error) {
 	instance := &domain.Instance{}
-	query := `SELECT id, project_id, name, region, cpu, memory_mb, image, status, created_at, updated_at FROM instances WHERE id = ?`
+	query := `SELECT id, project_id, name, region, cpu, memory_mb, image, status, status_changed_at, created_at, updated_at FROM instances WHERE id = ?`
 
 	err := r.db.QueryRow(query, id).Scan(
 		&instance.ID,
@@ -55,6 +56,7 @@ func (r *InstanceRepository) GetByID(id string) (*domain.Instance, error) {
 		&instance.MemoryMB,
 		&instance.Image,
 		&instance.Status,
+		&instance.StatusChangedAt,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 	)
@@ -68,12 +70,12 @@ func (r *InstanceRepository) GetByID(id string) (*domain.Instance, error) {
 	return instance, nil
 }
 
-// List retrieves instances with optional filtering
-func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.Instance, error) {
+// List retrieves a page of instances with optional filtering
+func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.Instance, string, error) {
 	var instances []*domain.Instance
 	var args []interface{}
 
-	query := `SELECT id, project_id, name, region, cpu, memory_mb, image, status, created_at, updated_at FROM instances`
+	query := `SELECT id, project_id, name, region, cpu, memory_mb, image, status, status_changed_at, created_at, updated_at FROM instances`
 	var conditions []string
 
 	if opts.ProjectID != "" {
@@ -96,15 +98,25 @@ func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.In
 		args = append(args, opts.Status)
 	}
 
+	cond, pageArgs, err := pageCondition("instance", "name", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
+
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY name"
+	// Names are only unique within a project, so the ID breaks ties
+	query += " ORDER BY name, id" + pageLimit(opts.PageOptions)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list instances: %w", err)
+		return nil, "", fmt.Errorf("failed to list instances: %w", err)
 	}
 	defer rows.Close()
 
@@ -119,29 +131,47 @@ func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.In
 			&instance.MemoryMB,
 			&instance.Image,
 			&instance.Status,
+			&instance.StatusChangedAt,
 			&instance.CreatedAt,
 			&instance.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan instance: %w", err)
+			return nil, "", fmt.Errorf("failed to scan instance: %w", err)
 		}
 		instances = append(instances, instance)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating instances: %w", err)
+		return nil, "", fmt.Errorf("error iterating instances: %w", err)
 	}
 
-	return instances, nil
+	instances, next := trimPage("instance", instances, opts.PageOptions, func(i *domain.Instance) (string, string) {
+		return i.Name, i.ID
+	})
+	return instances, next, nil
 }
 
 // Update updates an existing instance
 func (r *InstanceRepository) Update(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	return r.update(id, "", req)
+}
+
+// UpdateIfStatus updates an instance only while it is in the given status, and
+// returns a conflict error otherwise
+func (r *InstanceRepository) UpdateIfStatus(id, status string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	return r.update(id, status, req)
+}
+
+// update updates an instance, only while it is in expectedStatus unless that is empty
+func (r *InstanceRepository) update(id, expectedStatus string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
 	// First check if instance exists
 	existing, err := r.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	if expectedStatus != "" && existing.Status != expectedStatus {
+		return nil, instanceStatusConflict(id, expectedStatus, existing.Status)
+	}
 
 	// Update fields that are provided
 	if req.Name != nil {
@@ -156,20 +186,26 @@ func (r *InstanceRepository) Update(id string, req domain.UpdateInstanceRequest)
 	if req.Image != nil {
 		existing.Image = *req.Image
 	}
-	if req.Status != nil {
+	existing.UpdatedAt = time.Now()
+	if req.Status != nil && *req.Status != existing.Status {
 		existing.Status = *req.Status
+		existing.StatusChangedAt = existing.UpdatedAt
 	}
-	existing.UpdatedAt = time.Now()
 
-	query := `UPDATE instances SET name = ?, cpu = ?, memory_mb = ?, image = ?, status = ?, updated_at = ? WHERE id = ?`
-	
-	_, err = r.db.Exec(query, existing.Name, existing.CPU, existing.MemoryMB, existing.Image, existing.Status, existing.UpdatedAt, id)
+	// The status condition makes the write fail if another one changed the status since it was read
+	query := `UPDATE instances SET name = ?, cpu = ?, memory_mb = ?, image = ?, status = ?, status_changed_at = ?, updated_at = ?
+		WHERE id = ? AND (? = '' OR status = ?)`
+
+	result, err := r.db.Exec(query, existing.Name, existing.CPU, existing.MemoryMB, existing.Image, existing.Status, existing.StatusChangedAt, existing.UpdatedAt, id, expectedStatus, expectedStatus)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: instances.project_id, instances.name") {
 			return nil, domain.AlreadyExistsError("instance", "name", existing.Name)
 		}
 		return nil, fmt.Errorf("failed to update instance: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, r.missedStatus(id, expectedStatus)
+	}
 
 	return existing, nil
 }
@@ -190,4 +226,36 @@ func (r *InstanceRepository) Delete(id string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// DeleteIfStatus deletes an instance only while it is in the given status, and
+// returns a conflict error otherwise
+func (r *InstanceRepository) DeleteIfStatus(id, status string) error {
+	result, err := r.db.Exec(`DELETE FROM instances WHERE id = ? AND status = ?`, id, status)
+	if err != nil {
+		return fmt.Errorf("failed to delete instance: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return r.missedStatus(id, status)
+	}
+	return nil
+}
+
+// missedStatus explains why a conditional write to an instance changed nothing:
+// it was deleted, or its status is no longer expectedStatus
+func (r *InstanceRepository) missedStatus(id, expectedStatus string) error {
+	current, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return instanceStatusConflict(id, expectedStatus, current.Status)
+}
+
+// instanceStatusConflict reports that an instance left the status a write expected
+func instanceStatusConflict(id, expected, actual string) error {
+	return domain.ConflictError("instance status changed", map[string]interface{}{
+		"id":              id,
+		"expected_status": expected,
+		"current_status":  actual,
+	})
+}
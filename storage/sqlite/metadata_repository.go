@@ -134,8 +134,8 @@ func (r *MetadataRepository) Update(id string, req domain.UpdateMetadataRequest)
 	return existing, nil
 }
 
-// List retrieves metadata entries with optional prefix filtering
-func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
+// List retrieves a page of metadata entries with optional prefix filtering
+func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Metadata, string, error) {
 	var metadata []*domain.Metadata
 	var args []interface{}
 	
@@ -148,15 +148,24 @@ func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Me
 		args = append(args, opts.Prefix+"%")
 	}
 
+	cond, pageArgs, err := pageCondition("metadata", "path", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
+
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY path"
+	query += " ORDER BY path, id" + pageLimit(opts.PageOptions)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list metadata: %w", err)
+		return nil, "", fmt.Errorf("failed to list metadata: %w", err)
 	}
 	defer rows.Close()
 
@@ -164,16 +173,19 @@ func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Me
 		m := &domain.Metadata{}
 		err := rows.Scan(&m.ID, &m.Path, &m.Value, &m.CreatedAt, &m.UpdatedAt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan metadata: %w", err)
+			return nil, "", fmt.Errorf("failed to scan metadata: %w", err)
 		}
 		metadata = append(metadata, m)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating metadata: %w", err)
+		return nil, "", fmt.Errorf("error iterating metadata: %w", err)
 	}
 
-	return metadata, nil
+	metadata, next := trimPage("metadata", metadata, opts.PageOptions, func(m *domain.Metadata) (string, string) {
+		return m.Path, m.ID
+	})
+	return metadata, next, nil
 }
 
 // Delete deletes metadata by ID
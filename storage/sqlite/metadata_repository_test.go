@@ -347,7 +347,7 @@ func TestMetadataRepository_List(t *testing.T) {
 				Prefix: tt.prefix,
 			}
 
-			metadata, err := repo.List(opts)
+			metadata, _, err := repo.List(opts)
 			require.NoError(t, err)
 
 			assert.Len(t, metadata, tt.expectedLength)
@@ -460,7 +460,7 @@ func TestMetadataRepository_PathUniqueness(t *testing.T) {
 
 	// Verify only one exists
 	opts := domain.MetadataListOptions{}
-	allMetadata, err := repo.List(opts)
+	allMetadata, _, err := repo.List(opts)
 	require.NoError(t, err)
 	assert.Len(t, allMetadata, 1)
 	assert.Equal(t, metadata1.ID, allMetadata[0].ID)
@@ -0,0 +1,306 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFile matches migration file names, such as 0002_add_buckets.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change with the SQL to apply and revert it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+
+	// step runs after Up in the same transaction, for changes SQL can't make
+	// conditionally
+	step migrationStep
+}
+
+// migrationStep is a schema change made in Go as part of a migration
+type migrationStep func(ctx context.Context, tx *sql.Tx) error
+
+// migrationSteps are the Go steps of the embedded migrations, by version
+var migrationSteps = map[int]migrationStep{
+	1: addInstanceRegion,
+}
+
+// addInstanceRegion adds instances.region to databases created before it existed
+func addInstanceRegion(ctx context.Context, tx *sql.Tx) error {
+	exists, err := hasColumn(ctx, tx, "instances", "region")
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `ALTER TABLE instances ADD COLUMN region TEXT NOT NULL DEFAULT 'us-east-1'`)
+	return err
+}
+
+// hasColumn reports whether a table has a column
+func hasColumn(ctx context.Context, tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// MigrationStatus reports whether a migration has been applied to the database
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// loadMigrations reads migrations from a directory of NNNN_name.up.sql and
+// NNNN_name.down.sql files, ordered by version
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrations returns the migrations embedded in the server, ordered by version
+func Migrations() ([]Migration, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	for i := range migrations {
+		migrations[i].step = migrationSteps[migrations[i].Version]
+	}
+	return migrations, nil
+}
+
+// appliedMigrations returns when each applied migration version was applied
+func (db *DB) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	_, err := db.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus reports which of the embedded migrations have been applied
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return db.migrationStatus(ctx, migrations)
+}
+
+func (db *DB) migrationStatus(ctx context.Context, migrations []Migration) ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	// A newer server may have applied migrations this one doesn't know
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("database has migration %d applied, which this server does not know; it was migrated by a newer version", version)
+		}
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies up to steps pending migrations in version order, or all of
+// them if steps is 0, and returns the versions it applied
+func (db *DB) MigrateUp(ctx context.Context, steps int) ([]int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return db.migrateUp(ctx, migrations, steps)
+}
+
+func (db *DB) migrateUp(ctx context.Context, migrations []Migration, steps int) ([]int, error) {
+	statuses, err := db.migrationStatus(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for i, m := range migrations {
+		if statuses[i].Applied {
+			continue
+		}
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		err := db.inTx(ctx, m.Up, m.step, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().UTC())
+		if err != nil {
+			return done, fmt.Errorf("failed to apply migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts up to steps applied migrations, newest first, and returns
+// the versions it reverted
+func (db *DB) MigrateDown(ctx context.Context, steps int) ([]int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return db.migrateDown(ctx, migrations, steps)
+}
+
+func (db *DB) migrateDown(ctx context.Context, migrations []Migration, steps int) ([]int, error) {
+	statuses, err := db.migrationStatus(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if !statuses[i].Applied {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %d_%s cannot be reverted: it has no down file", m.Version, m.Name)
+		}
+		err := db.inTx(ctx, m.Down, nil, `DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+		if err != nil {
+			return done, fmt.Errorf("failed to revert migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// inTx runs a migration's SQL, its Go step if any, and the matching
+// schema_migrations change in one transaction, so a failed migration leaves no trace
+func (db *DB) inTx(ctx context.Context, script string, step migrationStep, record string, args ...any) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if step != nil {
+		if err := step(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pendingMigrations returns the versions of embedded migrations not yet applied
+func (db *DB) pendingMigrations(ctx context.Context) ([]int, error) {
+	statuses, err := db.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []int
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Version)
+		}
+	}
+	return pending, nil
+}
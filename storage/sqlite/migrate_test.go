@@ -0,0 +1,359 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrations(t *testing.T, files fstest.MapFS) []Migration {
+	t.Helper()
+	migrations, err := loadMigrations(files, "migrations")
+	require.NoError(t, err)
+	return migrations
+}
+
+func tableExists(t *testing.T, db *DB, table string) bool {
+	t.Helper()
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n))
+	return n > 0
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations := testMigrations(t, fstest.MapFS{
+		"migrations/0002_second.up.sql":  {Data: []byte("CREATE TABLE b (id TEXT)")},
+		"migrations/0001_first.up.sql":   {Data: []byte("CREATE TABLE a (id TEXT)")},
+		"migrations/0001_first.down.sql": {Data: []byte("DROP TABLE a")},
+	})
+
+	require.Len(t, migrations, 2)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE a", migrations[0].Down)
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Empty(t, migrations[1].Down)
+
+	_, err := loadMigrations(fstest.MapFS{"migrations/first.sql": {}}, "migrations")
+	assert.ErrorContains(t, err, "invalid migration file name")
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/0001_first.down.sql": {Data: []byte("DROP TABLE a")}}, "migrations")
+	assert.ErrorContains(t, err, "no up file")
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "migration versions are consecutive")
+		assert.NotEmpty(t, m.Down, "migration %d can be reverted", m.Version)
+	}
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus(context.Background())
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "NewDB applies migration %d", s.Version)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	// Every migration reverts cleanly and can be reapplied
+	reverted, err := db.MigrateDown(context.Background(), len(migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(migrations))
+	assert.False(t, tableExists(t, db, "projects"))
+
+	applied, err := db.MigrateUp(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrations))
+	assert.True(t, tableExists(t, db, "projects"))
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db, err := OpenDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	migrations := testMigrations(t, fstest.MapFS{
+		"migrations/0001_a.up.sql":   {Data: []byte("CREATE TABLE a (id TEXT); CREATE TABLE a2 (id TEXT);")},
+		"migrations/0001_a.down.sql": {Data: []byte("DROP TABLE a2; DROP TABLE a;")},
+		"migrations/0002_b.up.sql":   {Data: []byte("CREATE TABLE b (id TEXT)")},
+		"migrations/0002_b.down.sql": {Data: []byte("DROP TABLE b")},
+	})
+
+	applied, err := db.migrateUp(ctx, migrations, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, applied)
+	assert.True(t, tableExists(t, db, "a2"), "every statement in a migration is applied")
+	assert.False(t, tableExists(t, db, "b"))
+
+	applied, err = db.migrateUp(ctx, migrations, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, applied)
+
+	applied, err = db.migrateUp(ctx, migrations, 0)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "applied migrations are not run again")
+
+	reverted, err := db.migrateDown(ctx, migrations, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, reverted)
+	assert.False(t, tableExists(t, db, "b"))
+	assert.True(t, tableExists(t, db, "a"))
+
+	statuses, err := db.migrationStatus(ctx, migrations)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigrateUp_FailureRollsBack(t *testing.T) {
+	db, err := OpenDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	migrations := testMigrations(t, fstest.MapFS{
+		"migrations/0001_ok.up.sql":     {Data: []byte("CREATE TABLE ok (id TEXT)")},
+		"migrations/0002_broken.up.sql": {Data: []byte("CREATE TABLE half (id TEXT); ALTER TABLE missing ADD COLUMN x TEXT;")},
+		"migrations/0003_later.up.sql":  {Data: []byte("CREATE TABLE later (id TEXT)")},
+	})
+
+	applied, err := db.migrateUp(ctx, migrations, 0)
+	assert.ErrorContains(t, err, "failed to apply migration 2_broken")
+	assert.Equal(t, []int{1}, applied)
+	assert.True(t, tableExists(t, db, "ok"))
+	assert.False(t, tableExists(t, db, "half"), "a failed migration is rolled back")
+	assert.False(t, tableExists(t, db, "later"), "later migrations are not applied")
+
+	statuses, err := db.migrationStatus(ctx, migrations)
+	require.NoError(t, err)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigrationStatus_UnknownVersion(t *testing.T) {
+	db, err := OpenDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	newer := testMigrations(t, fstest.MapFS{
+		"migrations/0001_a.up.sql": {Data: []byte("CREATE TABLE a (id TEXT)")},
+		"migrations/0002_b.up.sql": {Data: []byte("CREATE TABLE b (id TEXT)")},
+	})
+	_, err = db.migrateUp(ctx, newer, 0)
+	require.NoError(t, err)
+
+	_, err = db.migrateUp(ctx, newer[:1], 0)
+	assert.ErrorContains(t, err, "newer version")
+}
+
+func TestMigrateDown_Irreversible(t *testing.T) {
+	db, err := OpenDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	migrations := testMigrations(t, fstest.MapFS{
+		"migrations/0001_a.up.sql": {Data: []byte("CREATE TABLE a (id TEXT)")},
+	})
+	_, err = db.migrateUp(ctx, migrations, 0)
+	require.NoError(t, err)
+
+	_, err = db.migrateDown(ctx, migrations, 1)
+	assert.ErrorContains(t, err, "no down file")
+	assert.True(t, tableExists(t, db, "a"))
+}
+
+func TestMigrateUp_AdoptsExistingDatabase(t *testing.T) {
+	// Databases created before versioned migrations have tables but no schema_migrations
+	path := t.TempDir() + "/legacy.db"
+	legacy, err := OpenDB(path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE projects (
+		id TEXT PRIMARY KEY,
+		name TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO projects (id, name) VALUES ('proj-1', 'kept')`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	project, err := NewProjectRepository(db).GetByID("proj-1")
+	require.NoError(t, err)
+	assert.Equal(t, "kept", project.Name)
+	require.NoError(t, db.CheckSchema(context.Background()))
+}
+
+func TestMigrateUp_AddsMissingRegion(t *testing.T) {
+	// The oldest databases have an instances table without a region column
+	path := t.TempDir() + "/legacy.db"
+	legacy, err := OpenDB(path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE projects (
+		id TEXT PRIMARY KEY,
+		name TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE instances (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		cpu INTEGER NOT NULL,
+		memory_mb INTEGER NOT NULL,
+		image TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'running',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		UNIQUE(project_id, name)
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO projects (id, name) VALUES ('proj-1', 'kept')`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO instances (id, project_id, name, cpu, memory_mb, image) VALUES ('inst-1', 'proj-1', 'web', 1, 512, 'ubuntu:22.04')`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	instance, err := NewInstanceRepository(db).GetByID("inst-1")
+	require.NoError(t, err)
+	assert.Equal(t, "web", instance.Name)
+	assert.Equal(t, domain.RegionUSEast1, instance.Region)
+	require.NoError(t, db.CheckSchema(context.Background()))
+}
+
+func TestMigrateTFState_MovesMetadata(t *testing.T) {
+	db, err := OpenDB(t.TempDir() + "/tfstate.db")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Before 0002, states, locks and versions were stored as metadata
+	_, err = db.MigrateUp(ctx, 1)
+	require.NoError(t, err)
+	metadata := NewMetadataRepository(db)
+	for path, value := range map[string]string{
+		"tfstate/app":                     `{"serial":2,"lineage":"abc"}`,
+		"tfstate/app.lock":                `{"ID":"lock-1"}`,
+		"tfstate/app/versions/0000000001": `{"state_id":"app","version":1,"serial":1,"lineage":"abc","size":27,"checksum":"c1","created_at":"2026-01-02T03:04:05.123456789Z","state":"{\"serial\":1,\"lineage\":\"abc\"}"}`,
+		"tfstate/app/versions/0000000002": `{"state_id":"app","version":2,"serial":2,"lineage":"abc","size":27,"checksum":"c2","lock_id":"lock-1","created_at":"2026-01-02T03:04:06Z","state":"{\"serial\":2,\"lineage\":\"abc\"}"}`,
+		"tfstate/legacy":                  `not json`,
+		"app/config":                      `kept`,
+	} {
+		_, err := metadata.Create(domain.CreateMetadataRequest{Path: path, Value: value})
+		require.NoError(t, err)
+	}
+
+	_, err = db.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+
+	tfstates := NewTFStateRepository(db)
+	state, err := tfstates.Get("app")
+	require.NoError(t, err)
+	assert.Equal(t, `{"serial":2,"lineage":"abc"}`, state.State)
+	assert.Equal(t, int64(2), state.Serial)
+	assert.Equal(t, 2, state.Version)
+
+	versions, err := tfstates.ListVersions("app")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "lock-1", versions[0].LockID)
+	assert.Equal(t, "c1", versions[1].Checksum)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC), versions[1].CreatedAt.UTC())
+	_, body, err := tfstates.GetVersion("app", 1)
+	require.NoError(t, err)
+	assert.Equal(t, `{"serial":1,"lineage":"abc"}`, body)
+
+	lock, err := tfstates.GetLock("app")
+	require.NoError(t, err)
+	assert.Equal(t, "lock-1", lock.LockID)
+
+	legacy, err := tfstates.Get("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "not json", legacy.State)
+	assert.Zero(t, legacy.Version)
+
+	items, _, err := metadata.List(domain.MetadataListOptions{})
+	require.NoError(t, err)
+	require.Len(t, items, 1, "only state rows leave metadata")
+	assert.Equal(t, "app/config", items[0].Path)
+
+	// Reverting to the first migration puts the rows back where older versions look for them
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	_, err = db.MigrateDown(ctx, len(migrations)-1)
+	require.NoError(t, err)
+	items, _, err = metadata.List(domain.MetadataListOptions{Prefix: "tfstate/"})
+	require.NoError(t, err)
+	require.Len(t, items, 5)
+	assert.Equal(t, "tfstate/app", items[0].Path)
+	assert.Equal(t, "tfstate/app.lock", items[1].Path)
+	assert.Equal(t, "tfstate/app/versions/0000000001", items[2].Path)
+	var version struct {
+		domain.TFStateVersion
+		State string `json:"state"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(items[2].Value), &version))
+	assert.Equal(t, 1, version.Version)
+	assert.Equal(t, `{"serial":1,"lineage":"abc"}`, version.State)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 123000000, time.UTC), version.CreatedAt.UTC())
+}
+
+func TestMigrateAPIKeys_ReferenceProjects(t *testing.T) {
+	db, err := OpenDB(t.TempDir() + "/keys.db")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Before 0006, unrestricted keys had an empty project and nothing checked the rest
+	_, err = db.MigrateUp(ctx, 5)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO projects (id, name) VALUES ('proj-1', 'web')`)
+	require.NoError(t, err)
+	for id, projectID := range map[string]string{"key-all": "", "key-web": "proj-1", "key-gone": "proj-gone"} {
+		_, err := db.Exec(`INSERT INTO api_keys (id, name, key_hash, prefix, scopes, project_id) VALUES (?, ?, ?, 'nah_x', '*', ?)`, id, id, "hash-"+id, projectID)
+		require.NoError(t, err)
+	}
+
+	_, err = db.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+
+	keys := NewAPIKeyRepository(db)
+	all, err := keys.GetByID("key-all")
+	require.NoError(t, err)
+	assert.Empty(t, all.ProjectID)
+	web, err := keys.GetByID("key-web")
+	require.NoError(t, err)
+	assert.Equal(t, "proj-1", web.ProjectID)
+	_, err = keys.GetByID("key-gone")
+	assert.True(t, domain.IsNotFound(err), "keys for missing projects are dropped")
+
+	// Reverting restores empty projects for unrestricted keys
+	_, err = db.MigrateDown(ctx, 1)
+	require.NoError(t, err)
+	var projectID string
+	require.NoError(t, db.QueryRow(`SELECT project_id FROM api_keys WHERE id = 'key-all'`).Scan(&projectID))
+	assert.Empty(t, projectID)
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS operations;
+DROP TABLE IF EXISTS objects;
+DROP TABLE IF EXISTS buckets;
+DROP TABLE IF EXISTS metadata;
+DROP TABLE IF EXISTS instances;
+DROP TABLE IF EXISTS projects;
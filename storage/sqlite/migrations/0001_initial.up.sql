@@ -0,0 +1,74 @@
+-- Databases created before versioned migrations already have these tables, so
+-- every statement tolerates them existing. The oldest of them lack
+-- instances.region, which a Go step of this migration adds (see migrate.go).
+
+CREATE TABLE IF NOT EXISTS projects (
+	id TEXT PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS instances (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	region TEXT NOT NULL DEFAULT 'us-east-1',
+	cpu INTEGER NOT NULL,
+	memory_mb INTEGER NOT NULL,
+	image TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'running',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS metadata (
+	id TEXT PRIMARY KEY,
+	path TEXT NOT NULL UNIQUE,
+	value TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS buckets (
+	id TEXT PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS objects (
+	id TEXT PRIMARY KEY,
+	bucket_id TEXT NOT NULL,
+	path TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
+	UNIQUE(bucket_id, path)
+);
+
+CREATE TABLE IF NOT EXISTS operations (
+	id TEXT PRIMARY KEY,
+	kind TEXT NOT NULL,
+	target_type TEXT NOT NULL,
+	target_id TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	error TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	prefix TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	project_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at DATETIME
+);
@@ -0,0 +1,31 @@
+INSERT INTO metadata (id, path, value, created_at, updated_at)
+SELECT lower(hex(randomblob(16))), 'tfstate/' || id, state, created_at, updated_at
+FROM tfstates;
+
+INSERT INTO metadata (id, path, value, created_at, updated_at)
+SELECT lower(hex(randomblob(16))), 'tfstate/' || state_id || '.lock', info, created_at, created_at
+FROM tfstate_locks;
+
+INSERT INTO metadata (id, path, value, created_at, updated_at)
+SELECT
+	lower(hex(randomblob(16))),
+	'tfstate/' || state_id || '/versions/' || printf('%010d', version),
+	json_object(
+		'state_id', state_id,
+		'version', version,
+		'serial', serial,
+		'lineage', lineage,
+		'size', size,
+		'checksum', checksum,
+		'lock_id', lock_id,
+		'rollback_from', rollback_from,
+		'created_at', strftime('%Y-%m-%dT%H:%M:%fZ', created_at),
+		'state', state
+	),
+	created_at,
+	created_at
+FROM tfstate_versions;
+
+DROP TABLE IF EXISTS tfstate_locks;
+DROP TABLE IF EXISTS tfstate_versions;
+DROP TABLE IF EXISTS tfstates;
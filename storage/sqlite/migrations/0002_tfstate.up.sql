@@ -0,0 +1,76 @@
+-- Terraform state moves out of metadata paths under tfstate/ into its own tables.
+-- A state's versions outlive it, so they don't reference tfstates.
+
+CREATE TABLE tfstates (
+	id TEXT PRIMARY KEY,
+	state TEXT NOT NULL,
+	serial INTEGER NOT NULL DEFAULT 0,
+	lineage TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 0,
+	size INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE tfstate_versions (
+	state_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	serial INTEGER NOT NULL DEFAULT 0,
+	lineage TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL,
+	checksum TEXT NOT NULL,
+	lock_id TEXT NOT NULL DEFAULT '',
+	rollback_from INTEGER NOT NULL DEFAULT 0,
+	state TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (state_id, version)
+);
+
+CREATE TABLE tfstate_locks (
+	state_id TEXT PRIMARY KEY,
+	lock_id TEXT NOT NULL,
+	info TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Versions were stored as JSON at tfstate/<id>/versions/<version>
+INSERT INTO tfstate_versions (state_id, version, serial, lineage, size, checksum, lock_id, rollback_from, state, created_at)
+SELECT
+	json_extract(value, '$.state_id'),
+	json_extract(value, '$.version'),
+	COALESCE(json_extract(value, '$.serial'), 0),
+	COALESCE(json_extract(value, '$.lineage'), ''),
+	json_extract(value, '$.size'),
+	json_extract(value, '$.checksum'),
+	COALESCE(json_extract(value, '$.lock_id'), ''),
+	COALESCE(json_extract(value, '$.rollback_from'), 0),
+	json_extract(value, '$.state'),
+	json_extract(value, '$.created_at')
+FROM metadata
+WHERE path GLOB 'tfstate/*/versions/*' AND json_valid(value);
+
+-- States were stored at tfstate/<id>
+INSERT INTO tfstates (id, state, serial, lineage, version, size, created_at, updated_at)
+SELECT
+	substr(m.path, 9),
+	m.value,
+	COALESCE(CASE WHEN json_valid(m.value) THEN json_extract(m.value, '$.serial') END, 0),
+	COALESCE(CASE WHEN json_valid(m.value) THEN json_extract(m.value, '$.lineage') END, ''),
+	COALESCE((SELECT MAX(v.version) FROM tfstate_versions v WHERE v.state_id = substr(m.path, 9)), 0),
+	length(CAST(m.value AS BLOB)),
+	m.created_at,
+	m.updated_at
+FROM metadata m
+WHERE m.path GLOB 'tfstate/*' AND m.path NOT GLOB 'tfstate/*/*' AND m.path NOT GLOB 'tfstate/*.lock';
+
+-- Locks were stored at tfstate/<id>.lock
+INSERT INTO tfstate_locks (state_id, lock_id, info, created_at)
+SELECT
+	substr(path, 9, length(path) - 13),
+	COALESCE(CASE WHEN json_valid(value) THEN json_extract(value, '$.ID') END, ''),
+	value,
+	created_at
+FROM metadata
+WHERE path GLOB 'tfstate/*.lock' AND path NOT GLOB 'tfstate/*/*';
+
+DELETE FROM metadata WHERE path GLOB 'tfstate/*';
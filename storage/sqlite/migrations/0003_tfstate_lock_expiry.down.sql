@@ -0,0 +1 @@
+ALTER TABLE tfstate_locks DROP COLUMN expires_at;
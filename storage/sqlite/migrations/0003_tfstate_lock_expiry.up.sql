@@ -0,0 +1,2 @@
+-- Locks taken while a lock TTL is configured expire; NULL never does
+ALTER TABLE tfstate_locks ADD COLUMN expires_at DATETIME;
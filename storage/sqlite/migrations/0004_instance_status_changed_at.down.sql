@@ -0,0 +1 @@
+ALTER TABLE instances DROP COLUMN status_changed_at;
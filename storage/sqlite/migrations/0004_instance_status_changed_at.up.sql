@@ -0,0 +1,3 @@
+-- Lifecycle transitions are timed from the last status change, not the last update
+ALTER TABLE instances ADD COLUMN status_changed_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE instances SET status_changed_at = updated_at;
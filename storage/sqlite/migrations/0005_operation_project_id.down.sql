@@ -0,0 +1 @@
+ALTER TABLE operations DROP COLUMN project_id;
@@ -0,0 +1,5 @@
+-- Operations record their instance's project, so project-restricted API keys
+-- can be limited to the operations on that project
+ALTER TABLE operations ADD COLUMN project_id TEXT NOT NULL DEFAULT '';
+UPDATE operations SET project_id = COALESCE((SELECT project_id FROM instances WHERE instances.id = operations.target_id), '')
+WHERE target_type = 'instance';
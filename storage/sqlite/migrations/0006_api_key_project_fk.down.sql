@@ -0,0 +1,15 @@
+CREATE TABLE api_keys_old (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	prefix TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	project_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at DATETIME
+);
+INSERT INTO api_keys_old (id, name, key_hash, prefix, scopes, project_id, created_at, revoked_at)
+SELECT id, name, key_hash, prefix, scopes, COALESCE(project_id, ''), created_at, revoked_at
+FROM api_keys;
+DROP TABLE api_keys;
+ALTER TABLE api_keys_old RENAME TO api_keys;
@@ -0,0 +1,21 @@
+-- API keys reference the project they are restricted to, and go with it when it
+-- is deleted. Unrestricted keys have a NULL project. SQLite can't add a foreign
+-- key to an existing table, so the table is rebuilt. Keys restricted to a
+-- project that no longer exists could not reach anything and are dropped.
+CREATE TABLE api_keys_new (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	prefix TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	project_id TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at DATETIME,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+INSERT INTO api_keys_new (id, name, key_hash, prefix, scopes, project_id, created_at, revoked_at)
+SELECT id, name, key_hash, prefix, scopes, NULLIF(project_id, ''), created_at, revoked_at
+FROM api_keys
+WHERE project_id = '' OR project_id IN (SELECT id FROM projects);
+DROP TABLE api_keys;
+ALTER TABLE api_keys_new RENAME TO api_keys;
@@ -86,8 +86,8 @@ func (r *ObjectRepository) Update(id string, req domain.UpdateObjectRequest) (*d
 	return obj, nil
 }
 
-// List retrieves objects with optional filtering
-func (r *ObjectRepository) List(opts domain.ObjectListOptions) ([]*domain.Object, error) {
+// List retrieves a page of objects with optional filtering
+func (r *ObjectRepository) List(opts domain.ObjectListOptions) ([]*domain.Object, string, error) {
 	var (
 		objects []*domain.Object
 		args    []interface{}
@@ -102,26 +102,37 @@ func (r *ObjectRepository) List(opts domain.ObjectListOptions) ([]*domain.Object
 		conditions = append(conditions, "path LIKE ?")
 		args = append(args, opts.Prefix+"%")
 	}
+	cond, pageArgs, err := pageCondition("object", "path", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
-	query += " ORDER BY path"
+	query += " ORDER BY path, id" + pageLimit(opts.PageOptions)
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list objects: %w", err)
+		return nil, "", fmt.Errorf("failed to list objects: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		o := &domain.Object{}
 		if err := rows.Scan(&o.ID, &o.BucketID, &o.Path, &o.Content, &o.CreatedAt, &o.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan object: %w", err)
+			return nil, "", fmt.Errorf("failed to scan object: %w", err)
 		}
 		objects = append(objects, o)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating objects: %w", err)
+		return nil, "", fmt.Errorf("error iterating objects: %w", err)
 	}
-	return objects, nil
+	objects, next := trimPage("object", objects, opts.PageOptions, func(o *domain.Object) (string, string) {
+		return o.Path, o.ID
+	})
+	return objects, next, nil
 }
 
 // Delete deletes an object by ID
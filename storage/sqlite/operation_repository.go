@@ -0,0 +1,195 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// OperationRepository handles operation data operations
+type OperationRepository struct {
+	db *DB
+}
+
+// NewOperationRepository creates a new operation repository
+func NewOperationRepository(db *DB) *OperationRepository {
+	return &OperationRepository{db: db}
+}
+
+const operationColumns = `id, kind, target_type, target_id, project_id, status, error, created_at, updated_at, completed_at`
+
+// Create creates a new operation
+// CompletedAt is set automatically when the operation is created in a terminal status.
+func (r *OperationRepository) Create(op *domain.Operation) error {
+	now := time.Now()
+	op.CreatedAt = now
+	op.UpdatedAt = now
+	if op.IsComplete() && op.CompletedAt == nil {
+		op.CompletedAt = &now
+	}
+
+	errJSON, err := encodeOperationError(op.Error)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO operations (` + operationColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(query, op.ID, op.Kind, op.TargetType, op.TargetID, op.ProjectID, op.Status, errJSON, op.CreatedAt, op.UpdatedAt, op.CompletedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: operations.id") {
+			return domain.AlreadyExistsError("operation", "id", op.ID)
+		}
+		return fmt.Errorf("failed to create operation: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an operation by ID
+func (r *OperationRepository) GetByID(id string) (*domain.Operation, error) {
+	query := `SELECT ` + operationColumns + ` FROM operations WHERE id = ?`
+	op, err := scanOperation(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("operation", id)
+		}
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+	return op, nil
+}
+
+// List retrieves a page of operations with optional filtering, newest first
+func (r *OperationRepository) List(opts domain.OperationListOptions) ([]*domain.Operation, string, error) {
+	var (
+		operations []*domain.Operation
+		args       []interface{}
+		conditions []string
+	)
+	query := `SELECT ` + operationColumns + ` FROM operations`
+	if opts.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, opts.TargetType)
+	}
+	if opts.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, opts.TargetID)
+	}
+	if opts.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, opts.Status)
+	}
+	if opts.PageToken != "" {
+		// Newest first, so the keyset walks backwards in time
+		key, id, err := domain.DecodePageToken("operation", opts.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, key)
+		if err != nil {
+			return nil, "", domain.InvalidPageTokenError(opts.PageToken)
+		}
+		conditions = append(conditions, "(created_at < ? OR (created_at = ? AND id > ?))")
+		args = append(args, createdAt, createdAt, id)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id" + pageLimit(opts.PageOptions)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan operation: %w", err)
+		}
+		operations = append(operations, op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating operations: %w", err)
+	}
+	operations, next := trimPage("operation", operations, opts.PageOptions, func(op *domain.Operation) (string, string) {
+		return op.CreatedAt.Format(time.RFC3339Nano), op.ID
+	})
+	return operations, next, nil
+}
+
+// Update records progress on an existing operation
+// CompletedAt is set automatically once the operation reaches a terminal status.
+func (r *OperationRepository) Update(id string, req domain.UpdateOperationRequest) (*domain.Operation, error) {
+	op, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != nil {
+		op.Status = *req.Status
+	}
+	if req.TargetID != nil {
+		op.TargetID = *req.TargetID
+	}
+	if req.Error != nil {
+		op.Error = req.Error
+	}
+	op.UpdatedAt = time.Now()
+	if op.IsComplete() && op.CompletedAt == nil {
+		completed := op.UpdatedAt
+		op.CompletedAt = &completed
+	}
+
+	errJSON, err := encodeOperationError(op.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE operations SET target_id = ?, status = ?, error = ?, updated_at = ?, completed_at = ? WHERE id = ?`
+	_, err = r.db.Exec(query, op.TargetID, op.Status, errJSON, op.UpdatedAt, op.CompletedAt, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update operation: %w", err)
+	}
+	return op, nil
+}
+
+// rowScanner is satisfied by both *Row and *Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOperation reads a single operation row
+func scanOperation(row rowScanner) (*domain.Operation, error) {
+	op := &domain.Operation{}
+	var errJSON sql.NullString
+	var completedAt sql.NullTime
+	err := row.Scan(&op.ID, &op.Kind, &op.TargetType, &op.TargetID, &op.ProjectID, &op.Status, &errJSON, &op.CreatedAt, &op.UpdatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if errJSON.Valid && errJSON.String != "" {
+		var nahErr domain.NahError
+		if err := json.Unmarshal([]byte(errJSON.String), &nahErr); err != nil {
+			return nil, fmt.Errorf("failed to decode operation error: %w", err)
+		}
+		op.Error = &nahErr
+	}
+	if completedAt.Valid {
+		op.CompletedAt = &completedAt.Time
+	}
+	return op, nil
+}
+
+// encodeOperationError serializes an operation error for storage
+func encodeOperationError(nahErr *domain.NahError) (sql.NullString, error) {
+	if nahErr == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(nahErr)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode operation error: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
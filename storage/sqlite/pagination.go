@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// pageCondition returns the keyset condition that resumes a listing ordered by (sortColumn, id)
+// after the position encoded in the page token. It returns an empty condition for the first page.
+func pageCondition(resource, sortColumn string, page domain.PageOptions) (string, []interface{}, error) {
+	if page.PageToken == "" {
+		return "", nil, nil
+	}
+	key, id, err := domain.DecodePageToken(resource, page.PageToken)
+	if err != nil {
+		return "", nil, err
+	}
+	cond := fmt.Sprintf("(%s > ? OR (%s = ? AND id > ?))", sortColumn, sortColumn)
+	return cond, []interface{}{key, key, id}, nil
+}
+
+// pageLimit returns the LIMIT clause for a page, fetching one extra row to detect a following page
+func pageLimit(page domain.PageOptions) string {
+	if page.PageSize <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d", page.PageSize+1)
+}
+
+// trimPage cuts a listing fetched with pageLimit down to the page size and
+// returns the token for the next page, if there is one
+func trimPage[T any](resource string, items []T, page domain.PageOptions, cursor func(T) (key string, id string)) ([]T, string) {
+	if page.PageSize <= 0 || len(items) <= page.PageSize {
+		return items, ""
+	}
+	items = items[:page.PageSize]
+	key, id := cursor(items[len(items)-1])
+	return items, domain.EncodePageToken(resource, key, id)
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagination_WalksAllPages(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewMetadataRepository(db)
+	for i := 0; i < 7; i++ {
+		_, err := repo.Create(domain.CreateMetadataRequest{Path: fmt.Sprintf("/page/%02d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+
+	var paths []string
+	opts := domain.MetadataListOptions{Prefix: "/page/", PageOptions: domain.PageOptions{PageSize: 3}}
+	pages := 0
+	for {
+		items, next, err := repo.List(opts)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(items), 3)
+		for _, m := range items {
+			paths = append(paths, m.Path)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"/page/00", "/page/01", "/page/02", "/page/03", "/page/04", "/page/05", "/page/06"}, paths)
+}
+
+func TestPagination_StableAcrossInserts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewMetadataRepository(db)
+	for _, p := range []string{"/b", "/d", "/f"} {
+		_, err := repo.Create(domain.CreateMetadataRequest{Path: p, Value: "v"})
+		require.NoError(t, err)
+	}
+
+	first, next, err := repo.List(domain.MetadataListOptions{PageOptions: domain.PageOptions{PageSize: 2}})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	require.NotEmpty(t, next)
+
+	// An item inserted before the cursor must not shift the next page
+	_, err = repo.Create(domain.CreateMetadataRequest{Path: "/a", Value: "v"})
+	require.NoError(t, err)
+
+	second, next, err := repo.List(domain.MetadataListOptions{PageOptions: domain.PageOptions{PageSize: 2, PageToken: next}})
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, "/f", second[0].Path)
+	assert.Empty(t, next)
+}
+
+func TestPagination_InvalidToken(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	projects := NewProjectRepository(db)
+	require.NoError(t, projects.Create(&domain.Project{ID: "p1", Name: "one"}))
+	require.NoError(t, projects.Create(&domain.Project{ID: "p2", Name: "two"}))
+
+	_, next, err := projects.List(domain.ProjectListOptions{PageOptions: domain.PageOptions{PageSize: 1}})
+	require.NoError(t, err)
+	require.NotEmpty(t, next)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "!!!"},
+		{name: "not a cursor", token: "bm90LWpzb24"},
+		{name: "token from another listing", token: next},
+	}
+
+	buckets := NewBucketRepository(db)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := buckets.List(domain.BucketListOptions{PageOptions: domain.PageOptions{PageSize: 1, PageToken: tt.token}})
+			require.Error(t, err)
+			assert.True(t, domain.IsInvalidInput(err))
+		})
+	}
+}
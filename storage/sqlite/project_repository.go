@@ -80,8 +80,8 @@ func (r *ProjectRepository) GetByName(name string) (*domain.Project, error) {
 	return project, nil
 }
 
-// List retrieves projects with optional filtering
-func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Project, error) {
+// List retrieves a page of projects with optional filtering
+func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Project, string, error) {
 	var projects []*domain.Project
 	var args []interface{}
 	
@@ -93,15 +93,24 @@ func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Proj
 		args = append(args, opts.Name)
 	}
 
+	cond, pageArgs, err := pageCondition("project", "name", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
+
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY name"
+	query += " ORDER BY name, id" + pageLimit(opts.PageOptions)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list projects: %w", err)
+		return nil, "", fmt.Errorf("failed to list projects: %w", err)
 	}
 	defer rows.Close()
 
@@ -114,16 +123,19 @@ func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Proj
 			&project.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan project: %w", err)
+			return nil, "", fmt.Errorf("failed to scan project: %w", err)
 		}
 		projects = append(projects, project)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating projects: %w", err)
+		return nil, "", fmt.Errorf("error iterating projects: %w", err)
 	}
 
-	return projects, nil
+	projects, next := trimPage("project", projects, opts.PageOptions, func(p *domain.Project) (string, string) {
+		return p.Name, p.ID
+	})
+	return projects, next, nil
 }
 
 // Update updates an existing project
@@ -0,0 +1,296 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+)
+
+// TFStateRepository handles Terraform state data operations
+type TFStateRepository struct {
+	db *DB
+}
+
+// NewTFStateRepository creates a new Terraform state repository
+func NewTFStateRepository(db *DB) *TFStateRepository {
+	return &TFStateRepository{db: db}
+}
+
+const (
+	tfStateVersionColumns = `state_id, version, serial, lineage, size, checksum, lock_id, rollback_from, created_at`
+	tfStateLockColumns    = `state_id, lock_id, info, created_at, expires_at`
+)
+
+// Get retrieves the current state by ID
+func (r *TFStateRepository) Get(id string) (*domain.TFState, error) {
+	state := &domain.TFState{}
+	query := `SELECT id, state, serial, lineage, version, size, created_at, updated_at FROM tfstates WHERE id = ?`
+	err := r.db.QueryRow(query, id).Scan(
+		&state.ID,
+		&state.State,
+		&state.Serial,
+		&state.Lineage,
+		&state.Version,
+		&state.Size,
+		&state.CreatedAt,
+		&state.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("tfstate", id)
+		}
+		return nil, fmt.Errorf("failed to get tfstate: %w", err)
+	}
+	return state, nil
+}
+
+// List retrieves a page of states ordered by ID, without their state JSON
+func (r *TFStateRepository) List(opts domain.TFStateListOptions) ([]*domain.TFState, string, error) {
+	var (
+		states     []*domain.TFState
+		args       []interface{}
+		conditions []string
+	)
+	query := `SELECT id, serial, lineage, version, size, created_at, updated_at FROM tfstates`
+	if opts.Prefix != "" {
+		// instr rather than LIKE, which is case-insensitive and treats _ and % as wildcards
+		conditions = append(conditions, "instr(id, ?) = 1")
+		args = append(args, opts.Prefix)
+	}
+	cond, pageArgs, err := pageCondition("tfstate", "id", opts.PageOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, pageArgs...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id" + pageLimit(opts.PageOptions)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list tfstates: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s := &domain.TFState{}
+		if err := rows.Scan(&s.ID, &s.Serial, &s.Lineage, &s.Version, &s.Size, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, "", fmt.Errorf("failed to scan tfstate: %w", err)
+		}
+		states = append(states, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating tfstates: %w", err)
+	}
+
+	states, next := trimPage("tfstate", states, opts.PageOptions, func(s *domain.TFState) (string, string) {
+		return s.ID, s.ID
+	})
+	return states, next, nil
+}
+
+// Write records state as the next version of a state and makes it current, in
+// one transaction. The version number is taken by the insert itself, so
+// concurrent writers can't be given the same one.
+func (r *TFStateRepository) Write(version *domain.TFStateVersion, state string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tfstate write: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `INSERT INTO tfstate_versions (` + tfStateVersionColumns + `, state)
+		SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, ?, ?, ?, ? FROM tfstate_versions WHERE state_id = ?
+		RETURNING version`
+	err = tx.QueryRow(query,
+		version.StateID, version.Serial, version.Lineage, version.Size, version.Checksum,
+		version.LockID, version.RollbackFrom, now, state, version.StateID,
+	).Scan(&version.Version)
+	if err != nil {
+		return fmt.Errorf("failed to create tfstate version: %w", err)
+	}
+
+	query = `INSERT INTO tfstates (id, state, serial, lineage, version, size, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			state = excluded.state,
+			serial = excluded.serial,
+			lineage = excluded.lineage,
+			version = excluded.version,
+			size = excluded.size,
+			updated_at = excluded.updated_at`
+	_, err = tx.Exec(query, version.StateID, state, version.Serial, version.Lineage, version.Version, version.Size, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to write tfstate: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tfstate write: %w", err)
+	}
+	version.CreatedAt = now
+	return nil
+}
+
+// Delete deletes the current state, keeping its versions
+func (r *TFStateRepository) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM tfstates WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tfstate: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.NotFoundError("tfstate", id)
+	}
+	return nil
+}
+
+// ListVersions returns a state's versions, newest first
+func (r *TFStateRepository) ListVersions(id string) ([]*domain.TFStateVersion, error) {
+	query := `SELECT ` + tfStateVersionColumns + ` FROM tfstate_versions WHERE state_id = ? ORDER BY version DESC`
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tfstate versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*domain.TFStateVersion
+	for rows.Next() {
+		v, err := scanTFStateVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tfstate version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tfstate versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion retrieves a version of a state along with the state JSON it recorded
+func (r *TFStateRepository) GetVersion(id string, version int) (*domain.TFStateVersion, string, error) {
+	var state string
+	query := `SELECT ` + tfStateVersionColumns + `, state FROM tfstate_versions WHERE state_id = ? AND version = ?`
+	v, err := scanTFStateVersion(r.db.QueryRow(query, id, version), &state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", domain.NotFoundError("tfstate version", id+"/"+strconv.Itoa(version))
+		}
+		return nil, "", fmt.Errorf("failed to get tfstate version: %w", err)
+	}
+	return v, state, nil
+}
+
+// Lock takes a state's lock with a single conditional insert, which replaces an
+// expired lock, so of any number of concurrent callers exactly one acquires it
+func (r *TFStateRepository) Lock(lock *domain.TFStateLockRecord) (bool, *domain.TFStateLockRecord, error) {
+	for {
+		lock.CreatedAt = time.Now()
+		query := `INSERT INTO tfstate_locks (state_id, lock_id, info, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(state_id) DO UPDATE SET
+				lock_id = excluded.lock_id,
+				info = excluded.info,
+				created_at = excluded.created_at,
+				expires_at = excluded.expires_at
+			WHERE tfstate_locks.expires_at IS NOT NULL AND julianday(tfstate_locks.expires_at) <= julianday(excluded.created_at)`
+		result, err := r.db.Exec(query, lock.StateID, lock.LockID, lock.Info, lock.CreatedAt, lock.ExpiresAt)
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to lock tfstate: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			return true, lock, nil
+		}
+
+		holder, err := r.GetLock(lock.StateID)
+		if domain.IsNotFound(err) {
+			// Released since the insert; try again
+			continue
+		}
+		if err != nil {
+			return false, nil, err
+		}
+		return false, holder, nil
+	}
+}
+
+// GetLock retrieves the lock held on a state
+func (r *TFStateRepository) GetLock(id string) (*domain.TFStateLockRecord, error) {
+	query := `SELECT ` + tfStateLockColumns + ` FROM tfstate_locks WHERE state_id = ?`
+	lock, err := scanTFStateLock(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("tfstate lock", id)
+		}
+		return nil, fmt.Errorf("failed to get tfstate lock: %w", err)
+	}
+	return lock, nil
+}
+
+// ListLocks retrieves every held lock, ordered by state ID
+func (r *TFStateRepository) ListLocks() ([]*domain.TFStateLockRecord, error) {
+	rows, err := r.db.Query(`SELECT ` + tfStateLockColumns + ` FROM tfstate_locks ORDER BY state_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tfstate locks: %w", err)
+	}
+	defer rows.Close()
+
+	var locks []*domain.TFStateLockRecord
+	for rows.Next() {
+		lock, err := scanTFStateLock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tfstate lock: %w", err)
+		}
+		locks = append(locks, lock)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tfstate locks: %w", err)
+	}
+	return locks, nil
+}
+
+// Unlock releases a state's lock if lockID holds it, or whichever lock holds it
+// if lockID is empty
+func (r *TFStateRepository) Unlock(id, lockID string) error {
+	query := `DELETE FROM tfstate_locks WHERE state_id = ? AND (? = '' OR lock_id = ?)`
+	result, err := r.db.Exec(query, id, lockID, lockID)
+	if err != nil {
+		return fmt.Errorf("failed to unlock tfstate: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.NotFoundError("tfstate lock", id)
+	}
+	return nil
+}
+
+// scanTFStateVersion scans the tfStateVersionColumns of a row, followed by any extra destinations
+func scanTFStateVersion(row rowScanner, extra ...any) (*domain.TFStateVersion, error) {
+	v := &domain.TFStateVersion{}
+	dest := append([]any{
+		&v.StateID, &v.Version, &v.Serial, &v.Lineage, &v.Size, &v.Checksum, &v.LockID, &v.RollbackFrom, &v.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// scanTFStateLock scans the tfStateLockColumns of a row
+func scanTFStateLock(row rowScanner) (*domain.TFStateLockRecord, error) {
+	lock := &domain.TFStateLockRecord{}
+	var expiresAt sql.NullTime
+	if err := row.Scan(&lock.StateID, &lock.LockID, &lock.Info, &lock.CreatedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		lock.ExpiresAt = &expiresAt.Time
+	}
+	return lock, nil
+}
@@ -0,0 +1,91 @@
+// Package storage opens the storage backends the service's repositories are built on
+package storage
+
+import (
+	"fmt"
+
+	"github.com/hypertf/nahcloud/service"
+	"github.com/hypertf/nahcloud/storage/memory"
+	"github.com/hypertf/nahcloud/storage/sqlite"
+)
+
+// Storage backends
+const (
+	SQLite = "sqlite"
+	Memory = "memory"
+)
+
+// Repositories is a complete set of repositories from one storage backend
+type Repositories struct {
+	Projects   service.ProjectRepository
+	Instances  service.InstanceRepository
+	Metadata   service.MetadataRepository
+	Buckets    service.BucketRepository
+	Objects    service.ObjectRepository
+	Operations service.OperationRepository
+	APIKeys    service.APIKeyRepository
+	TFStates   service.TFStateRepository
+}
+
+// Backend is an open storage backend
+type Backend struct {
+	Repositories
+
+	// DB is the SQLite database, or nil for the memory backend
+	DB *sqlite.DB
+
+	// CountResources returns the number of stored resources of each type
+	CountResources func() (map[string]int64, error)
+
+	close func() error
+}
+
+// Open opens a storage backend. sqliteDSN is only used by the SQLite backend,
+// whose schema is migrated to the latest version.
+func Open(backend, sqliteDSN string) (*Backend, error) {
+	switch backend {
+	case Memory:
+		store := memory.NewStore()
+		return &Backend{
+			Repositories: Repositories{
+				Projects:   memory.NewProjectRepository(store),
+				Instances:  memory.NewInstanceRepository(store),
+				Metadata:   memory.NewMetadataRepository(store),
+				Buckets:    memory.NewBucketRepository(store),
+				Objects:    memory.NewObjectRepository(store),
+				Operations: memory.NewOperationRepository(store),
+				APIKeys:    memory.NewAPIKeyRepository(store),
+				TFStates:   memory.NewTFStateRepository(store),
+			},
+			CountResources: store.CountResources,
+			close:          func() error { return nil },
+		}, nil
+
+	case SQLite:
+		db, err := sqlite.NewDB(sqliteDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+		return &Backend{
+			Repositories: Repositories{
+				Projects:   sqlite.NewProjectRepository(db),
+				Instances:  sqlite.NewInstanceRepository(db),
+				Metadata:   sqlite.NewMetadataRepository(db),
+				Buckets:    sqlite.NewBucketRepository(db),
+				Objects:    sqlite.NewObjectRepository(db),
+				Operations: sqlite.NewOperationRepository(db),
+				APIKeys:    sqlite.NewAPIKeyRepository(db),
+				TFStates:   sqlite.NewTFStateRepository(db),
+			},
+			DB:             db,
+			CountResources: db.CountResources,
+			close:          db.Close,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", backend)
+}
+
+// Close releases the backend's resources
+func (b *Backend) Close() error {
+	return b.close()
+}
@@ -0,0 +1,756 @@
+package storagetest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hypertf/nahcloud/domain"
+	"github.com/hypertf/nahcloud/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the conformance suite that every storage backend must pass. newRepos
+// is called once per test and must return repositories over empty storage.
+func Run(t *testing.T, newRepos func(t *testing.T) storage.Repositories) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repos storage.Repositories)
+	}{
+		{"Projects", testProjects},
+		{"ProjectDeleteWithInstances", testProjectDeleteWithInstances},
+		{"Instances", testInstances},
+		{"InstanceReferences", testInstanceReferences},
+		{"InstanceStatusConditions", testInstanceStatusConditions},
+		{"Metadata", testMetadata},
+		{"Buckets", testBuckets},
+		{"BucketDeleteCascades", testBucketDeleteCascades},
+		{"Objects", testObjects},
+		{"Operations", testOperations},
+		{"APIKeys", testAPIKeys},
+		{"Pagination", testPagination},
+		{"TFStates", testTFStates},
+		{"TFStateLocks", testTFStateLocks},
+		{"TFStateLockExpiry", testTFStateLockExpiry},
+		{"TFStateLockRace", testTFStateLockRace},
+		{"TFStateWriteRace", testTFStateWriteRace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepos(t))
+		})
+	}
+}
+
+// assertCode asserts that err is a domain error with the given code
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var nahErr *domain.NahError
+	require.ErrorAs(t, err, &nahErr)
+	assert.Equal(t, code, nahErr.Code)
+}
+
+func createProject(t *testing.T, repos storage.Repositories, id, name string) *domain.Project {
+	t.Helper()
+	project := &domain.Project{ID: id, Name: name}
+	require.NoError(t, repos.Projects.Create(project))
+	return project
+}
+
+func createInstance(t *testing.T, repos storage.Repositories, id, projectID, name string) *domain.Instance {
+	t.Helper()
+	instance := &domain.Instance{
+		ID:        id,
+		ProjectID: projectID,
+		Name:      name,
+		Region:    "us-east-1",
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu:22.04",
+		Status:    domain.StatusRunning,
+	}
+	require.NoError(t, repos.Instances.Create(instance))
+	return instance
+}
+
+func createBucket(t *testing.T, repos storage.Repositories, id, name string) *domain.Bucket {
+	t.Helper()
+	bucket := &domain.Bucket{ID: id, Name: name}
+	require.NoError(t, repos.Buckets.Create(bucket))
+	return bucket
+}
+
+func testProjects(t *testing.T, repos storage.Repositories) {
+	project := createProject(t, repos, "proj-1", "alpha")
+	assert.False(t, project.CreatedAt.IsZero())
+	createProject(t, repos, "proj-2", "beta")
+
+	got, err := repos.Projects.GetByID("proj-1")
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", got.Name)
+	assert.WithinDuration(t, project.CreatedAt, got.CreatedAt, time.Second)
+
+	got, err = repos.Projects.GetByName("beta")
+	require.NoError(t, err)
+	assert.Equal(t, "proj-2", got.ID)
+
+	_, err = repos.Projects.GetByID("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	_, err = repos.Projects.GetByName("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	err = repos.Projects.Create(&domain.Project{ID: "proj-3", Name: "alpha"})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	_, err = repos.Projects.Update("proj-2", domain.UpdateProjectRequest{Name: "alpha"})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+	updated, err := repos.Projects.Update("proj-2", domain.UpdateProjectRequest{Name: "gamma"})
+	require.NoError(t, err)
+	assert.Equal(t, "gamma", updated.Name)
+	_, err = repos.Projects.Update("missing", domain.UpdateProjectRequest{Name: "delta"})
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	// Returned values are copies
+	got.Name = "mutated"
+	got, err = repos.Projects.GetByID("proj-2")
+	require.NoError(t, err)
+	assert.Equal(t, "gamma", got.Name)
+
+	projects, next, err := repos.Projects.List(domain.ProjectListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	require.Len(t, projects, 2)
+	assert.Equal(t, "alpha", projects[0].Name)
+	assert.Equal(t, "gamma", projects[1].Name)
+
+	projects, _, err = repos.Projects.List(domain.ProjectListOptions{Name: "gamma"})
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, "proj-2", projects[0].ID)
+
+	require.NoError(t, repos.Projects.Delete("proj-1"))
+	_, err = repos.Projects.GetByID("proj-1")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	assertCode(t, repos.Projects.Delete("proj-1"), domain.ErrorCodeNotFound)
+}
+
+func testProjectDeleteWithInstances(t *testing.T, repos storage.Repositories) {
+	createProject(t, repos, "proj-1", "alpha")
+	createInstance(t, repos, "inst-1", "proj-1", "web")
+
+	err := repos.Projects.Delete("proj-1")
+	assertCode(t, err, domain.ErrorCodeInvalidInput)
+
+	require.NoError(t, repos.Instances.Delete("inst-1"))
+	require.NoError(t, repos.Projects.Delete("proj-1"))
+}
+
+func testInstances(t *testing.T, repos storage.Repositories) {
+	createProject(t, repos, "proj-1", "alpha")
+	createProject(t, repos, "proj-2", "beta")
+	instance := createInstance(t, repos, "inst-1", "proj-1", "web")
+	assert.False(t, instance.CreatedAt.IsZero())
+	createInstance(t, repos, "inst-2", "proj-1", "db")
+
+	// Names are unique within a project only
+	createInstance(t, repos, "inst-3", "proj-2", "web")
+	err := repos.Instances.Create(&domain.Instance{ID: "inst-4", ProjectID: "proj-1", Name: "web", Region: "us-east-1", CPU: 1, MemoryMB: 512, Image: "ubuntu:22.04", Status: domain.StatusRunning})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	got, err := repos.Instances.GetByID("inst-1")
+	require.NoError(t, err)
+	assert.Equal(t, "web", got.Name)
+	assert.Equal(t, "us-east-1", got.Region)
+	assert.Equal(t, 512, got.MemoryMB)
+	_, err = repos.Instances.GetByID("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	instances, _, err := repos.Instances.List(domain.InstanceListOptions{ProjectID: "proj-1"})
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "db", instances[0].Name)
+	assert.Equal(t, "web", instances[1].Name)
+
+	instances, _, err = repos.Instances.List(domain.InstanceListOptions{Name: "web"})
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+
+	cpu, stopped := 4, domain.StatusStopped
+	updated, err := repos.Instances.Update("inst-2", domain.UpdateInstanceRequest{CPU: &cpu, Status: &stopped})
+	require.NoError(t, err)
+	assert.Equal(t, 4, updated.CPU)
+	assert.Equal(t, "db", updated.Name)
+
+	instances, _, err = repos.Instances.List(domain.InstanceListOptions{Status: domain.StatusStopped})
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "inst-2", instances[0].ID)
+
+	instances, _, err = repos.Instances.List(domain.InstanceListOptions{Region: "eu-west-1"})
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	name := "web"
+	_, err = repos.Instances.Update("inst-2", domain.UpdateInstanceRequest{Name: &name})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+	_, err = repos.Instances.Update("missing", domain.UpdateInstanceRequest{CPU: &cpu})
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	require.NoError(t, repos.Instances.Delete("inst-1"))
+	assertCode(t, repos.Instances.Delete("inst-1"), domain.ErrorCodeNotFound)
+}
+
+func testInstanceReferences(t *testing.T, repos storage.Repositories) {
+	err := repos.Instances.Create(&domain.Instance{ID: "inst-1", ProjectID: "missing", Name: "web", Region: "us-east-1", CPU: 1, MemoryMB: 512, Image: "ubuntu:22.04", Status: domain.StatusRunning})
+	assertCode(t, err, domain.ErrorCodeForeignKeyViolation)
+}
+
+func testInstanceStatusConditions(t *testing.T, repos storage.Repositories) {
+	createProject(t, repos, "proj-1", "alpha")
+	instance := createInstance(t, repos, "inst-1", "proj-1", "web")
+	assert.False(t, instance.StatusChangedAt.IsZero())
+
+	// Only status changes move StatusChangedAt
+	name := "api"
+	renamed, err := repos.Instances.Update("inst-1", domain.UpdateInstanceRequest{Name: &name})
+	require.NoError(t, err)
+	assert.True(t, renamed.StatusChangedAt.Equal(instance.StatusChangedAt))
+	assert.True(t, renamed.UpdatedAt.After(instance.StatusChangedAt))
+
+	stopping, stopped := domain.StatusStopping, domain.StatusStopped
+	_, err = repos.Instances.UpdateIfStatus("inst-1", domain.StatusStopped, domain.UpdateInstanceRequest{Status: &stopping})
+	assertCode(t, err, domain.ErrorCodeConflict)
+	updated, err := repos.Instances.UpdateIfStatus("inst-1", domain.StatusRunning, domain.UpdateInstanceRequest{Status: &stopping})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopping, updated.Status)
+	assert.True(t, updated.StatusChangedAt.After(instance.StatusChangedAt))
+
+	got, err := repos.Instances.GetByID("inst-1")
+	require.NoError(t, err)
+	assert.True(t, got.StatusChangedAt.Equal(updated.StatusChangedAt))
+	_, err = repos.Instances.UpdateIfStatus("missing", domain.StatusRunning, domain.UpdateInstanceRequest{Status: &stopped})
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	assertCode(t, repos.Instances.DeleteIfStatus("inst-1", domain.StatusTerminating), domain.ErrorCodeConflict)
+	require.NoError(t, repos.Instances.DeleteIfStatus("inst-1", domain.StatusStopping))
+	assertCode(t, repos.Instances.DeleteIfStatus("inst-1", domain.StatusStopping), domain.ErrorCodeNotFound)
+}
+
+func testMetadata(t *testing.T, repos storage.Repositories) {
+	created, err := repos.Metadata.Create(domain.CreateMetadataRequest{Path: "/app/config", Value: "a"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	_, err = repos.Metadata.Create(domain.CreateMetadataRequest{Path: "/app/secret", Value: "b"})
+	require.NoError(t, err)
+	other, err := repos.Metadata.Create(domain.CreateMetadataRequest{Path: "/other", Value: "c"})
+	require.NoError(t, err)
+
+	_, err = repos.Metadata.Create(domain.CreateMetadataRequest{Path: "/app/config", Value: "dup"})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	got, err := repos.Metadata.GetByPath("/app/config")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	_, err = repos.Metadata.GetByPath("/missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	_, err = repos.Metadata.GetByID("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	items, _, err := repos.Metadata.List(domain.MetadataListOptions{Prefix: "/app/"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "/app/config", items[0].Path)
+	assert.Equal(t, "/app/secret", items[1].Path)
+
+	path := "/app/config"
+	_, err = repos.Metadata.Update(other.ID, domain.UpdateMetadataRequest{Path: &path})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	value := "updated"
+	updated, err := repos.Metadata.Update(other.ID, domain.UpdateMetadataRequest{Value: &value})
+	require.NoError(t, err)
+	assert.Equal(t, "/other", updated.Path)
+	assert.Equal(t, "updated", updated.Value)
+
+	require.NoError(t, repos.Metadata.Delete(other.ID))
+	assertCode(t, repos.Metadata.Delete(other.ID), domain.ErrorCodeNotFound)
+}
+
+func testBuckets(t *testing.T, repos storage.Repositories) {
+	bucket := createBucket(t, repos, "bucket-1", "assets")
+	assert.False(t, bucket.CreatedAt.IsZero())
+	createBucket(t, repos, "bucket-2", "backups")
+
+	err := repos.Buckets.Create(&domain.Bucket{ID: "bucket-3", Name: "assets"})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	got, err := repos.Buckets.GetByName("backups")
+	require.NoError(t, err)
+	assert.Equal(t, "bucket-2", got.ID)
+	_, err = repos.Buckets.GetByName("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	_, err = repos.Buckets.Update("bucket-2", domain.UpdateBucketRequest{Name: "assets"})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+	updated, err := repos.Buckets.Update("bucket-2", domain.UpdateBucketRequest{Name: "archive"})
+	require.NoError(t, err)
+	assert.Equal(t, "archive", updated.Name)
+
+	buckets, _, err := repos.Buckets.List(domain.BucketListOptions{})
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "archive", buckets[0].Name)
+	assert.Equal(t, "assets", buckets[1].Name)
+
+	require.NoError(t, repos.Buckets.Delete("bucket-1"))
+	assertCode(t, repos.Buckets.Delete("bucket-1"), domain.ErrorCodeNotFound)
+}
+
+func testBucketDeleteCascades(t *testing.T, repos storage.Repositories) {
+	createBucket(t, repos, "bucket-1", "assets")
+	createBucket(t, repos, "bucket-2", "backups")
+	obj, err := repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-1", Path: "a.txt", Content: "a"})
+	require.NoError(t, err)
+	kept, err := repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-2", Path: "a.txt", Content: "a"})
+	require.NoError(t, err)
+
+	require.NoError(t, repos.Buckets.Delete("bucket-1"))
+
+	_, err = repos.Objects.GetByID(obj.ID)
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	_, err = repos.Objects.GetByID(kept.ID)
+	require.NoError(t, err)
+}
+
+func testObjects(t *testing.T, repos storage.Repositories) {
+	createBucket(t, repos, "bucket-1", "assets")
+	createBucket(t, repos, "bucket-2", "backups")
+
+	_, err := repos.Objects.Create(domain.CreateObjectRequest{BucketID: "missing", Path: "a.txt", Content: "a"})
+	assertCode(t, err, domain.ErrorCodeForeignKeyViolation)
+
+	a, err := repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-1", Path: "img/a.png", Content: "a"})
+	require.NoError(t, err)
+	b, err := repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-1", Path: "img/b.png", Content: "b"})
+	require.NoError(t, err)
+	_, err = repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-1", Path: "doc/readme", Content: "r"})
+	require.NoError(t, err)
+
+	// Paths are unique within a bucket only
+	_, err = repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-2", Path: "img/a.png", Content: "a"})
+	require.NoError(t, err)
+	_, err = repos.Objects.Create(domain.CreateObjectRequest{BucketID: "bucket-1", Path: "img/a.png", Content: "dup"})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	objects, _, err := repos.Objects.List(domain.ObjectListOptions{BucketID: "bucket-1", Prefix: "img/"})
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, a.ID, objects[0].ID)
+	assert.Equal(t, b.ID, objects[1].ID)
+
+	objects, _, err = repos.Objects.List(domain.ObjectListOptions{BucketID: "bucket-1"})
+	require.NoError(t, err)
+	assert.Len(t, objects, 3)
+
+	path := "img/a.png"
+	_, err = repos.Objects.Update(b.ID, domain.UpdateObjectRequest{Path: &path})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	content := "new"
+	updated, err := repos.Objects.Update(b.ID, domain.UpdateObjectRequest{Content: &content})
+	require.NoError(t, err)
+	assert.Equal(t, "new", updated.Content)
+	assert.Equal(t, "img/b.png", updated.Path)
+
+	got, err := repos.Objects.GetByID(b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new", got.Content)
+
+	require.NoError(t, repos.Objects.Delete(a.ID))
+	assertCode(t, repos.Objects.Delete(a.ID), domain.ErrorCodeNotFound)
+	_, err = repos.Objects.Update(a.ID, domain.UpdateObjectRequest{Content: &content})
+	assertCode(t, err, domain.ErrorCodeNotFound)
+}
+
+func testOperations(t *testing.T, repos storage.Repositories) {
+	for i := 1; i <= 3; i++ {
+		op := &domain.Operation{
+			ID:         fmt.Sprintf("op-%d", i),
+			Kind:       "bucket.create",
+			TargetType: "bucket",
+			Status:     domain.OperationStatusPending,
+		}
+		require.NoError(t, repos.Operations.Create(op))
+		assert.Nil(t, op.CompletedAt)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	err := repos.Operations.Create(&domain.Operation{ID: "op-1", Kind: "bucket.create", TargetType: "bucket", Status: domain.OperationStatusPending})
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	done, target := domain.OperationStatusDone, "bucket-1"
+	updated, err := repos.Operations.Update("op-2", domain.UpdateOperationRequest{Status: &done, TargetID: &target})
+	require.NoError(t, err)
+	require.NotNil(t, updated.CompletedAt)
+	assert.Equal(t, "bucket-1", updated.TargetID)
+
+	failed := domain.OperationStatusFailed
+	_, err = repos.Operations.Update("op-3", domain.UpdateOperationRequest{Status: &failed, Error: domain.InternalError("boom")})
+	require.NoError(t, err)
+	got, err := repos.Operations.GetByID("op-3")
+	require.NoError(t, err)
+	require.NotNil(t, got.Error)
+	assert.Equal(t, "boom", got.Error.Message)
+	assert.NotNil(t, got.CompletedAt)
+
+	_, err = repos.Operations.GetByID("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	_, err = repos.Operations.Update("missing", domain.UpdateOperationRequest{Status: &done})
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	// Newest first, across pages
+	var ids []string
+	opts := domain.OperationListOptions{PageOptions: domain.PageOptions{PageSize: 2}}
+	for {
+		ops, next, err := repos.Operations.List(opts)
+		require.NoError(t, err)
+		for _, op := range ops {
+			ids = append(ids, op.ID)
+		}
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+	assert.Equal(t, []string{"op-3", "op-2", "op-1"}, ids)
+
+	ops, _, err := repos.Operations.List(domain.OperationListOptions{Status: domain.OperationStatusDone})
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.Equal(t, "op-2", ops[0].ID)
+
+	ops, _, err = repos.Operations.List(domain.OperationListOptions{TargetID: "bucket-1"})
+	require.NoError(t, err)
+	assert.Len(t, ops, 1)
+
+	require.NoError(t, repos.Operations.Create(&domain.Operation{ID: "op-4", Kind: "instance.stop", TargetType: "instance", TargetID: "inst-1", ProjectID: "proj-1", Status: domain.OperationStatusRunning}))
+	got, err = repos.Operations.GetByID("op-4")
+	require.NoError(t, err)
+	assert.Equal(t, "proj-1", got.ProjectID)
+}
+
+func testAPIKeys(t *testing.T, repos storage.Repositories) {
+	key := &domain.APIKey{ID: "key-1", Name: "ci", Prefix: "nah_abc", Scopes: []string{"projects:read", "instances:*"}}
+	require.NoError(t, repos.APIKeys.Create(key, "hash-1"))
+	assert.False(t, key.CreatedAt.IsZero())
+	require.NoError(t, repos.APIKeys.Create(&domain.APIKey{ID: "key-2", Name: "admin", Prefix: "nah_def", Scopes: []string{"*"}}, "hash-2"))
+
+	err := repos.APIKeys.Create(&domain.APIKey{ID: "key-3", Name: "dup", Prefix: "nah_ghi", Scopes: []string{"*"}}, "hash-1")
+	assertCode(t, err, domain.ErrorCodeAlreadyExists)
+
+	got, err := repos.APIKeys.GetByHash("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", got.ID)
+	assert.Equal(t, []string{"projects:read", "instances:*"}, got.Scopes)
+	assert.Nil(t, got.RevokedAt)
+	_, err = repos.APIKeys.GetByHash("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	_, err = repos.APIKeys.GetByID("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	revoked, err := repos.APIKeys.Revoke("key-1")
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	again, err := repos.APIKeys.Revoke("key-1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, *revoked.RevokedAt, *again.RevokedAt, time.Millisecond, "revoking again keeps the original time")
+	_, err = repos.APIKeys.Revoke("missing")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	keys, _, err := repos.APIKeys.List(domain.APIKeyListOptions{})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "key-2", keys[0].ID)
+
+	keys, _, err = repos.APIKeys.List(domain.APIKeyListOptions{IncludeRevoked: true})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "admin", keys[0].Name)
+	assert.Equal(t, "ci", keys[1].Name)
+
+	// Restricted keys reference their project and are deleted along with it
+	err = repos.APIKeys.Create(&domain.APIKey{ID: "key-4", Name: "orphan", Prefix: "nah_jkl", Scopes: []string{"*"}, ProjectID: "missing"}, "hash-4")
+	assertCode(t, err, domain.ErrorCodeForeignKeyViolation)
+	createProject(t, repos, "proj-1", "web")
+	require.NoError(t, repos.APIKeys.Create(&domain.APIKey{ID: "key-5", Name: "web", Prefix: "nah_mno", Scopes: []string{"*"}, ProjectID: "proj-1"}, "hash-5"))
+	got, err = repos.APIKeys.GetByID("key-5")
+	require.NoError(t, err)
+	assert.Equal(t, "proj-1", got.ProjectID)
+	got, err = repos.APIKeys.GetByID("key-2")
+	require.NoError(t, err)
+	assert.Empty(t, got.ProjectID)
+
+	require.NoError(t, repos.Projects.Delete("proj-1"))
+	_, err = repos.APIKeys.GetByID("key-5")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+}
+
+func testPagination(t *testing.T, repos storage.Repositories) {
+	// Duplicate sort keys across projects make the ID tie-break matter
+	createProject(t, repos, "proj-a", "alpha")
+	createProject(t, repos, "proj-b", "beta")
+	for i := 0; i < 3; i++ {
+		createInstance(t, repos, fmt.Sprintf("inst-a%d", i), "proj-a", fmt.Sprintf("node-%d", i))
+		createInstance(t, repos, fmt.Sprintf("inst-b%d", i), "proj-b", fmt.Sprintf("node-%d", i))
+	}
+
+	var ids []string
+	opts := domain.InstanceListOptions{PageOptions: domain.PageOptions{PageSize: 4}}
+	pages := 0
+	for {
+		items, next, err := repos.Instances.List(opts)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(items), 4)
+		for _, i := range items {
+			ids = append(ids, i.ID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, []string{"inst-a0", "inst-b0", "inst-a1", "inst-b1", "inst-a2", "inst-b2"}, ids)
+
+	// An exact final page has no next token
+	items, next, err := repos.Projects.List(domain.ProjectListOptions{PageOptions: domain.PageOptions{PageSize: 2}})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Empty(t, next)
+
+	_, _, err = repos.Projects.List(domain.ProjectListOptions{PageOptions: domain.PageOptions{PageToken: "not-a-token"}})
+	assertCode(t, err, domain.ErrorCodeInvalidInput)
+
+	// Tokens are bound to the listing that issued them
+	_, instanceToken, err := repos.Instances.List(domain.InstanceListOptions{PageOptions: domain.PageOptions{PageSize: 1}})
+	require.NoError(t, err)
+	_, _, err = repos.Projects.List(domain.ProjectListOptions{PageOptions: domain.PageOptions{PageToken: instanceToken}})
+	assertCode(t, err, domain.ErrorCodeInvalidInput)
+}
+
+func writeTFState(t *testing.T, repos storage.Repositories, id string, serial int64, state string) *domain.TFStateVersion {
+	t.Helper()
+	version := &domain.TFStateVersion{StateID: id, Serial: serial, Lineage: "abc", Size: len(state), Checksum: "sum"}
+	require.NoError(t, repos.TFStates.Write(version, state))
+	return version
+}
+
+func testTFStates(t *testing.T, repos storage.Repositories) {
+	_, err := repos.TFStates.Get("network")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	v1 := writeTFState(t, repos, "network", 1, `{"serial":1}`)
+	assert.Equal(t, 1, v1.Version)
+	assert.False(t, v1.CreatedAt.IsZero())
+	v2 := writeTFState(t, repos, "network", 2, `{"serial":2}`)
+	assert.Equal(t, 2, v2.Version)
+	writeTFState(t, repos, "network_prod", 7, `{"serial":7}`)
+	writeTFState(t, repos, "app", 1, `{}`)
+
+	state, err := repos.TFStates.Get("network")
+	require.NoError(t, err)
+	assert.Equal(t, `{"serial":2}`, state.State)
+	assert.Equal(t, int64(2), state.Serial)
+	assert.Equal(t, "abc", state.Lineage)
+	assert.Equal(t, 2, state.Version)
+	assert.Equal(t, len(`{"serial":2}`), state.Size)
+	assert.False(t, state.UpdatedAt.Before(state.CreatedAt))
+
+	versions, err := repos.TFStates.ListVersions("network")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version, "newest first")
+	assert.Equal(t, "sum", versions[0].Checksum)
+
+	got, body, err := repos.TFStates.GetVersion("network", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.Serial)
+	assert.Equal(t, `{"serial":1}`, body)
+	_, _, err = repos.TFStates.GetVersion("network", 3)
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	// Prefixes match literally and case-sensitively
+	states, _, err := repos.TFStates.List(domain.TFStateListOptions{Prefix: "network_"})
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, "network_prod", states[0].ID)
+	assert.Empty(t, states[0].State, "listings leave out the state itself")
+	states, _, err = repos.TFStates.List(domain.TFStateListOptions{Prefix: "NET"})
+	require.NoError(t, err)
+	assert.Empty(t, states)
+
+	var ids []string
+	opts := domain.TFStateListOptions{PageOptions: domain.PageOptions{PageSize: 2}}
+	for {
+		states, next, err := repos.TFStates.List(opts)
+		require.NoError(t, err)
+		for _, s := range states {
+			ids = append(ids, s.ID)
+		}
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+	assert.Equal(t, []string{"app", "network", "network_prod"}, ids)
+
+	// Deleting a state keeps its versions, and later writes carry on numbering them
+	require.NoError(t, repos.TFStates.Delete("network"))
+	assertCode(t, repos.TFStates.Delete("network"), domain.ErrorCodeNotFound)
+	_, err = repos.TFStates.Get("network")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+	versions, err = repos.TFStates.ListVersions("network")
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 3, writeTFState(t, repos, "network", 3, `{"serial":3}`).Version)
+
+	versions, err = repos.TFStates.ListVersions("missing")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func testTFStateLocks(t *testing.T, repos storage.Repositories) {
+	_, err := repos.TFStates.GetLock("app")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+
+	acquired, holder, err := repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "app", LockID: "one", Info: `{"ID":"one"}`})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "one", holder.LockID)
+
+	acquired, holder, err = repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "app", LockID: "two", Info: `{"ID":"two"}`})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, "one", holder.LockID)
+	assert.Equal(t, `{"ID":"one"}`, holder.Info)
+
+	// Locks are per state
+	acquired, _, err = repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "other", LockID: "two"})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	lock, err := repos.TFStates.GetLock("app")
+	require.NoError(t, err)
+	assert.Equal(t, "one", lock.LockID)
+	assert.False(t, lock.CreatedAt.IsZero())
+
+	assertCode(t, repos.TFStates.Unlock("app", "two"), domain.ErrorCodeNotFound)
+	require.NoError(t, repos.TFStates.Unlock("app", "one"))
+	assertCode(t, repos.TFStates.Unlock("app", "one"), domain.ErrorCodeNotFound)
+
+	// An empty lock ID releases whichever lock is held
+	require.NoError(t, repos.TFStates.Unlock("other", ""))
+	_, err = repos.TFStates.GetLock("other")
+	assertCode(t, err, domain.ErrorCodeNotFound)
+}
+
+func testTFStateLockExpiry(t *testing.T, repos storage.Repositories) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	acquired, _, err := repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "stale", LockID: "old", ExpiresAt: &past})
+	require.NoError(t, err)
+	require.True(t, acquired)
+	acquired, _, err = repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "fresh", LockID: "old", ExpiresAt: &future})
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// Expired locks are still listed until something replaces them
+	locks, err := repos.TFStates.ListLocks()
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	assert.Equal(t, "fresh", locks[0].StateID)
+	require.NotNil(t, locks[0].ExpiresAt)
+	assert.WithinDuration(t, future, *locks[0].ExpiresAt, time.Millisecond)
+	assert.Equal(t, "stale", locks[1].StateID)
+
+	acquired, holder, err := repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "stale", LockID: "new"})
+	require.NoError(t, err)
+	assert.True(t, acquired, "an expired lock is taken over")
+	assert.Equal(t, "new", holder.LockID)
+	lock, err := repos.TFStates.GetLock("stale")
+	require.NoError(t, err)
+	assert.Equal(t, "new", lock.LockID)
+	assert.Nil(t, lock.ExpiresAt)
+
+	acquired, holder, err = repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "fresh", LockID: "new"})
+	require.NoError(t, err)
+	assert.False(t, acquired, "a lock that hasn't expired is kept")
+	assert.Equal(t, "old", holder.LockID)
+}
+
+// raceWorkers is how many goroutines the race tests start at once
+const raceWorkers = 16
+
+// race runs fn from raceWorkers goroutines released together
+func race(fn func(worker int)) {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < raceWorkers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			<-start
+			fn(worker)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+func testTFStateLockRace(t *testing.T, repos storage.Repositories) {
+	var (
+		mu      sync.Mutex
+		winners []string
+		holders = make(map[string]bool)
+	)
+	race(func(worker int) {
+		lockID := fmt.Sprintf("lock-%d", worker)
+		acquired, holder, err := repos.TFStates.Lock(&domain.TFStateLockRecord{StateID: "app", LockID: lockID, Info: "{}"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if acquired {
+			winners = append(winners, lockID)
+		}
+		holders[holder.LockID] = true
+	})
+
+	require.Len(t, winners, 1, "exactly one concurrent lock request wins")
+	assert.Equal(t, map[string]bool{winners[0]: true}, holders, "every loser sees the winner's lock")
+	lock, err := repos.TFStates.GetLock("app")
+	require.NoError(t, err)
+	assert.Equal(t, winners[0], lock.LockID)
+}
+
+func testTFStateWriteRace(t *testing.T, repos storage.Repositories) {
+	race(func(worker int) {
+		version := &domain.TFStateVersion{StateID: "app", Serial: int64(worker)}
+		assert.NoError(t, repos.TFStates.Write(version, "{}"))
+	})
+
+	versions, err := repos.TFStates.ListVersions("app")
+	require.NoError(t, err)
+	require.Len(t, versions, raceWorkers, "no concurrent write is lost")
+	for i, v := range versions {
+		assert.Equal(t, raceWorkers-i, v.Version, "versions are numbered without gaps or repeats")
+	}
+	state, err := repos.TFStates.Get("app")
+	require.NoError(t, err)
+	assert.Equal(t, raceWorkers, state.Version)
+}
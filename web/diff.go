@@ -0,0 +1,129 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Diff line kinds
+const (
+	diffSame    = " "
+	diffAdded   = "+"
+	diffRemoved = "-"
+	diffSkipped = "~" // a run of unchanged lines left out of the diff
+)
+
+// maxDiffCells bounds the table diffLines builds for the lines that differ;
+// past it the changed region is shown as removed and re-added wholesale
+const maxDiffCells = 4_000_000
+
+// diffLine is one line of a line diff
+type diffLine struct {
+	Kind string
+	Text string
+}
+
+// stateLines pretty-prints a state so that its diffs line up, one value per line
+func stateLines(state string) []string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(state), "", "  "); err != nil {
+		return strings.Split(state, "\n")
+	}
+	return strings.Split(buf.String(), "\n")
+}
+
+// diffLines returns the line diff that turns a into b, using the longest common
+// subsequence of the lines between their common prefix and suffix
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{diffSame, text})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, text := range midA {
+			lines = append(lines, diffLine{diffRemoved, text})
+		}
+		for _, text := range midB {
+			lines = append(lines, diffLine{diffAdded, text})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:]
+		cols := len(midB) + 1
+		lcs := make([]int32, (len(midA)+1)*cols)
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i*cols+j] = lcs[(i+1)*cols+j+1] + 1
+				} else {
+					lcs[i*cols+j] = max(lcs[(i+1)*cols+j], lcs[i*cols+j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				lines = append(lines, diffLine{diffSame, midA[i]})
+				i++
+				j++
+			case i < len(midA) && (j == len(midB) || lcs[(i+1)*cols+j] >= lcs[i*cols+j+1]):
+				lines = append(lines, diffLine{diffRemoved, midA[i]})
+				i++
+			default:
+				lines = append(lines, diffLine{diffAdded, midB[j]})
+				j++
+			}
+		}
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{diffSame, text})
+	}
+	return lines
+}
+
+// withContext keeps the changed lines of a diff and up to n unchanged lines
+// around each change, replacing longer unchanged runs with a skipped line
+func withContext(lines []diffLine, n int) []diffLine {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Kind == diffSame {
+			continue
+		}
+		for j := max(0, i-n); j <= min(len(lines)-1, i+n); j++ {
+			keep[j] = true
+		}
+	}
+
+	var out []diffLine
+	skipped := 0
+	flush := func() {
+		if skipped > 0 {
+			out = append(out, diffLine{diffSkipped, strconv.Itoa(skipped) + " unchanged lines"})
+			skipped = 0
+		}
+	}
+	for i, line := range lines {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		flush()
+		out = append(out, line)
+	}
+	flush()
+	return out
+}
@@ -115,6 +115,12 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
                     </svg>
                     Storage
                 </a>
+                <a href="#" hx-get="/web/tfstate" hx-target="#content" class="flex items-center gap-3 px-4 py-3 text-slate-500 rounded-lg font-medium text-sm hover:bg-slate-50 hover:text-slate-800 transition-all mb-1">
+                    <svg class="w-5 h-5 opacity-70" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                        <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M12 8v4l3 3m6-3a9 9 0 11-18 0 9 9 0 0118 0z"></path>
+                    </svg>
+                    Terraform State
+                </a>
             </nav>
         </aside>
         <main class="flex-1 ml-60 p-8">
@@ -141,7 +147,7 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 
 // Projects handlers
 func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
-	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
+	projects, _, err := h.service.ListProjects(domain.ProjectListOptions{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -315,13 +321,13 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 
 // Instances handlers
 func (h *Handler) ListInstances(w http.ResponseWriter, r *http.Request) {
-	instances, err := h.service.ListInstances(domain.InstanceListOptions{})
+	instances, _, err := h.service.ListInstances(domain.InstanceListOptions{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
+	projects, _, err := h.service.ListProjects(domain.ProjectListOptions{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -368,11 +374,16 @@ func (h *Handler) ListInstances(w http.ResponseWriter, r *http.Request) {
                         <span class="w-1.5 h-1.5 rounded-full bg-emerald-500"></span>
                         Running
                     </span>
-                    {{else}}
+                    {{else if eq .Status "stopped"}}
                     <span class="inline-flex items-center gap-1.5 px-2.5 py-1 rounded-full text-xs font-medium bg-red-50 text-red-600">
                         <span class="w-1.5 h-1.5 rounded-full bg-red-500"></span>
                         Stopped
                     </span>
+                    {{else}}
+                    <span class="inline-flex items-center gap-1.5 px-2.5 py-1 rounded-full text-xs font-medium bg-slate-100 text-slate-600">
+                        <span class="w-1.5 h-1.5 rounded-full bg-[#2878B5]"></span>
+                        {{.Status}}
+                    </span>
                     {{end}}
                 </td>
                 <td class="px-6 py-4 border-b border-slate-100">
@@ -413,7 +424,7 @@ func (h *Handler) ListInstances(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) NewInstanceForm(w http.ResponseWriter, r *http.Request) {
-	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
+	projects, _, err := h.service.ListProjects(domain.ProjectListOptions{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -530,7 +541,7 @@ func (h *Handler) EditInstanceForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
+	projects, _, err := h.service.ListProjects(domain.ProjectListOptions{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -575,12 +586,28 @@ func (h *Handler) EditInstanceForm(w http.ResponseWriter, r *http.Request) {
             <label class="block text-sm font-medium mb-1.5" for="image">Image</label>
             <input type="text" id="image" name="image" value="{{.Instance.Image}}" required class="w-full px-3.5 py-2.5 text-sm border border-slate-200 rounded-lg focus:outline-none focus:border-[#2878B5] focus:ring-2 focus:ring-[#2878B5]/10 transition-all">
         </div>
+        <div class="mb-5">
+            <label class="block text-sm font-medium mb-1.5">Power</label>
+            <div class="flex gap-2">
+                <button type="button" class="btn btn-secondary btn-sm" hx-post="/web/instances/{{.Instance.ID}}/actions/start" hx-target="#content" {{if ne .Instance.Status "stopped"}}disabled{{end}}>Start</button>
+                <button type="button" class="btn btn-secondary btn-sm" hx-post="/web/instances/{{.Instance.ID}}/actions/stop" hx-target="#content" {{if ne .Instance.Status "running"}}disabled{{end}}>Stop</button>
+                <button type="button" class="btn btn-secondary btn-sm" hx-post="/web/instances/{{.Instance.ID}}/actions/reboot" hx-target="#content" {{if ne .Instance.Status "running"}}disabled{{end}}>Reboot</button>
+                <button type="button" class="btn btn-secondary btn-sm" hx-post="/web/instances/{{.Instance.ID}}/actions/resize" hx-include="#cpu, #memory_mb" hx-target="#content" {{if ne .Instance.Status "stopped"}}disabled{{end}}>Resize</button>
+            </div>
+            <p class="text-xs text-slate-400 mt-1">Resize applies the CPU and memory values above and requires the instance to be stopped</p>
+        </div>
         <div class="mb-5">
             <label class="block text-sm font-medium mb-1.5" for="status">Status</label>
+            {{if and (ne .Instance.Status "running") (ne .Instance.Status "stopped")}}
+            <select id="status" name="status" disabled title="The status can be changed once the instance is running or stopped" class="w-full px-3.5 py-2.5 text-sm border border-slate-200 rounded-lg bg-slate-100 text-slate-500 cursor-not-allowed">
+                <option selected>{{.Instance.Status}}</option>
+            </select>
+            {{else}}
             <select id="status" name="status" class="w-full px-3.5 py-2.5 text-sm border border-slate-200 rounded-lg focus:outline-none focus:border-[#2878B5] focus:ring-2 focus:ring-[#2878B5]/10 transition-all bg-white">
                 <option value="running" {{if eq .Instance.Status "running"}}selected{{end}}>Running</option>
                 <option value="stopped" {{if eq .Instance.Status "stopped"}}selected{{end}}>Stopped</option>
             </select>
+            {{end}}
         </div>
     </div>
     <div class="px-6 py-4 border-t border-slate-200 flex justify-end gap-3 bg-slate-50">
@@ -632,7 +659,10 @@ func (h *Handler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
 		CPU:      &cpu,
 		MemoryMB: &memoryMB,
 		Image:    &image,
-		Status:   &status,
+	}
+	// A transitional status isn't editable, so the form leaves it out
+	if status != "" {
+		req.Status = &status
 	}
 
 	_, err = h.service.UpdateInstance(id, req)
@@ -656,10 +686,48 @@ func (h *Handler) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *Handler) InstanceAction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	action := vars["action"]
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, "Invalid form data")
+		return
+	}
+
+	var req domain.InstanceActionRequest
+	if action == domain.InstanceActionResize {
+		cpu, err := strconv.Atoi(r.FormValue("cpu"))
+		if err != nil || cpu < 1 || cpu > 64 {
+			h.renderError(w, "Invalid CPU value")
+			return
+		}
+		memoryMB, err := strconv.Atoi(r.FormValue("memory_mb"))
+		if err != nil || memoryMB < 1 || memoryMB > 524288 {
+			h.renderError(w, "Invalid memory value")
+			return
+		}
+		req.CPU = &cpu
+		req.MemoryMB = &memoryMB
+	}
+
+	if _, err := h.service.InstanceAction(id, action, req); err != nil {
+		if nahErr, ok := err.(*domain.NahError); ok && nahErr.Code != domain.ErrorCodeInternalError {
+			h.renderError(w, template.HTMLEscapeString(nahErr.Message))
+			return
+		}
+		h.renderServerError(w)
+		return
+	}
+
+	h.ListInstances(w, r)
+}
+
 // Metadata handlers
 func (h *Handler) ListMetadata(w http.ResponseWriter, r *http.Request) {
 	prefix := r.URL.Query().Get("prefix")
-	paths, err := h.service.ListMetadata(domain.MetadataListOptions{Prefix: prefix})
+	paths, _, err := h.service.ListMetadata(domain.MetadataListOptions{Prefix: prefix})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -904,7 +972,7 @@ func (h *Handler) DeleteMetadata(w http.ResponseWriter, r *http.Request) {
 
 // Storage handlers
 func (h *Handler) ListStorage(w http.ResponseWriter, r *http.Request) {
-	buckets, err := h.service.ListBuckets(domain.BucketListOptions{})
+	buckets, _, err := h.service.ListBuckets(domain.BucketListOptions{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1012,7 +1080,7 @@ func (h *Handler) ListBucketObjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	objects, err := h.service.ListObjects(domain.ObjectListOptions{BucketID: bucket.Name, Prefix: prefix})
+	objects, _, err := h.service.ListObjects(domain.ObjectListOptions{BucketID: bucket.Name, Prefix: prefix})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1207,3 +1275,263 @@ func (h *Handler) ViewObject(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// Terraform state handlers
+func (h *Handler) ListTFStates(w http.ResponseWriter, r *http.Request) {
+	states, _, err := h.service.ListTFStates(domain.TFStateListOptions{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	locks, err := h.service.ListTFStateLocks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tmpl := `
+<div class="bg-white rounded-xl shadow-sm border border-slate-200 overflow-hidden mb-4">
+    <div class="px-6 py-5 border-b border-slate-200 flex justify-between items-center">
+        <h2 class="text-lg font-semibold">Terraform State</h2>
+    </div>
+    <table class="w-full">
+        <thead>
+            <tr>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">State</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Version</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Serial</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Updated At</th>
+            </tr>
+        </thead>
+        <tbody>
+            {{range .States}}
+            <tr class="hover:bg-slate-50">
+                <td class="px-6 py-4 border-b border-slate-100">
+                    <div class="flex items-center gap-2">
+                        <a href="#" hx-get="/web/tfstate/{{.ID}}" hx-target="#content" class="text-[#2878B5] font-medium hover:underline">{{.ID}}</a>
+                        {{if .Locked}}
+                        <span class="inline-flex items-center gap-1.5 px-2.5 py-1 rounded-full text-xs font-medium bg-red-50 text-red-600">
+                            <span class="w-1.5 h-1.5 rounded-full bg-red-500"></span>
+                            Locked
+                        </span>
+                        {{end}}
+                    </div>
+                </td>
+                {{if .Version}}
+                <td class="px-6 py-4 border-b border-slate-100">{{.Version}}</td>
+                {{else}}
+                <td class="px-6 py-4 border-b border-slate-100 text-slate-400">No history</td>
+                {{end}}
+                <td class="px-6 py-4 border-b border-slate-100">{{.Serial}}</td>
+                <td class="px-6 py-4 border-b border-slate-100 text-slate-500">{{.UpdatedAt.Format "2006-01-02 15:04:05"}}</td>
+            </tr>
+            {{else}}
+            <tr>
+                <td class="px-6 py-4 text-slate-500" colspan="4">No Terraform states yet.</td>
+            </tr>
+            {{end}}
+        </tbody>
+    </table>
+</div>
+<div class="bg-white rounded-xl shadow-sm border border-slate-200 overflow-hidden">
+    <div class="px-6 py-5 border-b border-slate-200 flex justify-between items-center">
+        <h2 class="text-lg font-semibold">Locks</h2>
+    </div>
+    <table class="w-full">
+        <thead>
+            <tr>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">State</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Who</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Operation</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Acquired At</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Expires At</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Actions</th>
+            </tr>
+        </thead>
+        <tbody>
+            {{range .Locks}}
+            <tr class="hover:bg-slate-50">
+                <td class="px-6 py-4 border-b border-slate-100">
+                    <div class="font-medium">{{.StateID}}</div>
+                    <code class="text-xs text-slate-500 font-mono">{{.Lock.ID}}</code>
+                </td>
+                <td class="px-6 py-4 border-b border-slate-100">{{if .Lock.Who}}{{.Lock.Who}}{{else}}<span class="text-slate-400">unknown</span>{{end}}</td>
+                <td class="px-6 py-4 border-b border-slate-100">{{if .Lock.Operation}}{{.Lock.Operation}}{{else}}<span class="text-slate-400">unknown</span>{{end}}</td>
+                <td class="px-6 py-4 border-b border-slate-100 text-slate-500">{{.AcquiredAt.Format "2006-01-02 15:04:05"}}</td>
+                <td class="px-6 py-4 border-b border-slate-100 text-slate-500">{{if .ExpiresAt}}{{.ExpiresAt.Format "2006-01-02 15:04:05"}}{{else}}Never{{end}}</td>
+                <td class="px-6 py-4 border-b border-slate-100">
+                    <button class="btn btn-danger btn-sm" hx-delete="/web/tfstate/{{.StateID}}/lock" hx-target="#content" hx-confirm="Force unlock {{.StateID}}? Only do this if the client holding the lock is no longer running.">Force Unlock</button>
+                </td>
+            </tr>
+            {{else}}
+            <tr>
+                <td class="px-6 py-4 text-slate-500" colspan="6">No states are locked.</td>
+            </tr>
+            {{end}}
+        </tbody>
+    </table>
+</div>
+`
+
+	data := struct {
+		States []*domain.TFState
+		Locks  []*domain.TFStateLockStatus
+	}{states, locks}
+
+	t := template.Must(template.New("tfstates").Parse(tmpl))
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ForceUnlockTFState releases a state's lock and shows the state list again
+func (h *Handler) ForceUnlockTFState(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := h.service.ForceUnlockTFState(id); err != nil && !domain.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.ListTFStates(w, r)
+}
+
+func (h *Handler) TFStateHistory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	versions, err := h.service.ListTFStateVersions(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tmpl := `
+<div class="bg-white rounded-xl shadow-sm border border-slate-200 overflow-hidden">
+    <div class="px-6 py-5 border-b border-slate-200 flex justify-between items-center">
+        <div class="flex items-center gap-3">
+            <button class="btn btn-secondary btn-sm" hx-get="/web/tfstate" hx-target="#content">
+                <svg class="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M10 19l-7-7m0 0l7-7m-7 7h18"></path>
+                </svg>
+                Back
+            </button>
+            <h2 class="text-lg font-semibold">{{.ID}}</h2>
+        </div>
+    </div>
+    <table class="w-full">
+        <thead>
+            <tr>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Version</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Serial</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Lineage</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Size</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Checksum</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Lock</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Created At</th>
+                <th class="text-left px-6 py-3 text-xs font-semibold uppercase tracking-wider text-slate-500 bg-slate-50 border-b border-slate-200">Actions</th>
+            </tr>
+        </thead>
+        <tbody>
+            {{range .Versions}}
+            <tr class="hover:bg-slate-50">
+                <td class="px-6 py-4 border-b border-slate-100 font-medium">
+                    {{.Version}}
+                    {{if .RollbackFrom}}<span class="text-xs text-slate-500">(rollback to {{.RollbackFrom}})</span>{{end}}
+                </td>
+                <td class="px-6 py-4 border-b border-slate-100">{{.Serial}}</td>
+                <td class="px-6 py-4 border-b border-slate-100"><code class="bg-slate-100 px-2 py-0.5 rounded text-xs">{{.Lineage}}</code></td>
+                <td class="px-6 py-4 border-b border-slate-100 text-slate-500">{{.Size}} bytes</td>
+                <td class="px-6 py-4 border-b border-slate-100"><code class="text-xs" title="{{.Checksum}}">{{printf "%.12s" .Checksum}}</code></td>
+                <td class="px-6 py-4 border-b border-slate-100 text-xs text-slate-500">{{.LockID}}</td>
+                <td class="px-6 py-4 border-b border-slate-100 text-slate-500">{{.CreatedAt.Format "2006-01-02 15:04:05"}}</td>
+                <td class="px-6 py-4 border-b border-slate-100">
+                    {{if gt .Version 1}}
+                    <button class="btn btn-secondary btn-sm" hx-get="/web/tfstate/{{$.ID}}/diff?from={{previous .Version}}&to={{.Version}}" hx-target="#tfstate-diff">Diff</button>
+                    {{end}}
+                </td>
+            </tr>
+            {{end}}
+        </tbody>
+    </table>
+    {{if gt (len .Versions) 1}}
+    <form class="px-6 py-4 border-t border-slate-200 flex items-center gap-3" hx-get="/web/tfstate/{{.ID}}/diff" hx-target="#tfstate-diff">
+        <label class="text-sm font-medium" for="diff-from">Compare</label>
+        <select id="diff-from" name="from" class="px-3.5 py-2.5 text-sm border border-slate-200 rounded-lg focus:outline-none focus:border-[#2878B5] focus:ring-2 focus:ring-[#2878B5]/10 transition-all bg-white">
+            {{range $i, $v := .Versions}}<option value="{{$v.Version}}"{{if eq $i 1}} selected{{end}}>Version {{$v.Version}}</option>{{end}}
+        </select>
+        <label class="text-sm font-medium" for="diff-to">with</label>
+        <select id="diff-to" name="to" class="px-3.5 py-2.5 text-sm border border-slate-200 rounded-lg focus:outline-none focus:border-[#2878B5] focus:ring-2 focus:ring-[#2878B5]/10 transition-all bg-white">
+            {{range .Versions}}<option value="{{.Version}}">Version {{.Version}}</option>{{end}}
+        </select>
+        <button type="submit" class="btn btn-primary btn-sm">Show Diff</button>
+    </form>
+    {{end}}
+    <div id="tfstate-diff"></div>
+</div>
+`
+
+	data := struct {
+		ID       string
+		Versions []*domain.TFStateVersion
+	}{
+		ID:       id,
+		Versions: versions,
+	}
+
+	funcs := template.FuncMap{"previous": func(version int) int { return version - 1 }}
+	t := template.Must(template.New("tfstate-history").Funcs(funcs).Parse(tmpl))
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) TFStateDiff(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	from, err1 := strconv.Atoi(r.URL.Query().Get("from"))
+	to, err2 := strconv.Atoi(r.URL.Query().Get("to"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "from and to must be version numbers", http.StatusBadRequest)
+		return
+	}
+	_, fromState, err := h.service.GetTFStateVersion(id, from)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	_, toState, err := h.service.GetTFStateVersion(id, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tmpl := `
+<div class="px-6 py-4 border-t border-slate-200">
+    <h3 class="text-sm font-semibold mb-3">Version {{.From}} &rarr; Version {{.To}}</h3>
+    {{if .Changed}}
+    <pre class="bg-slate-50 border border-slate-200 rounded-lg overflow-auto max-h-[50vh] text-xs font-mono">{{range .Lines}}{{if eq .Kind "+"}}<div class="bg-emerald-50 text-emerald-600 px-4">+ {{.Text}}</div>{{else if eq .Kind "-"}}<div class="bg-red-50 text-red-700 px-4">- {{.Text}}</div>{{else if eq .Kind "~"}}<div class="text-slate-400 px-4">  … {{.Text}}</div>{{else}}<div class="px-4">  {{.Text}}</div>{{end}}{{end}}</pre>
+    {{else}}
+    <p class="text-sm text-slate-500">The versions are identical.</p>
+    {{end}}
+</div>
+`
+
+	lines := withContext(diffLines(stateLines(fromState), stateLines(toState)), 3)
+	changed := false
+	for _, line := range lines {
+		if line.Kind == diffAdded || line.Kind == diffRemoved {
+			changed = true
+			break
+		}
+	}
+	data := struct {
+		From, To int
+		Changed  bool
+		Lines    []diffLine
+	}{
+		From:    from,
+		To:      to,
+		Changed: changed,
+		Lines:   lines,
+	}
+
+	t := template.Must(template.New("tfstate-diff").Parse(tmpl))
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}